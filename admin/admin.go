@@ -0,0 +1,184 @@
+// Package admin serves administrative HTTP endpoints of a bot: liveness and readiness probes
+// and a JSON API for support staff
+//
+//	server := admin.New(bot,
+//		admin.WithToken(os.Getenv("ADMIN_TOKEN")),
+//		admin.WithHandler(metrics.DefaultPath, collector),
+//	)
+//	go server.ListenAndServe(":8081")
+//
+// Endpoints:
+//
+//	/healthz  liveness: the polling loop is alive and getUpdates succeeded recently
+//	/readyz   readiness: liveness, the webhook status matches the way updates are received,
+//	          storages are reachable and the number of pending updates is below the limit
+//
+// Probes respond with 200 when all checks pass and 503 otherwise; the body is a JSON report
+// of every check and the bot's tgfsm.Health.
+//
+// The API is served only if a token is set with WithToken; requests must carry it
+// in the "Authorization: Bearer <token>" header:
+//
+//	GET    /api/states                     states with the number of users in each
+//	GET    /api/states/{state}/users       IDs of users in the state
+//	GET    /api/users/{id}                 state, state history and session data of the user
+//	PUT    /api/users/{id}/state           move the user: {"state": "menu", "force": true}
+//	DELETE /api/users/{id}/state           reset the user's state and history
+//	GET    /api/users/{id}/session         session data of the user
+//	DELETE /api/users/{id}/session         clear the session data
+//	PUT    /api/users/{id}/session/{key}   set a session value, the body is its JSON
+//	DELETE /api/users/{id}/session/{key}   delete a session value
+//	GET    /api/blacklist                  active bans of chats and users
+//	PUT    /api/blacklist/{chat}           ban the chat: {"reason": "spam", "duration": "24h"}, optional
+//	DELETE /api/blacklist/{chat}           remove the ban of the chat
+//	PUT    /api/blacklist/users/{id}       ban the user in all chats, the body is like for chats
+//	DELETE /api/blacklist/users/{id}       remove the ban of the user
+//	GET    /api/limiter                    rate limiter status
+//
+// Errors are returned as {"error": "..."}. Changes are logged with the bot's logger.
+package admin
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"tgfsm"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Default limits of the checks
+const (
+	DefaultMaxPollAge      = 2 * time.Minute // Longer than any reasonable long polling timeout
+	DefaultCheckTimeout    = 5 * time.Second
+	DefaultWebhookCacheTTL = 30 * time.Second // Frequent probes make few getWebhookInfo calls
+)
+
+// Check is a custom readiness check, it returns an error if the bot is not ready
+type Check func(ctx context.Context) error
+
+// namedCheck is a custom check with its name in the report
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Server serves the administrative endpoints of a bot
+type Server struct {
+	bot *tgfsm.Bot
+	mux *http.ServeMux
+
+	maxPollAge   time.Duration
+	maxPending   int
+	checkTimeout time.Duration
+	checkWebhook bool
+	webhookTTL   time.Duration
+	checks       []namedCheck
+	handlers     map[string]http.Handler
+	token        string
+
+	webhookMu        sync.Mutex
+	webhookInfo      tgbotapi.WebhookInfo
+	webhookCheckedAt time.Time
+}
+
+// Option is a function that modifies the server configuration
+type Option func(*Server)
+
+// WithMaxPollAge sets the time without a successful getUpdates after which the bot is not alive,
+// DefaultMaxPollAge by default. It must be longer than the polling timeout passed to Bot.Start.
+func WithMaxPollAge(age time.Duration) Option {
+	return func(s *Server) {
+		s.maxPollAge = age
+	}
+}
+
+// WithMaxPending sets the number of queued and in-flight updates above which the bot is not ready,
+// 0 (the default) disables the check
+func WithMaxPending(pending int) Option {
+	return func(s *Server) {
+		s.maxPending = pending
+	}
+}
+
+// WithCheckTimeout sets the timeout of readiness checks, DefaultCheckTimeout by default
+func WithCheckTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.checkTimeout = timeout
+	}
+}
+
+// WithWebhookCheck enables checking the webhook status with getWebhookInfo on readiness probes,
+// enabled by default
+func WithWebhookCheck(enabled bool) Option {
+	return func(s *Server) {
+		s.checkWebhook = enabled
+	}
+}
+
+// WithWebhookCacheTTL sets how long the webhook status is reused by readiness probes,
+// DefaultWebhookCacheTTL by default; 0 requests it on every probe
+func WithWebhookCacheTTL(ttl time.Duration) Option {
+	return func(s *Server) {
+		s.webhookTTL = ttl
+	}
+}
+
+// WithCheck adds a custom readiness check, e.g. of the application's database
+func WithCheck(name string, check Check) Option {
+	return func(s *Server) {
+		s.checks = append(s.checks, namedCheck{name: name, check: check})
+	}
+}
+
+// WithToken enables the API authenticated with the bearer token
+// The probes are served without authentication.
+func WithToken(token string) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// WithHandler serves an additional handler on the admin server, e.g. the metrics collector
+func WithHandler(pattern string, handler http.Handler) Option {
+	return func(s *Server) {
+		s.handlers[pattern] = handler
+	}
+}
+
+// New creates the admin server of the bot
+func New(bot *tgfsm.Bot, options ...Option) *Server {
+	s := &Server{
+		bot:          bot,
+		mux:          http.NewServeMux(),
+		maxPollAge:   DefaultMaxPollAge,
+		checkTimeout: DefaultCheckTimeout,
+		checkWebhook: true,
+		webhookTTL:   DefaultWebhookCacheTTL,
+		handlers:     make(map[string]http.Handler),
+	}
+	for _, option := range options {
+		option(s)
+	}
+
+	s.mux.HandleFunc("/healthz", s.serveLiveness)
+	s.mux.HandleFunc("/readyz", s.serveReadiness)
+	if s.token != "" {
+		s.mux.HandleFunc("/api/", s.serveAPI)
+	}
+	for pattern, handler := range s.handlers {
+		s.mux.Handle(pattern, handler)
+	}
+	return s
+}
+
+// ServeHTTP serves the admin endpoints
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves the admin endpoints on the address
+func (s *Server) ListenAndServe(addr string) error {
+	return http.ListenAndServe(addr, s)
+}
@@ -0,0 +1,333 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"tgfsm"
+	"time"
+)
+
+// StateInfo describes a state in the API
+type StateInfo struct {
+	Name   string `json:"name"`
+	Title  string `json:"title,omitempty"` // State.Name
+	Global bool   `json:"global,omitempty"`
+	Users  int    `json:"users"` // Users with the state stored
+}
+
+// UserInfo describes a user in the API
+type UserInfo struct {
+	UserID  int64                  `json:"user_id"`
+	State   string                 `json:"state"`   // Current state, the initial one for users without a stored state
+	History []string               `json:"history"` // State history, the last element is restored by PopUserState
+	Session map[string]interface{} `json:"session"` // Session data (see tgfsm.Bot.UserSession)
+}
+
+// SetStateRequest is the body of PUT /api/users/{id}/state
+type SetStateRequest struct {
+	State string `json:"state"`
+	// Force bypasses State.Transitions and State.Guards (see tgfsm.Bot.ForceUserState)
+	Force bool `json:"force"`
+}
+
+// BanRequest is the optional body of PUT /api/blacklist/...
+type BanRequest struct {
+	Reason string `json:"reason"`
+	// Duration of the ban in time.ParseDuration format ("24h"), permanent if empty
+	Duration string `json:"duration"`
+}
+
+// errorResponse is the body of failed API responses
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// errNotFound is returned for unknown API paths
+var errNotFound = errors.New("not found")
+
+// serveAPI authenticates the request and routes it
+func (s *Server) serveAPI(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="tgfsm admin"`)
+		writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/"), "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case parts[0] == "states":
+		s.routeStates(w, r, parts[1:])
+	case parts[0] == "users" && len(parts) > 1:
+		s.routeUser(w, r, parts[1:])
+	case parts[0] == "blacklist":
+		s.routeBlacklist(w, r, parts[1:])
+	case path == "limiter":
+		if allowMethods(w, r, http.MethodGet) {
+			writeJSON(w, http.StatusOK, s.bot.LimiterStatus())
+		}
+	default:
+		writeError(w, http.StatusNotFound, errNotFound)
+	}
+}
+
+// routeStates serves /api/states and /api/states/{state}/users
+func (s *Server) routeStates(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case len(parts) == 0:
+		if allowMethods(w, r, http.MethodGet) {
+			writeJSON(w, http.StatusOK, s.states())
+		}
+	case len(parts) == 2 && parts[1] == "users":
+		if !allowMethods(w, r, http.MethodGet) {
+			return
+		}
+		if _, ok := s.bot.States()[parts[0]]; !ok {
+			writeError(w, http.StatusNotFound, tgfsm.NewSFMError(tgfsm.ErrStateHandlerNotFound, parts[0]))
+			return
+		}
+		users := s.bot.UsersInState(parts[0])
+		if users == nil {
+			users = []int64{}
+		}
+		writeJSON(w, http.StatusOK, users)
+	default:
+		writeError(w, http.StatusNotFound, errNotFound)
+	}
+}
+
+// states returns the states of the bot by name
+func (s *Server) states() []StateInfo {
+	users := s.bot.ActiveUsers()
+	states := make([]StateInfo, 0, len(s.bot.States()))
+	for name, state := range s.bot.States() {
+		states = append(states, StateInfo{Name: name, Title: state.Name, Global: state.Global, Users: users[name]})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+// routeUser serves /api/users/{id}, /api/users/{id}/state and /api/users/{id}/session[/{key}]
+func (s *Server) routeUser(w http.ResponseWriter, r *http.Request, parts []string) {
+	userID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid user ID %q", parts[0]))
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		if allowMethods(w, r, http.MethodGet) {
+			writeJSON(w, http.StatusOK, s.user(userID))
+		}
+	case len(parts) == 2 && parts[1] == "state":
+		s.serveUserState(w, r, userID)
+	case len(parts) == 2 && parts[1] == "session":
+		if !allowMethods(w, r, http.MethodGet, http.MethodDelete) {
+			return
+		}
+		if r.Method == http.MethodDelete {
+			s.bot.ClearUserSession(userID)
+			s.bot.Logger().Info("admin: user session cleared", tgfsm.LogField("user_id", userID))
+		}
+		writeJSON(w, http.StatusOK, s.user(userID).Session)
+	case len(parts) == 3 && parts[1] == "session":
+		s.serveSessionValue(w, r, userID, parts[2])
+	default:
+		writeError(w, http.StatusNotFound, errNotFound)
+	}
+}
+
+// serveUserState sets (PUT) or resets (DELETE) the user's state
+func (s *Server) serveUserState(w http.ResponseWriter, r *http.Request, userID int64) {
+	if !allowMethods(w, r, http.MethodPut, http.MethodDelete) {
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		s.bot.ResetUserState(userID)
+		s.bot.Logger().Info("admin: user state reset", tgfsm.LogField("user_id", userID))
+		writeJSON(w, http.StatusOK, s.user(userID))
+		return
+	}
+
+	var request SetStateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+
+	var err error
+	if request.Force {
+		err = s.bot.ForceUserState(userID, request.State)
+	} else {
+		err = s.bot.SetUserState(userID, request.State)
+	}
+	switch {
+	case errors.Is(err, tgfsm.ErrStateHandlerNotFound):
+		writeError(w, http.StatusNotFound, err)
+		return
+	case errors.Is(err, tgfsm.ErrTransitionNotAllowed), errors.Is(err, tgfsm.ErrTransitionRejected):
+		writeError(w, http.StatusConflict, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.bot.Logger().Info("admin: user state set",
+		tgfsm.LogField("user_id", userID),
+		tgfsm.LogField("state", request.State),
+		tgfsm.LogField("force", request.Force),
+	)
+	writeJSON(w, http.StatusOK, s.user(userID))
+}
+
+// serveSessionValue sets (PUT) or deletes (DELETE) a session value of the user
+// The body of PUT is the JSON value; it is stored as decoded by encoding/json,
+// so numbers become float64 and objects map[string]interface{}.
+func (s *Server) serveSessionValue(w http.ResponseWriter, r *http.Request, userID int64, key string) {
+	if !allowMethods(w, r, http.MethodPut, http.MethodDelete) {
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		s.bot.DeleteUserSessionValue(userID, key)
+		s.bot.Logger().Info("admin: user session value deleted", tgfsm.LogField("user_id", userID), tgfsm.LogField("key", key))
+		writeJSON(w, http.StatusOK, s.user(userID).Session)
+		return
+	}
+
+	var value interface{}
+	if err := json.NewDecoder(r.Body).Decode(&value); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid value: %w", err))
+		return
+	}
+	s.bot.SetUserSessionValue(userID, key, value)
+	s.bot.Logger().Info("admin: user session value set", tgfsm.LogField("user_id", userID), tgfsm.LogField("key", key))
+	writeJSON(w, http.StatusOK, s.user(userID).Session)
+}
+
+// user returns the information about the user
+func (s *Server) user(userID int64) UserInfo {
+	state, _ := s.bot.GetUserState(userID)
+
+	session := s.bot.UserSession(userID)
+	for key, value := range session {
+		session[key] = jsonValue(value)
+	}
+
+	history := s.bot.GetUserStateStack(userID)
+	if history == nil {
+		history = []string{}
+	}
+
+	return UserInfo{
+		UserID:  userID,
+		State:   state,
+		History: history,
+		Session: session,
+	}
+}
+
+// routeBlacklist serves /api/blacklist, /api/blacklist/{chatID} and /api/blacklist/users/{userID}
+func (s *Server) routeBlacklist(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case len(parts) == 0:
+		if allowMethods(w, r, http.MethodGet) {
+			writeJSON(w, http.StatusOK, s.bot.Bans())
+		}
+	case len(parts) == 1:
+		s.serveBan(w, r, tgfsm.BanChat, parts[0])
+	case len(parts) == 2 && parts[0] == "users":
+		s.serveBan(w, r, tgfsm.BanUser, parts[1])
+	default:
+		writeError(w, http.StatusNotFound, errNotFound)
+	}
+}
+
+// serveBan bans (PUT) or unbans (DELETE) the chat or user
+// The body of PUT is optional: {"reason": "spam", "duration": "24h"}, the ban is permanent without duration.
+func (s *Server) serveBan(w http.ResponseWriter, r *http.Request, kind tgfsm.BanKind, idText string) {
+	if !allowMethods(w, r, http.MethodPut, http.MethodDelete) {
+		return
+	}
+	id, err := strconv.ParseInt(idText, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid %s ID %q", kind, idText))
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if err := s.bot.Unban(kind, id); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		s.bot.Logger().Info("admin: ban removed", tgfsm.LogField("kind", string(kind)), tgfsm.LogField("id", id))
+		writeJSON(w, http.StatusOK, s.bot.Bans())
+		return
+	}
+
+	var request BanRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+	var duration time.Duration
+	if request.Duration != "" {
+		if duration, err = time.ParseDuration(request.Duration); err != nil || duration < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid duration %q", request.Duration))
+			return
+		}
+	}
+
+	ban := tgfsm.Ban{Kind: kind, ID: id, Reason: request.Reason, Created: time.Now()}
+	if duration > 0 {
+		ban.Until = ban.Created.Add(duration)
+	}
+	if err := s.bot.Ban(ban); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.bot.Logger().Info("admin: ban added",
+		tgfsm.LogField("kind", string(kind)),
+		tgfsm.LogField("id", id),
+		tgfsm.LogField("reason", request.Reason),
+		tgfsm.LogField("duration", duration),
+	)
+	writeJSON(w, http.StatusOK, s.bot.Bans())
+}
+
+// allowMethods checks the request method and responds with 405 if it is not allowed
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+// jsonValue returns the value if it can be encoded to JSON, its text representation otherwise
+func jsonValue(value interface{}) interface{} {
+	if _, err := json.Marshal(value); err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return value
+}
+
+// writeError writes a failed API response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,259 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"tgfsm"
+	"tgfsm/admin"
+	"tgfsm/tgfsmtest"
+)
+
+const (
+	apiToken  = "secret"
+	apiUserID = 42
+)
+
+// newAPIServer creates an admin server with the API for a bot with the states
+// home -> menu -> settings, where settings can be reached only from menu
+func newAPIServer(t *testing.T, options ...admin.Option) (*tgfsm.Bot, *admin.Server) {
+	t.Helper()
+
+	server := tgfsmtest.NewServer()
+	t.Cleanup(server.Close)
+
+	states := map[string]tgfsm.State{
+		"home":     {Transitions: []string{"menu"}},
+		"menu":     {Transitions: []string{"home", "settings"}},
+		"settings": {},
+	}
+	bot, err := tgfsmtest.NewBot(server, tgfsm.WithStates(states), tgfsm.WithInitialState("home"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bot, admin.New(bot, append([]admin.Option{admin.WithToken(apiToken)}, options...)...)
+}
+
+// call makes an authenticated API request and decodes the response into result if it is not nil
+func call(t *testing.T, s *admin.Server, method, path, body string, result interface{}) int {
+	t.Helper()
+
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.Header.Set("Authorization", "Bearer "+apiToken)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, request)
+
+	if result != nil {
+		if err := json.NewDecoder(rec.Body).Decode(result); err != nil {
+			t.Fatalf("%s %s: invalid response: %v", method, path, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestAPIAuthentication(t *testing.T) {
+	_, s := newAPIServer(t)
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{name: "missing token", status: http.StatusUnauthorized},
+		{name: "wrong token", header: "Bearer wrong", status: http.StatusUnauthorized},
+		{name: "not bearer", header: apiToken, status: http.StatusUnauthorized},
+		{name: "valid token", header: "Bearer " + apiToken, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/api/states", nil)
+			if tt.header != "" {
+				request.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, request)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("no WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestAPIRequiresToken(t *testing.T) {
+	server := tgfsmtest.NewServer()
+	t.Cleanup(server.Close)
+	bot, err := tgfsmtest.NewBot(server, tgfsm.WithStates(map[string]tgfsm.State{"home": {}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := admin.New(bot)
+
+	request := httptest.NewRequest(http.MethodGet, "/api/states", nil)
+	request.Header.Set("Authorization", "Bearer ")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, request)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status %d, want %d: the API is not served without a token", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestAPIUserState(t *testing.T) {
+	bot, s := newAPIServer(t)
+	path := "/api/users/42/state"
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+		state  string
+	}{
+		{name: "allowed transition", method: http.MethodPut, body: `{"state": "menu"}`, status: http.StatusOK, state: "menu"},
+		{name: "back home", method: http.MethodPut, body: `{"state": "home"}`, status: http.StatusOK, state: "home"},
+		{name: "undeclared transition", method: http.MethodPut, body: `{"state": "settings"}`, status: http.StatusConflict, state: "home"},
+		{name: "forced transition", method: http.MethodPut, body: `{"state": "settings", "force": true}`, status: http.StatusOK, state: "settings"},
+		{name: "unknown state", method: http.MethodPut, body: `{"state": "missing", "force": true}`, status: http.StatusNotFound, state: "settings"},
+		{name: "invalid body", method: http.MethodPut, body: `{`, status: http.StatusBadRequest, state: "settings"},
+		{name: "reset", method: http.MethodDelete, status: http.StatusOK, state: "home"},
+		{name: "method not allowed", method: http.MethodGet, status: http.StatusMethodNotAllowed, state: "home"},
+	}
+
+	for _, tt := range tests {
+		if status := call(t, s, tt.method, path, tt.body, nil); status != tt.status {
+			t.Fatalf("%s: status %d, want %d", tt.name, status, tt.status)
+		}
+		if state, _ := bot.GetUserState(apiUserID); state != tt.state {
+			t.Fatalf("%s: state %q, want %q", tt.name, state, tt.state)
+		}
+	}
+
+	var user admin.UserInfo
+	if status := call(t, s, http.MethodGet, "/api/users/42", "", &user); status != http.StatusOK {
+		t.Fatalf("status %d, want %d", status, http.StatusOK)
+	}
+	if user.UserID != apiUserID || user.State != "home" || len(user.History) != 0 {
+		t.Fatalf("user %+v, want user 42 at home without history", user)
+	}
+}
+
+func TestAPISession(t *testing.T) {
+	bot, s := newAPIServer(t)
+
+	var session map[string]interface{}
+	if status := call(t, s, http.MethodPut, "/api/users/42/session/lang", `"en"`, &session); status != http.StatusOK {
+		t.Fatalf("set: status %d", status)
+	}
+	if status := call(t, s, http.MethodPut, "/api/users/42/session/count", `3`, &session); status != http.StatusOK {
+		t.Fatalf("set: status %d", status)
+	}
+	if session["lang"] != "en" || session["count"] != float64(3) {
+		t.Fatalf("session %v, want lang and count", session)
+	}
+	if value := bot.UserSession(apiUserID)["lang"]; value != "en" {
+		t.Fatalf("bot session value %v, want en", value)
+	}
+
+	if status := call(t, s, http.MethodPut, "/api/users/42/session/lang", `{`, nil); status != http.StatusBadRequest {
+		t.Fatalf("invalid value: status %d, want %d", status, http.StatusBadRequest)
+	}
+
+	session = nil
+	if status := call(t, s, http.MethodDelete, "/api/users/42/session/lang", "", &session); status != http.StatusOK {
+		t.Fatalf("delete: status %d", status)
+	}
+	if _, ok := session["lang"]; ok || session["count"] != float64(3) {
+		t.Fatalf("session %v, want only count", session)
+	}
+
+	session = nil
+	if status := call(t, s, http.MethodDelete, "/api/users/42/session", "", &session); status != http.StatusOK {
+		t.Fatalf("clear: status %d", status)
+	}
+	if len(session) != 0 {
+		t.Fatalf("session %v after clearing, want empty", session)
+	}
+}
+
+func TestAPIBlacklist(t *testing.T) {
+	bot, s := newAPIServer(t)
+
+	var bans []tgfsm.Ban
+	if status := call(t, s, http.MethodPut, "/api/blacklist/-100", `{"reason": "spam", "duration": "24h"}`, &bans); status != http.StatusOK {
+		t.Fatalf("ban chat: status %d", status)
+	}
+	if status := call(t, s, http.MethodPut, "/api/blacklist/users/42", "", &bans); status != http.StatusOK {
+		t.Fatalf("ban user: status %d", status)
+	}
+	if len(bans) != 2 || bans[0].Kind != tgfsm.BanChat || bans[1].Kind != tgfsm.BanUser {
+		t.Fatalf("bans %+v, want the chat and the user", bans)
+	}
+
+	chat, ok := bot.GetBan(tgfsm.BanChat, -100)
+	if !ok || chat.Reason != "spam" || chat.Until.Sub(chat.Created) != 24*time.Hour {
+		t.Fatalf("chat ban %+v, want a 24h spam ban", chat)
+	}
+	if user, ok := bot.GetBan(tgfsm.BanUser, apiUserID); !ok || !user.Until.IsZero() {
+		t.Fatalf("user ban %+v, want a permanent ban", user)
+	}
+
+	for _, body := range []string{`{"duration": "soon"}`, `{"duration": "-1h"}`} {
+		if status := call(t, s, http.MethodPut, "/api/blacklist/-200", body, nil); status != http.StatusBadRequest {
+			t.Fatalf("body %s: status %d, want %d", body, status, http.StatusBadRequest)
+		}
+	}
+	if status := call(t, s, http.MethodPut, "/api/blacklist/chat", "", nil); status != http.StatusBadRequest {
+		t.Fatalf("invalid ID: status %d, want %d", status, http.StatusBadRequest)
+	}
+
+	if status := call(t, s, http.MethodDelete, "/api/blacklist/-100", "", &bans); status != http.StatusOK {
+		t.Fatalf("unban chat: status %d", status)
+	}
+	if status := call(t, s, http.MethodDelete, "/api/blacklist/users/42", "", &bans); status != http.StatusOK {
+		t.Fatalf("unban user: status %d", status)
+	}
+	if len(bans) != 0 {
+		t.Fatalf("bans %+v after unbanning, want none", bans)
+	}
+	if bot.IsUserBanned(apiUserID) {
+		t.Fatal("user is still banned")
+	}
+}
+
+func TestAPIStates(t *testing.T) {
+	bot, s := newAPIServer(t)
+	if err := bot.SetUserState(apiUserID, "menu"); err != nil {
+		t.Fatal(err)
+	}
+
+	var states []admin.StateInfo
+	if status := call(t, s, http.MethodGet, "/api/states", "", &states); status != http.StatusOK {
+		t.Fatalf("status %d", status)
+	}
+	if len(states) != 3 || states[1].Name != "menu" || states[1].Users != 1 {
+		t.Fatalf("states %+v, want three states with one user in menu", states)
+	}
+
+	var users []int64
+	if status := call(t, s, http.MethodGet, "/api/states/menu/users", "", &users); status != http.StatusOK {
+		t.Fatalf("status %d", status)
+	}
+	if len(users) != 1 || users[0] != apiUserID {
+		t.Fatalf("users %v, want [42]", users)
+	}
+	if status := call(t, s, http.MethodGet, "/api/states/missing/users", "", nil); status != http.StatusNotFound {
+		t.Fatalf("unknown state: status %d, want %d", status, http.StatusNotFound)
+	}
+	if status := call(t, s, http.MethodGet, "/api/unknown", "", nil); status != http.StatusNotFound {
+		t.Fatalf("unknown path: status %d, want %d", status, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,207 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"tgfsm"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Statuses of checks and reports
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckResult is the result of a check in a probe report
+type CheckResult struct {
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report is the body of probe responses
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+	Health tgfsm.Health           `json:"health"`
+}
+
+// serveLiveness reports whether update polling works
+func (s *Server) serveLiveness(w http.ResponseWriter, r *http.Request) {
+	health := s.bot.Health()
+	report := newReport(health)
+	report.add("polling", s.checkPolling(health))
+
+	writeReport(w, report)
+}
+
+// serveReadiness reports whether the bot can process updates
+func (s *Server) serveReadiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), s.checkTimeout)
+	defer cancel()
+
+	health := s.bot.Health()
+	report := newReport(health)
+	report.add("polling", s.checkPolling(health))
+	report.add("pending", s.checkPending(health))
+
+	checks := []namedCheck{{name: "storage", check: s.bot.CheckStorage}}
+	if s.checkWebhook {
+		checks = append(checks, namedCheck{name: "webhook", check: func(ctx context.Context) error {
+			return s.checkWebhookInfo(ctx, health)
+		}})
+	}
+	checks = append(checks, s.checks...)
+	for name, err := range runChecks(ctx, checks) {
+		report.add(name, err)
+	}
+
+	writeReport(w, report)
+}
+
+// checkPolling checks that the polling loop is alive and receives updates
+// A bot that does not poll (webhooks, ProcessUpdate) passes the check.
+func (s *Server) checkPolling(health tgfsm.Health) error {
+	if !health.Polling {
+		return nil
+	}
+	if !health.PollingAlive {
+		return fmt.Errorf("polling loop is not running")
+	}
+
+	last := health.LastPoll
+	if last.Before(health.PollingSince) {
+		last = health.PollingSince
+	}
+	if age := time.Since(last); age > s.maxPollAge {
+		if health.LastPollError != "" {
+			return fmt.Errorf("no successful getUpdates for %s: %s", age.Round(time.Second), health.LastPollError)
+		}
+		return fmt.Errorf("no successful getUpdates for %s", age.Round(time.Second))
+	}
+	return nil
+}
+
+// checkPending checks that the bot keeps up with incoming updates
+func (s *Server) checkPending(health tgfsm.Health) error {
+	pending := health.QueueDepth + health.InFlight
+	if s.maxPending > 0 && pending > s.maxPending {
+		return fmt.Errorf("%d pending updates, limit %d", pending, s.maxPending)
+	}
+	return nil
+}
+
+// checkWebhookInfo checks that the webhook status matches the way the bot receives updates:
+// a polling bot must have no webhook, otherwise getUpdates fails; a webhook must deliver updates
+func (s *Server) checkWebhookInfo(ctx context.Context, health tgfsm.Health) error {
+	info, err := s.webhookStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("getWebhookInfo: %w", err)
+	}
+
+	switch {
+	case health.Polling && info.URL != "":
+		return fmt.Errorf("webhook %s is set while polling updates", info.URL)
+	case !health.Polling && info.URL == "":
+		return fmt.Errorf("no webhook is set and updates are not polled")
+	case info.URL != "" && info.LastErrorDate != 0 && time.Since(time.Unix(int64(info.LastErrorDate), 0)) < s.maxPollAge:
+		return fmt.Errorf("webhook delivery failed: %s", info.LastErrorMessage)
+	}
+	return nil
+}
+
+// webhookStatus returns the webhook status, reusing it for the webhook cache TTL
+// Failed requests are not cached. Concurrent probes wait for a single request.
+func (s *Server) webhookStatus(ctx context.Context) (tgbotapi.WebhookInfo, error) {
+	s.webhookMu.Lock()
+	defer s.webhookMu.Unlock()
+
+	if !s.webhookCheckedAt.IsZero() && time.Since(s.webhookCheckedAt) < s.webhookTTL {
+		return s.webhookInfo, nil
+	}
+
+	info, err := s.bot.WebhookInfoContext(ctx)
+	if err != nil {
+		return tgbotapi.WebhookInfo{}, err
+	}
+	s.webhookInfo = info
+	s.webhookCheckedAt = time.Now()
+	return info, nil
+}
+
+// runChecks runs the checks concurrently; checks not finished before ctx is done fail
+func runChecks(ctx context.Context, checks []namedCheck) map[string]error {
+	var mu sync.Mutex
+	results := make(map[string]error, len(checks))
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check namedCheck) {
+			defer wg.Done()
+			err := check.check(ctx)
+			mu.Lock()
+			results[check.name] = err
+			mu.Unlock()
+		}(check)
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	finished := make(map[string]error, len(checks))
+	for _, check := range checks {
+		err, ok := results[check.name]
+		if !ok {
+			err = fmt.Errorf("timed out: %w", ctx.Err())
+		}
+		finished[check.name] = err
+	}
+	return finished
+}
+
+// newReport creates a passing report
+func newReport(health tgfsm.Health) *Report {
+	return &Report{Status: StatusOK, Checks: make(map[string]CheckResult), Health: health}
+}
+
+// add adds the result of a check, a failed check fails the report
+func (r *Report) add(name string, err error) {
+	if err != nil {
+		r.Status = StatusFail
+		r.Checks[name] = CheckResult{Status: StatusFail, Detail: err.Error()}
+		return
+	}
+	r.Checks[name] = CheckResult{Status: StatusOK}
+}
+
+// writeReport writes the report, with status 503 if it failed
+func writeReport(w http.ResponseWriter, report *Report) {
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+// writeJSON writes the value as a JSON response
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
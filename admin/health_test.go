@@ -0,0 +1,107 @@
+package admin_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"tgfsm"
+	"tgfsm/admin"
+	"tgfsm/tgfsmtest"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// newWebhookBot creates a bot with a webhook set on the test server
+func newWebhookBot(t *testing.T, options ...tgfsm.Option) (*tgfsmtest.Server, *tgfsm.Bot) {
+	t.Helper()
+
+	server := tgfsmtest.NewServer()
+	t.Cleanup(server.Close)
+	server.SetResponse("getWebhookInfo", tgbotapi.WebhookInfo{URL: "https://example.com/hook"})
+
+	bot, err := tgfsmtest.NewBot(server, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, bot
+}
+
+// probe requests the readiness probe and returns the status code
+func probe(s *admin.Server) int {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	return rec.Code
+}
+
+func TestReadinessWebhookCache(t *testing.T) {
+	tests := []struct {
+		name  string
+		ttl   time.Duration
+		calls int
+	}{
+		{name: "cached", ttl: time.Minute, calls: 1},
+		{name: "disabled", ttl: 0, calls: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, bot := newWebhookBot(t)
+			s := admin.New(bot, admin.WithWebhookCacheTTL(tt.ttl))
+
+			for i := 0; i < 3; i++ {
+				if code := probe(s); code != http.StatusOK {
+					t.Fatalf("probe %d: status %d, want %d", i, code, http.StatusOK)
+				}
+			}
+			if calls := len(server.Calls("getWebhookInfo")); calls != tt.calls {
+				t.Errorf("getWebhookInfo called %d times, want %d", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestReadinessWebhookFailureNotCached(t *testing.T) {
+	server, bot := newWebhookBot(t)
+
+	// A limiter without tokens left makes the check time out
+	limiter := tgfsm.NewLimiter(tgfsm.LimiterAPIRequestLimit(1, time.Hour, 1))
+	limiter.WaitForAPI(context.Background())
+	if err := bot.UpdateBot(tgfsm.WithLimiter(limiter)); err != nil {
+		t.Fatal(err)
+	}
+	s := admin.New(bot, admin.WithCheckTimeout(50*time.Millisecond))
+	if code := probe(s); code != http.StatusServiceUnavailable {
+		t.Fatalf("status %d, want %d", code, http.StatusServiceUnavailable)
+	}
+	if calls := len(server.Calls("getWebhookInfo")); calls != 0 {
+		t.Fatalf("getWebhookInfo called %d times while the limiter waits", calls)
+	}
+
+	if err := bot.UpdateBot(tgfsm.WithLimiter(tgfsm.NewLimiter())); err != nil {
+		t.Fatal(err)
+	}
+	if code := probe(s); code != http.StatusOK {
+		t.Fatalf("status %d after the limiter was replaced, want %d", code, http.StatusOK)
+	}
+}
+
+func TestWebhookInfoContext(t *testing.T) {
+	limiter := tgfsm.NewLimiter(tgfsm.LimiterAPIRequestLimit(1, time.Hour, 1))
+	limiter.WaitForAPI(context.Background())
+	_, bot := newWebhookBot(t, tgfsm.WithLimiter(limiter))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := bot.WebhookInfoContext(ctx)
+	if err == nil {
+		t.Fatal("WebhookInfoContext returned no error while the limiter waits")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("WebhookInfoContext returned after %s, want it to stop with the context", elapsed)
+	}
+}
@@ -0,0 +1,106 @@
+package tgfsm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// apiMethods are the Bot API methods of the tgbotapi configs
+// tgbotapi keeps the method of a config unexported (Chattable.method), so it is listed here
+// for the configs of the tgbotapi version in go.mod.
+var apiMethods = map[reflect.Type]string{
+	reflect.TypeOf(tgbotapi.LogOutConfig{}):                    "logOut",
+	reflect.TypeOf(tgbotapi.CloseConfig{}):                     "close",
+	reflect.TypeOf(tgbotapi.MessageConfig{}):                   "sendMessage",
+	reflect.TypeOf(tgbotapi.ForwardConfig{}):                   "forwardMessage",
+	reflect.TypeOf(tgbotapi.CopyMessageConfig{}):               "copyMessage",
+	reflect.TypeOf(tgbotapi.PhotoConfig{}):                     "sendPhoto",
+	reflect.TypeOf(tgbotapi.AudioConfig{}):                     "sendAudio",
+	reflect.TypeOf(tgbotapi.DocumentConfig{}):                  "sendDocument",
+	reflect.TypeOf(tgbotapi.StickerConfig{}):                   "sendSticker",
+	reflect.TypeOf(tgbotapi.VideoConfig{}):                     "sendVideo",
+	reflect.TypeOf(tgbotapi.AnimationConfig{}):                 "sendAnimation",
+	reflect.TypeOf(tgbotapi.VideoNoteConfig{}):                 "sendVideoNote",
+	reflect.TypeOf(tgbotapi.VoiceConfig{}):                     "sendVoice",
+	reflect.TypeOf(tgbotapi.LocationConfig{}):                  "sendLocation",
+	reflect.TypeOf(tgbotapi.EditMessageLiveLocationConfig{}):   "editMessageLiveLocation",
+	reflect.TypeOf(tgbotapi.StopMessageLiveLocationConfig{}):   "stopMessageLiveLocation",
+	reflect.TypeOf(tgbotapi.VenueConfig{}):                     "sendVenue",
+	reflect.TypeOf(tgbotapi.ContactConfig{}):                   "sendContact",
+	reflect.TypeOf(tgbotapi.SendPollConfig{}):                  "sendPoll",
+	reflect.TypeOf(tgbotapi.GameConfig{}):                      "sendGame",
+	reflect.TypeOf(tgbotapi.SetGameScoreConfig{}):              "setGameScore",
+	reflect.TypeOf(tgbotapi.GetGameHighScoresConfig{}):         "getGameHighScores",
+	reflect.TypeOf(tgbotapi.ChatActionConfig{}):                "sendChatAction",
+	reflect.TypeOf(tgbotapi.EditMessageTextConfig{}):           "editMessageText",
+	reflect.TypeOf(tgbotapi.EditMessageCaptionConfig{}):        "editMessageCaption",
+	reflect.TypeOf(tgbotapi.EditMessageMediaConfig{}):          "editMessageMedia",
+	reflect.TypeOf(tgbotapi.EditMessageReplyMarkupConfig{}):    "editMessageReplyMarkup",
+	reflect.TypeOf(tgbotapi.StopPollConfig{}):                  "stopPoll",
+	reflect.TypeOf(tgbotapi.UserProfilePhotosConfig{}):         "getUserProfilePhotos",
+	reflect.TypeOf(tgbotapi.FileConfig{}):                      "getFile",
+	reflect.TypeOf(tgbotapi.UpdateConfig{}):                    "getUpdates",
+	reflect.TypeOf(tgbotapi.WebhookConfig{}):                   "setWebhook",
+	reflect.TypeOf(tgbotapi.DeleteWebhookConfig{}):             "deleteWebhook",
+	reflect.TypeOf(tgbotapi.InlineConfig{}):                    "answerInlineQuery",
+	reflect.TypeOf(tgbotapi.CallbackConfig{}):                  "answerCallbackQuery",
+	reflect.TypeOf(tgbotapi.UnbanChatMemberConfig{}):           "unbanChatMember",
+	reflect.TypeOf(tgbotapi.BanChatMemberConfig{}):             "banChatMember",
+	reflect.TypeOf(tgbotapi.RestrictChatMemberConfig{}):        "restrictChatMember",
+	reflect.TypeOf(tgbotapi.PromoteChatMemberConfig{}):         "promoteChatMember",
+	reflect.TypeOf(tgbotapi.SetChatAdministratorCustomTitle{}): "setChatAdministratorCustomTitle",
+	reflect.TypeOf(tgbotapi.BanChatSenderChatConfig{}):         "banChatSenderChat",
+	reflect.TypeOf(tgbotapi.UnbanChatSenderChatConfig{}):       "unbanChatSenderChat",
+	reflect.TypeOf(tgbotapi.ChatInfoConfig{}):                  "getChat",
+	reflect.TypeOf(tgbotapi.ChatMemberCountConfig{}):           "getChatMembersCount",
+	reflect.TypeOf(tgbotapi.ChatAdministratorsConfig{}):        "getChatAdministrators",
+	reflect.TypeOf(tgbotapi.SetChatPermissionsConfig{}):        "setChatPermissions",
+	reflect.TypeOf(tgbotapi.ChatInviteLinkConfig{}):            "exportChatInviteLink",
+	reflect.TypeOf(tgbotapi.CreateChatInviteLinkConfig{}):      "createChatInviteLink",
+	reflect.TypeOf(tgbotapi.EditChatInviteLinkConfig{}):        "editChatInviteLink",
+	reflect.TypeOf(tgbotapi.RevokeChatInviteLinkConfig{}):      "revokeChatInviteLink",
+	reflect.TypeOf(tgbotapi.ApproveChatJoinRequestConfig{}):    "approveChatJoinRequest",
+	reflect.TypeOf(tgbotapi.DeclineChatJoinRequest{}):          "declineChatJoinRequest",
+	reflect.TypeOf(tgbotapi.LeaveChatConfig{}):                 "leaveChat",
+	reflect.TypeOf(tgbotapi.GetChatMemberConfig{}):             "getChatMember",
+	reflect.TypeOf(tgbotapi.InvoiceConfig{}):                   "sendInvoice",
+	reflect.TypeOf(tgbotapi.ShippingConfig{}):                  "answerShippingQuery",
+	reflect.TypeOf(tgbotapi.PreCheckoutConfig{}):               "answerPreCheckoutQuery",
+	reflect.TypeOf(tgbotapi.DeleteMessageConfig{}):             "deleteMessage",
+	reflect.TypeOf(tgbotapi.PinChatMessageConfig{}):            "pinChatMessage",
+	reflect.TypeOf(tgbotapi.UnpinChatMessageConfig{}):          "unpinChatMessage",
+	reflect.TypeOf(tgbotapi.UnpinAllChatMessagesConfig{}):      "unpinAllChatMessages",
+	reflect.TypeOf(tgbotapi.SetChatPhotoConfig{}):              "setChatPhoto",
+	reflect.TypeOf(tgbotapi.DeleteChatPhotoConfig{}):           "deleteChatPhoto",
+	reflect.TypeOf(tgbotapi.SetChatTitleConfig{}):              "setChatTitle",
+	reflect.TypeOf(tgbotapi.SetChatDescriptionConfig{}):        "setChatDescription",
+	reflect.TypeOf(tgbotapi.GetStickerSetConfig{}):             "getStickerSet",
+	reflect.TypeOf(tgbotapi.UploadStickerConfig{}):             "uploadStickerFile",
+	reflect.TypeOf(tgbotapi.NewStickerSetConfig{}):             "createNewStickerSet",
+	reflect.TypeOf(tgbotapi.AddStickerConfig{}):                "addStickerToSet",
+	reflect.TypeOf(tgbotapi.SetStickerPositionConfig{}):        "setStickerPositionInSet",
+	reflect.TypeOf(tgbotapi.DeleteStickerConfig{}):             "deleteStickerFromSet",
+	reflect.TypeOf(tgbotapi.SetStickerSetThumbConfig{}):        "setStickerSetThumb",
+	reflect.TypeOf(tgbotapi.SetChatStickerSetConfig{}):         "setChatStickerSet",
+	reflect.TypeOf(tgbotapi.DeleteChatStickerSetConfig{}):      "deleteChatStickerSet",
+	reflect.TypeOf(tgbotapi.MediaGroupConfig{}):                "sendMediaGroup",
+	reflect.TypeOf(tgbotapi.DiceConfig{}):                      "sendDice",
+	reflect.TypeOf(tgbotapi.GetMyCommandsConfig{}):             "getMyCommands",
+	reflect.TypeOf(tgbotapi.SetMyCommandsConfig{}):             "setMyCommands",
+	reflect.TypeOf(tgbotapi.DeleteMyCommandsConfig{}):          "deleteMyCommands",
+}
+
+// callName returns the Bot API method of the call by its config: tgbotapi.MessageConfig -> "sendMessage"
+// Configs unknown to apiMethods are named by their type: "CustomConfig" -> "Custom".
+func callName(config interface{}) string {
+	if method, ok := apiMethods[reflect.TypeOf(config)]; ok {
+		return method
+	}
+
+	name := fmt.Sprintf("%T", config)
+	name = name[strings.LastIndex(name, ".")+1:]
+	return strings.TrimSuffix(name, "Config")
+}
@@ -0,0 +1,346 @@
+package tgfsm
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BanKind is the kind of a banned ID
+type BanKind string
+
+// Kinds of bans
+const (
+	BanChat BanKind = "chat" // Updates from the chat are ignored
+	BanUser BanKind = "user" // Updates from the user are ignored in any chat
+)
+
+// Ban is an entry of the blacklist
+type Ban struct {
+	Kind   BanKind `json:"kind"`
+	ID     int64   `json:"id"` // Chat or user ID
+	Reason string  `json:"reason,omitempty"`
+	// Until is the time the ban expires, zero for permanent bans
+	Until   time.Time `json:"until"`
+	Created time.Time `json:"created"`
+}
+
+// Active reports whether the ban has not expired at the time
+func (b Ban) Active(now time.Time) bool {
+	return b.Until.IsZero() || now.Before(b.Until)
+}
+
+// banKey identifies a ban in the blacklist
+type banKey struct {
+	kind BanKind
+	id   int64
+}
+
+// Kinds of blacklist events
+const (
+	BlacklistAdded   = "added"   // A ban was added or replaced
+	BlacklistRemoved = "removed" // A ban was removed
+	BlacklistResync  = "resync"  // Events may have been lost, the blacklist must be loaded again
+)
+
+// BlacklistEvent is a change of the blacklist in a BlacklistStore
+// Ban has only Kind and ID set for removed bans and is empty for BlacklistResync.
+type BlacklistEvent struct {
+	Type string `json:"type"`
+	Ban  Ban    `json:"ban"`
+}
+
+// BlacklistHook is called for changes of the blacklist store (see WithBlacklistHook)
+type BlacklistHook func(b *Bot, event BlacklistEvent)
+
+// BlacklistStore stores the blacklist (see WithBlacklistStore)
+// The bot keeps a copy of the bans in memory to filter updates and keeps it in sync
+// with the store through Watch, so bots sharing a store see each other's changes.
+// Implementations must be safe for concurrent use; the package tgfsm/cache provides
+// Redis and PostgreSQL implementations, NewMemoryBlacklistStore is used by default.
+type BlacklistStore interface {
+	// AddBan adds the ban or replaces the ban of the same chat or user
+	AddBan(ban Ban) error
+	// RemoveBan removes the ban, removing a missing ban is not an error
+	RemoveBan(kind BanKind, id int64) error
+	// Bans returns the bans that have not expired
+	Bans() ([]Ban, error)
+	// Watch calls fn for changes of the store made by any bot until stop is called
+	Watch(fn func(BlacklistEvent)) (stop func(), err error)
+}
+
+// MemoryBlacklistStore is a BlacklistStore keeping bans in memory
+// Bans are lost on restart and shared only by bots of the process using the same store.
+type MemoryBlacklistStore struct {
+	mu       sync.Mutex
+	bans     map[banKey]Ban
+	watchers map[int]func(BlacklistEvent)
+	nextID   int
+}
+
+// Ensure MemoryBlacklistStore implements BlacklistStore interface
+var _ BlacklistStore = (*MemoryBlacklistStore)(nil)
+
+// NewMemoryBlacklistStore creates an in-memory blacklist store
+func NewMemoryBlacklistStore() *MemoryBlacklistStore {
+	return &MemoryBlacklistStore{
+		bans:     make(map[banKey]Ban),
+		watchers: make(map[int]func(BlacklistEvent)),
+	}
+}
+
+// AddBan adds the ban or replaces the ban of the same chat or user
+func (s *MemoryBlacklistStore) AddBan(ban Ban) error {
+	s.mu.Lock()
+	s.bans[banKey{ban.Kind, ban.ID}] = ban
+	s.mu.Unlock()
+
+	s.notify(BlacklistEvent{Type: BlacklistAdded, Ban: ban})
+	return nil
+}
+
+// RemoveBan removes the ban
+func (s *MemoryBlacklistStore) RemoveBan(kind BanKind, id int64) error {
+	s.mu.Lock()
+	delete(s.bans, banKey{kind, id})
+	s.mu.Unlock()
+
+	s.notify(BlacklistEvent{Type: BlacklistRemoved, Ban: Ban{Kind: kind, ID: id}})
+	return nil
+}
+
+// Bans returns the bans that have not expired, expired bans are deleted
+func (s *MemoryBlacklistStore) Bans() ([]Ban, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	bans := make([]Ban, 0, len(s.bans))
+	for key, ban := range s.bans {
+		if !ban.Active(now) {
+			delete(s.bans, key)
+			continue
+		}
+		bans = append(bans, ban)
+	}
+	return bans, nil
+}
+
+// Watch calls fn for changes of the store until stop is called
+func (s *MemoryBlacklistStore) Watch(fn func(BlacklistEvent)) (func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID
+	s.nextID++
+	s.watchers[id] = fn
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.watchers, id)
+	}, nil
+}
+
+// notify calls the watchers with the event
+func (s *MemoryBlacklistStore) notify(event BlacklistEvent) {
+	s.mu.Lock()
+	watchers := make([]func(BlacklistEvent), 0, len(s.watchers))
+	for _, fn := range s.watchers {
+		watchers = append(watchers, fn)
+	}
+	s.mu.Unlock()
+
+	for _, fn := range watchers {
+		fn(event)
+	}
+}
+
+// syncBlacklist subscribes to a new blacklist store and loads its bans
+// together with the chats set by WithBlacklistedChats
+func (b *Bot) syncBlacklist() error {
+	if b.blacklistStore == nil {
+		b.blacklistStore = NewMemoryBlacklistStore()
+		b.blacklistStoreChanged = true
+	}
+
+	if b.blacklistStoreChanged {
+		if b.stopBlacklistWatch != nil {
+			b.stopBlacklistWatch()
+			b.stopBlacklistWatch = nil
+		}
+		// Subscribe before loading, so changes made in between are not lost
+		stop, err := b.blacklistStore.Watch(b.applyBlacklistEvent)
+		if err != nil {
+			return NewSFMError(ErrBlacklistStore, err)
+		}
+		b.stopBlacklistWatch = stop
+		b.blacklistStoreChanged = false
+	}
+
+	// Loaded on every update of the configuration, the chats may have changed
+	return b.loadBlacklist()
+}
+
+// loadBlacklist replaces the bans in memory with the bans of the store
+// and the chats set by WithBlacklistedChats
+func (b *Bot) loadBlacklist() error {
+	bans, err := b.blacklistStore.Bans()
+	if err != nil {
+		return NewSFMError(ErrBlacklistStore, err)
+	}
+
+	loaded := make(map[banKey]Ban, len(bans))
+	for _, ban := range bans {
+		loaded[banKey{ban.Kind, ban.ID}] = ban
+	}
+
+	b.blacklistMu.Lock()
+	defer b.blacklistMu.Unlock()
+
+	now := time.Now()
+	for _, chatID := range b.blacklistedChats {
+		// A ban of the store may have a reason, it is kept
+		if _, ok := loaded[banKey{BanChat, chatID}]; !ok {
+			loaded[banKey{BanChat, chatID}] = Ban{Kind: BanChat, ID: chatID, Created: now}
+		}
+	}
+	b.bans = loaded
+	return nil
+}
+
+// applyBlacklistEvent applies a change of the store to the bans in memory
+func (b *Bot) applyBlacklistEvent(event BlacklistEvent) {
+	switch event.Type {
+	case BlacklistAdded:
+		b.setBan(event.Ban)
+	case BlacklistRemoved:
+		b.deleteBan(event.Ban.Kind, event.Ban.ID)
+	case BlacklistResync:
+		if err := b.loadBlacklist(); err != nil {
+			b.Logger().Error("failed to reload blacklist", ErrorField(err))
+		}
+	}
+
+	if b.blacklistHook != nil {
+		b.blacklistHook(b, event)
+	}
+}
+
+// setBan stores the ban in memory
+func (b *Bot) setBan(ban Ban) {
+	b.blacklistMu.Lock()
+	defer b.blacklistMu.Unlock()
+	b.bans[banKey{ban.Kind, ban.ID}] = ban
+}
+
+// deleteBan deletes the ban from memory
+// Chats set by WithBlacklistedChats stay banned: they are not in the store,
+// so a removal made by another bot does not apply to them.
+func (b *Bot) deleteBan(kind BanKind, id int64) {
+	b.blacklistMu.Lock()
+	defer b.blacklistMu.Unlock()
+
+	if kind == BanChat && slices.Contains(b.blacklistedChats, id) {
+		b.bans[banKey{kind, id}] = Ban{Kind: kind, ID: id, Created: time.Now()}
+		return
+	}
+	delete(b.bans, banKey{kind, id})
+}
+
+// Ban adds the ban to the blacklist store, Created is set to now if it is zero
+// Can be called while bot is running (uses blacklistMu)
+func (b *Bot) Ban(ban Ban) error {
+	if ban.Created.IsZero() {
+		ban.Created = time.Now()
+	}
+	if err := b.blacklistStore.AddBan(ban); err != nil {
+		return NewSFMError(ErrBlacklistStore, err)
+	}
+	// Stores may notify asynchronously, the ban applies immediately
+	b.setBan(ban)
+	return nil
+}
+
+// BanChat bans the chat for the duration, 0 for a permanent ban
+func (b *Bot) BanChat(chatID int64, reason string, duration time.Duration) error {
+	return b.Ban(newBan(BanChat, chatID, reason, duration))
+}
+
+// BanUser bans the user in all chats for the duration, 0 for a permanent ban
+func (b *Bot) BanUser(userID int64, reason string, duration time.Duration) error {
+	return b.Ban(newBan(BanUser, userID, reason, duration))
+}
+
+// newBan creates a ban starting now
+func newBan(kind BanKind, id int64, reason string, duration time.Duration) Ban {
+	now := time.Now()
+	ban := Ban{Kind: kind, ID: id, Reason: reason, Created: now}
+	if duration > 0 {
+		ban.Until = now.Add(duration)
+	}
+	return ban
+}
+
+// Unban removes the ban of the chat or user from the blacklist store
+// A chat set by WithBlacklistedChats is unbanned by this bot until the option is set again.
+// Can be called while bot is running (uses blacklistMu)
+func (b *Bot) Unban(kind BanKind, id int64) error {
+	if err := b.blacklistStore.RemoveBan(kind, id); err != nil {
+		return NewSFMError(ErrBlacklistStore, err)
+	}
+
+	b.blacklistMu.Lock()
+	if kind == BanChat {
+		b.blacklistedChats = slices.DeleteFunc(b.blacklistedChats, func(chatID int64) bool {
+			return chatID == id
+		})
+	}
+	b.blacklistMu.Unlock()
+
+	b.deleteBan(kind, id)
+	return nil
+}
+
+// GetBan returns the active ban of the chat or user
+// Can be called while bot is running (uses blacklistMu)
+func (b *Bot) GetBan(kind BanKind, id int64) (Ban, bool) {
+	b.blacklistMu.RLock()
+	defer b.blacklistMu.RUnlock()
+
+	ban, ok := b.bans[banKey{kind, id}]
+	if !ok || !ban.Active(time.Now()) {
+		return Ban{}, false
+	}
+	return ban, true
+}
+
+// Bans returns the active bans, chats first, by ID
+// Can be called while bot is running (uses blacklistMu)
+func (b *Bot) Bans() []Ban {
+	b.blacklistMu.RLock()
+	defer b.blacklistMu.RUnlock()
+
+	now := time.Now()
+	bans := make([]Ban, 0, len(b.bans))
+	for _, ban := range b.bans {
+		if ban.Active(now) {
+			bans = append(bans, ban)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		if bans[i].Kind != bans[j].Kind {
+			return bans[i].Kind == BanChat
+		}
+		return bans[i].ID < bans[j].ID
+	})
+	return bans
+}
+
+// IsUserBanned checks if the user is banned
+// Can be called while bot is running (uses blacklistMu)
+func (b *Bot) IsUserBanned(userID int64) bool {
+	_, banned := b.GetBan(BanUser, userID)
+	return banned
+}
@@ -0,0 +1,49 @@
+package tgfsm
+
+import "testing"
+
+func TestBlacklistedChatsStayOutOfStore(t *testing.T) {
+	store := NewMemoryBlacklistStore()
+	b := &Bot{
+		bans:             make(map[banKey]Ban),
+		blacklistStore:   store,
+		blacklistedChats: []int64{-100},
+	}
+	b.blacklistStoreChanged = true
+	if err := b.syncBlacklist(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	defer b.stopBlacklistWatch()
+
+	if _, ok := b.GetBan(BanChat, -100); !ok {
+		t.Fatal("chat of WithBlacklistedChats is not banned")
+	}
+	if bans, _ := store.Bans(); len(bans) != 0 {
+		t.Fatalf("store has bans %v, want none", bans)
+	}
+
+	// Another bot bans and unbans the chat in the shared store
+	if err := store.AddBan(Ban{Kind: BanChat, ID: -100, Reason: "spam"}); err != nil {
+		t.Fatalf("ban failed: %v", err)
+	}
+	if err := store.RemoveBan(BanChat, -100); err != nil {
+		t.Fatalf("unban failed: %v", err)
+	}
+	if _, ok := b.GetBan(BanChat, -100); !ok {
+		t.Fatal("chat of WithBlacklistedChats unbanned by another bot")
+	}
+
+	// A resync keeps the chat banned
+	b.applyBlacklistEvent(BlacklistEvent{Type: BlacklistResync})
+	if _, ok := b.GetBan(BanChat, -100); !ok {
+		t.Fatal("chat of WithBlacklistedChats unbanned by resync")
+	}
+
+	if err := b.Unban(BanChat, -100); err != nil {
+		t.Fatalf("unban failed: %v", err)
+	}
+	b.applyBlacklistEvent(BlacklistEvent{Type: BlacklistResync})
+	if _, ok := b.GetBan(BanChat, -100); ok {
+		t.Fatal("chat still banned after Unban")
+	}
+}
@@ -1,14 +1,16 @@
 package tgfsm
 
 import (
+	"errors"
+	"net/http"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	gocache "github.com/patrickmn/go-cache"
-	"go.uber.org/zap"
 )
 
 const (
@@ -19,21 +21,54 @@ const (
 
 // Bot represents the bot instance
 type Bot struct {
-	BotAPI            *tgbotapi.BotAPI // Bot API. Exported for external access
-	expiration        time.Duration    // User state storage duration
-	cleanupInterval   time.Duration    // Cache cleanup interval
-	limiter           *Limiter         // Limiter for API request rate limiting
-	cache             *gocache.Cache   // Cache for storing user states
-	logger            *zap.Logger      // Logger for recording events
-	states            map[string]State // User states
-	globalStates      []*State         // States that user can transition to from any other state
-	updateHandler     HandlerFunc      // Handler that will be called when receiving any update
-	privateOnly       bool             // Only accept messages from private chats
-	blacklistedChats  map[int64]bool   // Blacklisted chat IDs
-	mu                sync.RWMutex     // Mutex for bot state (start/stop/update)
-	blacklistMu       sync.RWMutex     // Mutex for blacklist operations
-	autoDeleteEnabled bool             // Enable auto-deletion of last message
-	lastMessageCache  LastMessageCache // Cache for last message IDs
+	BotAPI                *tgbotapi.BotAPI                // Bot API. Exported for external access, nil if a custom client is used
+	client                Client                          // Telegram Bot API client used by the bot
+	metrics               Metrics                         // Receiver of monitoring events
+	tracer                Tracer                          // Tracer of update processing and API calls
+	traces                map[int64]*updateTrace          // Spans of updates being processed by chat ID
+	updateTraces          map[int]*updateTrace            // Spans of updates being processed by update ID
+	tracesMu              sync.Mutex                      // Mutex for update spans
+	expiration            time.Duration                   // User state storage duration
+	cleanupInterval       time.Duration                   // Cache cleanup interval
+	limiter               *Limiter                        // Limiter for API request rate limiting
+	apiEndpoint           string                          // Bot API endpoint, tgbotapi.APIEndpoint if empty
+	httpClient            *http.Client                    // HTTP client for Bot API requests
+	cache                 *gocache.Cache                  // Cache for storing user states
+	logger                Logger                          // Logger for recording events
+	states                map[string]State                // User states
+	initialState          string                          // State of users who have no state stored
+	orphanState           string                          // State of users whose state was removed by Reload
+	current               atomic.Pointer[stateSet]        // Current states configuration, replaced by Reload
+	live                  atomic.Pointer[runtimeSettings] // Current runtime options, replaced by Reload
+	reloadMu              sync.Mutex                      // Mutex for states reload
+	reloadableOption      bool                            // Set by the options accepted by Reload (see reloadable)
+	updateHandler         HandlerFunc                     // Handler that will be called when receiving any update
+	privateOnly           bool                            // Only accept messages from private chats
+	allowedUsers          map[int64]bool                  // Users served in allowlist mode
+	allowedRoles          []string                        // Roles of users served in allowlist mode
+	roleProviders         []RoleProvider                  // Sources of user roles
+	accessDenied          AccessDeniedFunc                // Handler of updates denied by the allowlist or roles
+	flood                 *floodLimiter                   // Limit of incoming updates set by WithFloodControl
+	blacklistedChats      []int64                         // Chats banned in memory only, set by WithBlacklistedChats
+	blacklistStore        BlacklistStore                  // Storage of the blacklist shared by bots
+	bans                  map[banKey]Ban                  // Copy of the blacklist store kept in sync by its events
+	blacklistHook         BlacklistHook                   // Called for changes of the blacklist store
+	stopBlacklistWatch    func()                          // Stops watching the blacklist store
+	blacklistStoreChanged bool                            // The blacklist store was set and is not watched yet
+	mu                    sync.RWMutex                    // Mutex for bot state (start/stop/update)
+	blacklistMu           sync.RWMutex                    // Mutex for blacklist operations
+	autoDeleteEnabled     bool                            // Enable auto-deletion of last message
+	lastMessageCache      LastMessageCache                // Cache for last message IDs
+	maxStateHistory       int                             // Maximum number of previous states kept per user
+	stackMu               sync.Mutex                      // Mutex for state history operations
+	strictValidation      bool                            // Fail on states configuration problems instead of logging them
+	pollMu                sync.Mutex                      // Mutex for updates polling
+	stopPolling           chan struct{}                   // Closed to stop the current updates polling
+	pollHealth            pollHealth                      // State of the polling loop reported by Health
+	inFlight              atomic.Int64                    // Number of updates being processed
+	stateTimers           map[int64]*stateTimer           // Timeouts of user states with TTL
+	timerSeq              uint64                          // Sequence number of the last scheduled state timeout
+	timersMu              sync.Mutex                      // Mutex for state timeouts
 }
 
 // NewBot creates a new bot instance
@@ -65,25 +100,46 @@ func NewBot(token string, options ...Option) (*Bot, error) {
 	if app.cleanupInterval < 0 {
 		return nil, NewSFMError(ErrNegativeCleanup, app.cleanupInterval)
 	}
+	if app.maxStateHistory < 0 {
+		return nil, NewSFMError(ErrNegativeStateHistory, app.maxStateHistory)
+	}
 
-	// Initialize Telegram Bot API
-	botAPI, err := tgbotapi.NewBotAPI(token)
-	if err != nil {
-		return nil, NewSFMError(ErrTelegramInit, err)
+	// Validate initial state and states configuration
+	if err := app.validateConfig(); err != nil {
+		return nil, err
 	}
-	app.BotAPI = botAPI
+
+	// Initialize Telegram Bot API unless a custom client is set
+	// A custom endpoint or HTTP client is used by local Bot API servers and tests
+	if app.client == nil {
+		var botAPI *tgbotapi.BotAPI
+		var err error
+		if app.apiEndpoint != "" || app.httpClient != nil {
+			endpoint := app.apiEndpoint
+			if endpoint == "" {
+				endpoint = tgbotapi.APIEndpoint
+			}
+			client := app.httpClient
+			if client == nil {
+				client = &http.Client{}
+			}
+			botAPI, err = tgbotapi.NewBotAPIWithClient(token, endpoint, client)
+		} else {
+			botAPI, err = tgbotapi.NewBotAPI(token)
+		}
+		if err != nil {
+			return nil, NewSFMError(ErrTelegramInit, err)
+		}
+		app.BotAPI = botAPI
+		app.client = botAPI
+	}
+	app.client = app.instrumentClient(app.client)
 
 	// Initialize cache with configured values
 	app.cache = gocache.New(app.expiration, app.cleanupInterval)
 
-	// Build global states map
-	globalStates := make([]*State, 0)
-	for _, state := range app.states {
-		if state.Global {
-			globalStates = append(globalStates, &state)
-		}
-	}
-	app.globalStates = globalStates
+	// Build states snapshot
+	app.current.Store(newStateSet(app.states, app.initialState, app.orphanState))
 
 	return &app, nil
 }
@@ -99,28 +155,60 @@ func (b *Bot) setDefaults() error {
 	if b.states == nil {
 		b.states = make(map[string]State)
 	}
-	if b.blacklistedChats == nil {
-		b.blacklistedChats = make(map[int64]bool)
+	if b.maxStateHistory == 0 {
+		b.maxStateHistory = DefaultMaxStateHistory
+	}
+	if b.metrics == nil {
+		b.metrics = nopMetrics{}
+	}
+	if b.tracer == nil {
+		b.tracer = nopTracer{}
+	}
+	if b.traces == nil {
+		b.traces = make(map[int64]*updateTrace)
+	}
+	if b.updateTraces == nil {
+		b.updateTraces = make(map[int]*updateTrace)
+	}
+	if b.stateTimers == nil {
+		b.stateTimers = make(map[int64]*stateTimer)
+	}
+	if b.bans == nil {
+		b.bans = make(map[banKey]Ban)
 	}
 	if b.logger == nil {
-		logger, err := NewZapLogger()
+		logger, err := newDefaultLogger()
 		if err != nil {
-			return NewSFMError(ErrTelegramInit, err)
+			return err
 		}
 		b.logger = logger
 	}
+	// Update processing reads the runtime options from the snapshot (see Reload)
+	b.live.Store(b.newRuntimeSettings())
 
-	return nil
+	return b.syncBlacklist()
+}
+
+// newDefaultLogger creates the logger of bots configured without one (see NewZapLogger)
+func newDefaultLogger() (Logger, error) {
+	logger, err := NewZapLogger()
+	if err != nil {
+		return nil, NewSFMError(ErrTelegramInit, err)
+	}
+	return NewZapAdapter(logger), nil
 }
 
 // UpdateBot updates bot configuration with new options
-// Can only be called when bot is not running
+// Can only be called when bot is not running; user states are kept
+// To change states of a running bot use Reload
 func (b *Bot) UpdateBot(options ...Option) error {
 	// Try to acquire write lock - this will fail if bot is running
 	if !b.mu.TryLock() {
 		return NewSFMError(ErrBotStarted, "cannot update running bot")
 	}
 	defer b.mu.Unlock()
+	b.reloadMu.Lock()
+	defer b.reloadMu.Unlock()
 
 	// Apply new options
 	for _, option := range options {
@@ -139,55 +227,64 @@ func (b *Bot) UpdateBot(options ...Option) error {
 	if b.cleanupInterval < 0 {
 		return NewSFMError(ErrNegativeCleanup, b.cleanupInterval)
 	}
+	if b.maxStateHistory < 0 {
+		return NewSFMError(ErrNegativeStateHistory, b.maxStateHistory)
+	}
+	if err := b.validateConfig(); err != nil {
+		return err
+	}
 
-	// Reinitialize cache with new values
-	b.cache = gocache.New(b.expiration, b.cleanupInterval)
+	// A client set by the options is instrumented like in NewBot
+	b.client = b.instrumentClient(b.client)
 
-	// Rebuild global states map
-	globalStates := make([]*State, 0)
-	for _, state := range b.states {
-		if state.Global {
-			globalStates = append(globalStates, &state)
-		}
+	// Reinitialize cache with new values, keeping user states
+	if b.cache != nil {
+		b.cache = gocache.NewFrom(b.expiration, b.cleanupInterval, b.cache.Items())
+	} else {
+		b.cache = gocache.New(b.expiration, b.cleanupInterval)
 	}
-	b.globalStates = globalStates
+
+	// Rebuild states snapshot
+	set := newStateSet(b.states, b.initialState, b.orphanState)
+	b.current.Store(set)
+	b.recoverOrphans(set)
 
 	return nil
 }
 
-// AddToBlacklist adds a chat ID to the blacklist
+// AddToBlacklist bans a chat permanently (see BanChat)
+// Errors of the blacklist store are logged
 // Can be called while bot is running (uses blacklistMu)
 func (b *Bot) AddToBlacklist(chatID int64) {
-	b.blacklistMu.Lock()
-	defer b.blacklistMu.Unlock()
-	b.blacklistedChats[chatID] = true
+	if err := b.BanChat(chatID, "", 0); err != nil {
+		b.Logger().Error("failed to add chat to blacklist", LogField("chat_id", chatID), ErrorField(err))
+	}
 }
 
-// RemoveFromBlacklist removes a chat ID from the blacklist
+// RemoveFromBlacklist removes the ban of a chat (see Unban)
+// Errors of the blacklist store are logged
 // Can be called while bot is running (uses blacklistMu)
 func (b *Bot) RemoveFromBlacklist(chatID int64) {
-	b.blacklistMu.Lock()
-	defer b.blacklistMu.Unlock()
-	delete(b.blacklistedChats, chatID)
+	if err := b.Unban(BanChat, chatID); err != nil {
+		b.Logger().Error("failed to remove chat from blacklist", LogField("chat_id", chatID), ErrorField(err))
+	}
 }
 
-// IsBlacklisted checks if a chat ID is blacklisted
+// IsBlacklisted checks if a chat ID is banned
 // Can be called while bot is running (uses blacklistMu)
 func (b *Bot) IsBlacklisted(chatID int64) bool {
-	b.blacklistMu.RLock()
-	defer b.blacklistMu.RUnlock()
-	return b.blacklistedChats[chatID]
+	_, banned := b.GetBan(BanChat, chatID)
+	return banned
 }
 
-// GetBlacklist returns a copy of the current blacklist
+// GetBlacklist returns the IDs of banned chats
 // Can be called while bot is running (uses blacklistMu)
 func (b *Bot) GetBlacklist() []int64 {
-	b.blacklistMu.RLock()
-	defer b.blacklistMu.RUnlock()
-
-	blacklist := make([]int64, 0, len(b.blacklistedChats))
-	for chatID := range b.blacklistedChats {
-		blacklist = append(blacklist, chatID)
+	var blacklist []int64
+	for _, ban := range b.Bans() {
+		if ban.Kind == BanChat {
+			blacklist = append(blacklist, ban.ID)
+		}
 	}
 	return blacklist
 }
@@ -200,6 +297,26 @@ func (b *Bot) IsPrivateOnly() bool {
 
 // shouldProcessUpdate determines if an update should be processed based on filters
 func (b *Bot) shouldProcessUpdate(update tgbotapi.Update) bool {
+	b.metrics.UpdateReceived(UpdateType(update))
+
+	if reason := b.filterUpdate(update); reason != "" {
+		b.metrics.UpdateFiltered(UpdateType(update), reason)
+		return false
+	}
+	// Flooding users are dropped before a goroutine is spawned for the update
+	flood := b.settings().flood
+	if violation, dropped := checkFlood(flood, update); dropped {
+		b.metrics.UpdateFiltered(UpdateType(update), FilterFlood)
+		if violation.Dropped == 1 || flood.control.Hook != nil {
+			go b.handleFlood(update, flood.control, violation)
+		}
+		return false
+	}
+	return true
+}
+
+// filterUpdate returns the reason to skip the update (see Filter* constants), empty if it passes the filters
+func (b *Bot) filterUpdate(update tgbotapi.Update) string {
 	// Check if update has a chat
 	var chatID int64
 	if update.Message != nil {
@@ -208,42 +325,47 @@ func (b *Bot) shouldProcessUpdate(update tgbotapi.Update) bool {
 		chatID = update.CallbackQuery.Message.Chat.ID
 	} else {
 		// No chat information, skip
-		return false
+		return FilterNoChat
 	}
 
-	// Check if chat is blacklisted (uses blacklistMu)
+	// Check if chat or user is banned (uses blacklistMu)
 	if b.IsBlacklisted(chatID) {
-		return false
+		return FilterBlacklisted
+	}
+	if user := update.SentFrom(); user != nil && b.IsUserBanned(user.ID) {
+		return FilterUserBanned
 	}
 	// Check if private only mode is enabled (uses main mu)
 	if b.IsPrivateOnly() {
 		// Only process if it's a private chat
-		if update.Message != nil {
-			return update.Message.Chat.Type == "private"
-		} else if update.CallbackQuery != nil {
-			return update.CallbackQuery.Message.Chat.Type == "private"
+		if update.Message != nil && update.Message.Chat.Type != "private" {
+			return FilterPrivateOnly
+		} else if update.CallbackQuery != nil && update.CallbackQuery.Message.Chat.Type != "private" {
+			return FilterPrivateOnly
 		}
 	}
 
-	return true
+	return ""
 }
 
 // Start starts update processing in a goroutine
 func (b *Bot) Start(offset, timeout int) {
 	if !b.mu.TryLock() {
-		b.logger.Warn("Bot is already running")
+		b.Logger().Warn("Bot is already running")
 		return
 	}
-	b.logger.Info("Starting bot")
+	b.Logger().Info("Starting bot")
+	// Polling is prepared before the goroutine starts, so an immediate Stop stops it
+	b.startPolling()
 	go b.HandleUpdates(offset, timeout)
 	// Note: mutex remains locked while bot is running
 }
 
 // Stop stops update processing
 func (b *Bot) Stop() {
-	b.BotAPI.StopReceivingUpdates() // Stop receiving updates
-	b.mu.Unlock()                   // Unlock mutex locked in Start()
-	b.logger.Info("Stopping update processing")
+	b.stopUpdatesPolling() // Stop receiving updates
+	b.mu.Unlock()          // Unlock mutex locked in Start()
+	b.Logger().Info("Stopping update processing")
 }
 
 // HandleUpdates starts processing all updates received by the bot from Telegram
@@ -251,58 +373,95 @@ func (app *Bot) HandleUpdates(offset, timeout int) {
 	// Configure updates
 	u := tgbotapi.NewUpdate(offset)
 	u.Timeout = timeout
-	updates := app.BotAPI.GetUpdatesChan(u)
-	app.logger.Info("Starting update processing")
+	updates := app.pollUpdates(u, app.startPolling())
+	app.Logger().Info("Starting update processing")
 
 	for update := range updates {
 		// Check if update should be processed based on filters
 		if !app.shouldProcessUpdate(update) {
 			continue
 		}
-		go func(update tgbotapi.Update) {
-			if app.updateHandler != nil {
-				app.updateHandler(app, update)
-			}
+		go app.processUpdate(update)
+	}
+}
 
-			// Process local states
-			if update.SentFrom() == nil {
-				return
-			}
+// ProcessUpdate processes the update synchronously, the same way as updates received by Start:
+// filters, the update handler, global states and the user's current state.
+// Useful for webhooks and tests (see package tgfsmtest).
+func (app *Bot) ProcessUpdate(update tgbotapi.Update) {
+	// Check if update should be processed based on filters
+	if !app.shouldProcessUpdate(update) {
+		return
+	}
+	app.processUpdate(update)
+}
 
-			// Process global states
-			globalStateFound, err := app.HandleGlobalStates(update)
-			if err != nil {
-				app.logger.Error("failed to handle global state", zap.Error(err))
-			}
-			// If global state is found, exit the function
-			if globalStateFound {
-				return
-			}
-			// Get user state name
-			userStateName, err := app.GetUserState(update.SentFrom().ID)
-			if err != nil {
-				return
-			}
-			// Get state
-			userState, ok := app.states[userStateName]
-			if !ok {
-				app.logger.Error("state not found in states map", zap.String("state", userStateName))
-			}
-			// Process update by local state
-			_, err = app.SelectHandler(update, &userState)
-			if err != nil {
-				app.logger.Error("failed to handle user state", zap.Error(err))
-			}
+// processUpdate routes the update that passed the filters
+func (app *Bot) processUpdate(update tgbotapi.Update) {
+	app.inFlight.Add(1)
+	defer app.inFlight.Add(-1)
+	defer app.traceUpdate(update)()
 
-		}(update)
+	// In allowlist mode other users are not served at all
+	if err := app.checkAllowlist(update); err != nil {
+		app.denyAccess(update, FilterNotAllowed, app.settings().allowedRoles, err)
+		return
+	}
 
+	if app.updateHandler != nil {
+		app.updateHandler(app, update)
+	}
+
+	// Process local states
+	if update.SentFrom() == nil {
+		return
+	}
+
+	// Process global states
+	globalStateFound, err := app.HandleGlobalStates(update)
+	if err != nil {
+		app.updateLogger(update, "", "").Error("failed to handle global state", ErrorField(err))
+	}
+	// If global state is found, exit the function
+	if globalStateFound {
+		return
+	}
+	// Get user state name
+	// Users without a state are routed to the initial state if it is configured,
+	// otherwise only global states are processed for them
+	userStateName, err := app.GetUserState(update.SentFrom().ID)
+	if err != nil {
+		return
+	}
+	// Get state
+	userState, ok := app.stateSet().states[userStateName]
+	if !ok {
+		// The state was removed by Reload
+		app.recoverOrphan(update.SentFrom().ID, userStateName)
+		userStateName, err = app.GetUserState(update.SentFrom().ID)
+		if err != nil {
+			return
+		}
+		if userState, ok = app.stateSet().states[userStateName]; !ok {
+			return
+		}
+	}
+	// Process update by local state
+	_, err = app.SelectHandler(update, &userState)
+	if err != nil {
+		app.updateLogger(update, userState.key, "").Error("failed to handle user state", ErrorField(err))
 	}
 }
 
 // GetUserState returns the name of the state the user is currently in
+// If the user has no state, the initial state is returned when configured (see WithInitialState),
+// otherwise ErrStateNotFound
 func (app *Bot) GetUserState(userId int64) (string, error) {
 	userStateInterface, ok := app.cache.Get(strconv.FormatInt(userId, 10))
 	if !ok {
+		if initialState := app.stateSet().initialState; initialState != "" {
+			return initialState, nil
+		}
 		return "", ErrStateNotFound
 	}
 
@@ -315,52 +474,114 @@ func (app *Bot) GetUserState(userId int64) (string, error) {
 }
 
 // SetUserState changes the user's state
+// The transition must be allowed by the current state (see State.Transitions and State.Guards)
+// The user leaves the states entered by PushUserState, so the state history is cleared
+// unless the user stays in the current state; use ReplaceUserState to keep it.
+// An empty state name resets the user's state (see ResetUserState)
 func (app *Bot) SetUserState(userId int64, state string) error {
-	_, ok := app.states[state]
+	return app.setUserState(userId, state, nil, false)
+}
+
+// setUserState changes the user's state and runs transition hooks
+// update is the update that caused the transition, nil if unknown;
+// keepHistory keeps the state history, otherwise it is cleared when the state changes
+func (app *Bot) setUserState(userId int64, state string, update *tgbotapi.Update, keepHistory bool) error {
+	if state == "" {
+		app.resetUserState(userId, update)
+		return nil
+	}
+
+	_, ok := app.stateSet().states[state]
 	if !ok {
 		return NewSFMError(ErrStateHandlerNotFound, state)
 	}
+	if err := app.checkUserTransition(userId, state, update); err != nil {
+		return err
+	}
+
+	app.stackMu.Lock()
+	if !keepHistory {
+		app.clearStackOnLeave(userId, state)
+	}
+	transition := app.storeUserState(userId, state, update)
+	app.stackMu.Unlock()
 
-	app.cache.Set(strconv.FormatInt(userId, 10), state, app.expiration)
+	// Hooks run without the lock so that they can change the state themselves
+	app.runTransitionHooks(transition)
 	return nil
 }
 
+// ResetUserState clears the user's state and state history in storage
+// The user is then treated as having no state: routed to the initial state
+// if it is configured (see WithInitialState), otherwise only global states apply
+func (app *Bot) ResetUserState(userId int64) {
+	app.resetUserState(userId, nil)
+}
+
+// resetUserState clears the user's state and history and runs transition hooks
+func (app *Bot) resetUserState(userId int64, update *tgbotapi.Update) {
+	app.stackMu.Lock()
+	app.cache.Delete(stackKey(userId))
+	transition := app.storeUserState(userId, "", update)
+	app.stackMu.Unlock()
+
+	app.runTransitionHooks(transition)
+}
+
 // SetUserStateImmediate changes the user's state and immediately processes the current update
 func (app *Bot) SetUserStateImmediate(userId int64, state string, update tgbotapi.Update) error {
-	if err := app.SetUserState(userId, state); err != nil {
+	if err := app.setUserState(userId, state, &update, false); err != nil {
 		return err
 	}
 
-	if newState, ok := app.states[state]; ok {
-		// Call entrance action if it exists and this is not a global state
-		if newState.AtEntranceFunc != nil {
-			if err := newState.AtEntranceFunc.Handle(app, update); err != nil {
-				app.logger.Error("failed to handle entrance function", zap.Error(err))
-			}
-			return nil
-		}
+	app.processImmediate(state, update)
+	return nil
+}
 
-		// Immediate processing of current update
-		_, err := app.SelectHandler(update, &newState)
-		if err != nil {
-			app.logger.Error("failed to handle immediate reaction", zap.Error(err))
+// processImmediate processes the update in the state the user has just entered
+func (app *Bot) processImmediate(state string, update tgbotapi.Update) {
+	newState, ok := app.stateSet().states[state]
+	if !ok {
+		return
+	}
+
+	// Call entrance action if it exists and this is not a global state
+	if newState.AtEntranceFunc != nil {
+		if err := app.callHandler(&newState, "at_entrance", newState.AtEntranceFunc, update); err != nil && !errors.Is(err, ErrAccessDenied) {
+			app.updateLogger(update, newState.key, "at_entrance").Error("failed to handle entrance function", ErrorField(err))
 		}
+		return
 	}
 
-	return nil
+	// Immediate processing of current update
+	_, err := app.SelectHandler(update, &newState)
+	if err != nil {
+		app.updateLogger(update, newState.key, "").Error("failed to handle immediate reaction", ErrorField(err))
+	}
 }
 
 // HandleGlobalStates checks if user action matches global states and executes it if it does.
+// Global states are checked in precedence order (see globals.go).
 // Returns true if a handler was found and executed.
 func (app *Bot) HandleGlobalStates(update tgbotapi.Update) (bool, error) {
+	// The current state defines which scoped global states are active
+	current := ""
+	if user := update.SentFrom(); user != nil {
+		if state, err := app.GetUserState(user.ID); err == nil {
+			current = state
+		}
+	}
+
 	// Process all global states
-	for _, state := range app.globalStates {
-		// Process state
+	for _, global := range app.stateSet().globalStates {
+		if !global.activeIn(current) {
+			continue
+		}
 
-		handlerIsFound, err := app.SelectHandler(update, state)
+		handlerIsFound, err := app.SelectHandler(update, global.state)
 		// If error, skip state
 		if err != nil {
-			app.logger.Error("failed to handle global state", zap.Error(err))
+			app.updateLogger(update, global.state.key, "").Error("failed to handle global state", ErrorField(err))
 			continue
 		}
 		// If handler found, return true
@@ -377,10 +598,9 @@ func (app *Bot) SelectHandler(update tgbotapi.Update, userState *State) (bool, e
 		if userState.MessageHandlers != nil {
 			return app.handleMessage(userState, update)
 		} else {
-			app.logger.Info("command not found",
-				zap.String("command", update.Message.Text),
-				zap.Int64("chat_id", update.Message.Chat.ID),
-				zap.String("username", update.Message.Chat.UserName),
+			app.updateLogger(update, userState.key, "").Info("command not found",
+				LogField("command", update.Message.Text),
+				LogField("username", update.Message.Chat.UserName),
 			)
 			return false, nil
 		}
@@ -388,10 +608,9 @@ func (app *Bot) SelectHandler(update tgbotapi.Update, userState *State) (bool, e
 		if userState.CallbackHandlers != nil {
 			return app.handleCallback(userState, update)
 		} else {
-			app.logger.Info("callback not found",
-				zap.String("callback", update.CallbackQuery.Data),
-				zap.Int64("user_id", update.CallbackQuery.From.ID),
-				zap.String("username", update.CallbackQuery.From.UserName),
+			app.updateLogger(update, userState.key, "").Info("callback not found",
+				LogField("callback", update.CallbackQuery.Data),
+				LogField("username", update.CallbackQuery.From.UserName),
 			)
 			return false, nil
 		}
@@ -404,28 +623,31 @@ func (app *Bot) handleMessage(userState *State, update tgbotapi.Update) (bool, e
 	messageFound := false
 
 	// Search for handler
-	if currentAction, ok := userState.MessageHandlers[strings.ToLower(strings.TrimSpace(update.Message.Text))]; ok {
+	trigger := strings.ToLower(strings.TrimSpace(update.Message.Text))
+	if currentAction, ok := userState.MessageHandlers[trigger]; ok {
 		messageFound = true
-		if err := currentAction.Handle(app, update); err != nil {
-			app.logger.Error("failed to handle command", zap.Error(err))
-		} else {
-			app.logger.Info("command handled successfully",
-				zap.String("command", update.Message.Text),
-				zap.Int64("chat_id", update.Message.Chat.ID),
-				zap.String("username", update.Message.Chat.UserName),
+		logger := app.updateLogger(update, userState.key, trigger)
+		switch err := app.callHandler(userState, trigger, &currentAction, update); {
+		case errors.Is(err, ErrAccessDenied):
+			// Reported by callHandler
+		case err != nil:
+			logger.Error("failed to handle command", ErrorField(err))
+		default:
+			logger.Info("command handled successfully",
+				LogField("command", update.Message.Text),
+				LogField("username", update.Message.Chat.UserName),
 			)
 		}
 	} else {
 		if userState.CatchAllFunc != nil {
-			err := userState.CatchAllFunc.Handle(app, update)
-			if err != nil {
-				app.logger.Error("failed to handle command", zap.Error(err))
+			err := app.callHandler(userState, "catch_all", userState.CatchAllFunc, update)
+			if err != nil && !errors.Is(err, ErrAccessDenied) {
+				app.updateLogger(update, userState.key, "catch_all").Error("failed to handle command", ErrorField(err))
 			}
 		} else {
-			app.logger.Info("command not found",
-				zap.String("command", update.Message.Text),
-				zap.Int64("chat_id", update.Message.Chat.ID),
-				zap.String("username", update.Message.Chat.UserName),
+			app.updateLogger(update, userState.key, "").Info("command not found",
+				LogField("command", update.Message.Text),
+				LogField("username", update.Message.Chat.UserName),
 			)
 		}
 
@@ -439,27 +661,30 @@ func (app *Bot) handleCallback(userState *State, update tgbotapi.Update) (bool,
 
 	if currentAction, ok := userState.CallbackHandlers[update.CallbackQuery.Data]; ok {
 		callbackFound = true
-		if err := currentAction.Handle(app, update); err != nil {
-			app.logger.Error("failed to handle callback", zap.Error(err))
+		logger := app.updateLogger(update, userState.key, update.CallbackQuery.Data)
+		switch err := app.callHandler(userState, update.CallbackQuery.Data, &currentAction, update); {
+		case errors.Is(err, ErrAccessDenied):
+			// Reported by callHandler
+			return callbackFound, nil
+		case err != nil:
+			logger.Error("failed to handle callback", ErrorField(err))
 			return callbackFound, err
 		}
 
-		app.logger.Info("callback handled successfully",
-			zap.String("callback", update.CallbackQuery.Data),
-			zap.Int64("user_id", update.CallbackQuery.From.ID),
-			zap.String("username", update.CallbackQuery.From.UserName),
+		logger.Info("callback handled successfully",
+			LogField("callback", update.CallbackQuery.Data),
+			LogField("username", update.CallbackQuery.From.UserName),
 		)
 	} else {
 		if userState.CatchAllFunc != nil {
-			err := userState.CatchAllFunc.Handle(app, update)
-			if err != nil {
-				app.logger.Error("failed to handle callback", zap.Error(err))
+			err := app.callHandler(userState, "catch_all", userState.CatchAllFunc, update)
+			if err != nil && !errors.Is(err, ErrAccessDenied) {
+				app.updateLogger(update, userState.key, "catch_all").Error("failed to handle callback", ErrorField(err))
 			}
 		} else {
-			app.logger.Info("callback not found",
-				zap.Int64("user_id", update.CallbackQuery.From.ID),
-				zap.String("username", update.CallbackQuery.From.UserName),
-				zap.String("callback", update.CallbackQuery.Data),
+			app.updateLogger(update, userState.key, "").Info("callback not found",
+				LogField("username", update.CallbackQuery.From.UserName),
+				LogField("callback", update.CallbackQuery.Data),
 			)
 		}
 	}
@@ -1,5 +1,5 @@
 // Package cache provides implementations of LastMessageCache interface
-// for auto-deletion feature.
+// for auto-deletion feature and BlacklistStore interface for a shared blacklist.
 package cache
 
 import (
@@ -1,5 +1,5 @@
 // Package cache provides implementations of LastMessageCache interface
-// for auto-deletion feature.
+// for auto-deletion feature and BlacklistStore interface for a shared blacklist.
 //
 // To use PostgreSQL implementation, install the dependency:
 //
@@ -7,6 +7,7 @@
 package cache
 
 import (
+	"context"
 	"database/sql"
 	"time"
 
@@ -21,8 +22,11 @@ type PostgresLastMessage struct {
 	tableName string
 }
 
-// Ensure PostgresLastMessage implements LastMessageCache interface
-var _ tgfsm.LastMessageCache = (*PostgresLastMessage)(nil)
+// Ensure PostgresLastMessage implements LastMessageCache and Pinger interfaces
+var (
+	_ tgfsm.LastMessageCache = (*PostgresLastMessage)(nil)
+	_ tgfsm.Pinger           = (*PostgresLastMessage)(nil)
+)
 
 // NewPostgresLastMessage creates a new cache implementation using PostgreSQL
 // db - database connection
@@ -105,3 +109,8 @@ func (c *PostgresLastMessage) CleanupOldRecords(olderThan time.Duration) error {
 	_, err := c.db.Exec(query, olderThan)
 	return err
 }
+
+// Ping checks that the database is reachable (see Bot.CheckStorage)
+func (c *PostgresLastMessage) Ping(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
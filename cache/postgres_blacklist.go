@@ -0,0 +1,181 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	tgfsm "tgfsm"
+
+	"github.com/lib/pq"
+)
+
+// PostgresBlacklist implements BlacklistStore using PostgreSQL
+// Changes are sent with NOTIFY to the "<tableName>_events" channel and received
+// with LISTEN on a dedicated connection.
+type PostgresBlacklist struct {
+	db        *sql.DB
+	tableName string
+	connStr   string
+}
+
+// Ensure PostgresBlacklist implements BlacklistStore and Pinger interfaces
+var (
+	_ tgfsm.BlacklistStore = (*PostgresBlacklist)(nil)
+	_ tgfsm.Pinger         = (*PostgresBlacklist)(nil)
+)
+
+// NewPostgresBlacklist creates a new blacklist store using PostgreSQL
+// db - database connection
+// tableName - table name (default: "blacklist")
+// connStr - connection string for listening to changes of other bots;
+// if empty, changes made by other bots are seen only after a restart
+func NewPostgresBlacklist(db *sql.DB, tableName, connStr string) (*PostgresBlacklist, error) {
+	if tableName == "" {
+		tableName = "blacklist"
+	}
+
+	store := &PostgresBlacklist{
+		db:        db,
+		tableName: tableName,
+		connStr:   connStr,
+	}
+
+	// Create table if it doesn't exist
+	if err := store.createTable(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// createTable creates table for storing bans
+func (c *PostgresBlacklist) createTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS ` + c.tableName + ` (
+		kind TEXT NOT NULL,
+		id BIGINT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		until TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (kind, id)
+	);
+	`
+	_, err := c.db.Exec(query)
+	return err
+}
+
+// channel returns the channel of change events
+func (c *PostgresBlacklist) channel() string {
+	return c.tableName + "_events"
+}
+
+// AddBan saves the ban and notifies watching bots
+func (c *PostgresBlacklist) AddBan(ban tgfsm.Ban) error {
+	until := sql.NullTime{Time: ban.Until, Valid: !ban.Until.IsZero()}
+	query := `
+	INSERT INTO ` + c.tableName + ` (kind, id, reason, until, created_at)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (kind, id) DO UPDATE
+	SET reason = EXCLUDED.reason, until = EXCLUDED.until, created_at = EXCLUDED.created_at
+	`
+	return c.exec(tgfsm.BlacklistEvent{Type: tgfsm.BlacklistAdded, Ban: ban},
+		query, string(ban.Kind), ban.ID, ban.Reason, until, ban.Created)
+}
+
+// RemoveBan deletes the ban and notifies watching bots
+func (c *PostgresBlacklist) RemoveBan(kind tgfsm.BanKind, id int64) error {
+	query := "DELETE FROM " + c.tableName + " WHERE kind = $1 AND id = $2"
+	return c.exec(tgfsm.BlacklistEvent{Type: tgfsm.BlacklistRemoved, Ban: tgfsm.Ban{Kind: kind, ID: id}},
+		query, string(kind), id)
+}
+
+// exec executes the query and sends the event in one transaction,
+// so the event is delivered only if the change is committed
+func (c *PostgresBlacklist) exec(event tgfsm.BlacklistEvent, query string, args ...interface{}) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("SELECT pg_notify($1, $2)", c.channel(), string(payload)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Bans returns the bans that have not expired, expired bans are deleted
+func (c *PostgresBlacklist) Bans() ([]tgfsm.Ban, error) {
+	if _, err := c.db.Exec("DELETE FROM " + c.tableName + " WHERE until <= NOW()"); err != nil {
+		return nil, err
+	}
+
+	rows, err := c.db.Query("SELECT kind, id, reason, until, created_at FROM " + c.tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bans []tgfsm.Ban
+	for rows.Next() {
+		var ban tgfsm.Ban
+		var kind string
+		var until sql.NullTime
+		if err := rows.Scan(&kind, &ban.ID, &ban.Reason, &until, &ban.Created); err != nil {
+			return nil, err
+		}
+		ban.Kind = tgfsm.BanKind(kind)
+		if until.Valid {
+			ban.Until = until.Time
+		}
+		bans = append(bans, ban)
+	}
+	return bans, rows.Err()
+}
+
+// Watch calls fn for changes notified by any bot until stop is called
+// Without a connection string nothing is watched. After the listening connection
+// is restored, fn receives a BlacklistResync event, as notifications may have been lost.
+func (c *PostgresBlacklist) Watch(fn func(tgfsm.BlacklistEvent)) (func(), error) {
+	if c.connStr == "" {
+		return func() {}, nil
+	}
+
+	listener := pq.NewListener(c.connStr, time.Second, time.Minute, nil)
+	if err := listener.Listen(c.channel()); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	go func() {
+		for notification := range listener.Notify {
+			// A nil notification is sent after reconnecting
+			if notification == nil {
+				fn(tgfsm.BlacklistEvent{Type: tgfsm.BlacklistResync})
+				continue
+			}
+			var event tgfsm.BlacklistEvent
+			if err := json.Unmarshal([]byte(notification.Extra), &event); err != nil {
+				continue
+			}
+			fn(event)
+		}
+	}()
+
+	return func() { listener.Close() }, nil
+}
+
+// Ping checks that the database is reachable (see Bot.CheckStorage)
+func (c *PostgresBlacklist) Ping(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
@@ -1,5 +1,5 @@
 // Package cache provides implementations of LastMessageCache interface
-// for auto-deletion feature.
+// for auto-deletion feature and BlacklistStore interface for a shared blacklist.
 //
 // To use Redis implementation, install the dependency:
 //
@@ -24,8 +24,11 @@ type RedisLastMessage struct {
 	keyPrefix string
 }
 
-// Ensure RedisLastMessage implements LastMessageCache interface
-var _ tgfsm.LastMessageCache = (*RedisLastMessage)(nil)
+// Ensure RedisLastMessage implements LastMessageCache and Pinger interfaces
+var (
+	_ tgfsm.LastMessageCache = (*RedisLastMessage)(nil)
+	_ tgfsm.Pinger           = (*RedisLastMessage)(nil)
+)
 
 // NewRedisLastMessage creates a new cache implementation using Redis
 // client - Redis client
@@ -77,3 +80,8 @@ func (c *RedisLastMessage) DeleteLastMessageInfo(chatID int64) error {
 	key := c.keyPrefix + strconv.FormatInt(chatID, 10)
 	return c.client.Del(c.ctx, key).Err()
 }
+
+// Ping checks that Redis is reachable (see Bot.CheckStorage)
+func (c *RedisLastMessage) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	tgfsm "tgfsm"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBlacklist implements BlacklistStore using Redis
+// Bans are stored as JSON under "<keyPrefix><kind>:<id>" keys expiring with the bans;
+// changes are published to the "<keyPrefix>events" channel.
+type RedisBlacklist struct {
+	client    *redis.Client
+	ctx       context.Context
+	keyPrefix string
+}
+
+// Ensure RedisBlacklist implements BlacklistStore and Pinger interfaces
+var (
+	_ tgfsm.BlacklistStore = (*RedisBlacklist)(nil)
+	_ tgfsm.Pinger         = (*RedisBlacklist)(nil)
+)
+
+// NewRedisBlacklist creates a new blacklist store using Redis
+// client - Redis client
+// keyPrefix - prefix for keys and the events channel (default: "tgfsm:blacklist:")
+func NewRedisBlacklist(client *redis.Client, keyPrefix string) *RedisBlacklist {
+	if keyPrefix == "" {
+		keyPrefix = "tgfsm:blacklist:"
+	}
+	return &RedisBlacklist{
+		client:    client,
+		ctx:       context.Background(),
+		keyPrefix: keyPrefix,
+	}
+}
+
+// key returns the key of the ban
+func (c *RedisBlacklist) key(kind tgfsm.BanKind, id int64) string {
+	return c.keyPrefix + string(kind) + ":" + strconv.FormatInt(id, 10)
+}
+
+// channel returns the channel of change events
+func (c *RedisBlacklist) channel() string {
+	return c.keyPrefix + "events"
+}
+
+// AddBan saves the ban and publishes the change
+func (c *RedisBlacklist) AddBan(ban tgfsm.Ban) error {
+	value, err := json.Marshal(ban)
+	if err != nil {
+		return err
+	}
+
+	// Redis deletes expired bans
+	var ttl time.Duration
+	if !ban.Until.IsZero() {
+		ttl = time.Until(ban.Until)
+		if ttl <= 0 {
+			return c.RemoveBan(ban.Kind, ban.ID)
+		}
+	}
+	if err := c.client.Set(c.ctx, c.key(ban.Kind, ban.ID), value, ttl).Err(); err != nil {
+		return err
+	}
+
+	return c.publish(tgfsm.BlacklistEvent{Type: tgfsm.BlacklistAdded, Ban: ban})
+}
+
+// RemoveBan deletes the ban and publishes the change
+func (c *RedisBlacklist) RemoveBan(kind tgfsm.BanKind, id int64) error {
+	if err := c.client.Del(c.ctx, c.key(kind, id)).Err(); err != nil {
+		return err
+	}
+
+	return c.publish(tgfsm.BlacklistEvent{Type: tgfsm.BlacklistRemoved, Ban: tgfsm.Ban{Kind: kind, ID: id}})
+}
+
+// Bans returns the bans that have not expired
+func (c *RedisBlacklist) Bans() ([]tgfsm.Ban, error) {
+	var keys []string
+	iter := c.client.Scan(c.ctx, 0, c.keyPrefix+"*", 0).Iterator()
+	for iter.Next(c.ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	values, err := c.client.MGet(c.ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	bans := make([]tgfsm.Ban, 0, len(values))
+	for _, value := range values {
+		// The key expired after the scan
+		text, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var ban tgfsm.Ban
+		if err := json.Unmarshal([]byte(text), &ban); err != nil {
+			return nil, err
+		}
+		if ban.Active(now) {
+			bans = append(bans, ban)
+		}
+	}
+	return bans, nil
+}
+
+// Watch calls fn for changes published by any bot until stop is called
+// go-redis resubscribes after a lost connection; changes published meanwhile are lost,
+// so fn receives BlacklistResync after every resubscription.
+func (c *RedisBlacklist) Watch(fn func(tgfsm.BlacklistEvent)) (func(), error) {
+	pubsub := c.client.Subscribe(c.ctx, c.channel())
+	// Wait for the subscription, so changes made after Watch returns are received
+	if _, err := pubsub.Receive(c.ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	go func() {
+		// The first subscription was received above, the next ones follow reconnects
+		for message := range pubsub.ChannelWithSubscriptions() {
+			switch message := message.(type) {
+			case *redis.Subscription:
+				if message.Kind == "subscribe" {
+					fn(tgfsm.BlacklistEvent{Type: tgfsm.BlacklistResync})
+				}
+			case *redis.Message:
+				var event tgfsm.BlacklistEvent
+				if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+					continue
+				}
+				fn(event)
+			}
+		}
+	}()
+
+	return func() { pubsub.Close() }, nil
+}
+
+// publish publishes the change to watching bots
+func (c *RedisBlacklist) publish(event tgfsm.BlacklistEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return c.client.Publish(c.ctx, c.channel(), payload).Err()
+}
+
+// Ping checks that Redis is reachable (see Bot.CheckStorage)
+func (c *RedisBlacklist) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
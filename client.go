@@ -0,0 +1,126 @@
+package tgfsm
+
+import (
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// pollRetryDelay is the delay before polling updates again after an error
+const pollRetryDelay = 3 * time.Second
+
+// Client is the Telegram Bot API client used by the bot
+// *tgbotapi.BotAPI implements it and is used by default. Other implementations wrap it
+// to add retries, metrics or recording, or replace it in tests (see WithClient):
+//
+//	type retryClient struct {
+//		tgfsm.Client
+//	}
+//
+//	func (c retryClient) Send(chattable tgbotapi.Chattable) (tgbotapi.Message, error) {
+//		...
+//	}
+type Client interface {
+	// Send sends a message and returns the sent message
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	// Request makes a call that does not return a message: answering callbacks, deleting messages, ...
+	Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+	// GetUpdates returns updates starting from the offset of the config, used to poll updates
+	GetUpdates(config tgbotapi.UpdateConfig) ([]tgbotapi.Update, error)
+	// GetFile returns the file info needed to download a file
+	GetFile(config tgbotapi.FileConfig) (tgbotapi.File, error)
+	// GetFileDirectURL returns the URL to download a file
+	GetFileDirectURL(fileID string) (string, error)
+	// GetMe returns the bot user
+	GetMe() (tgbotapi.User, error)
+	// GetWebhookInfo returns the webhook status of the bot
+	GetWebhookInfo() (tgbotapi.WebhookInfo, error)
+}
+
+// Client returns the Telegram Bot API client of the bot
+func (b *Bot) Client() Client {
+	return b.client
+}
+
+// Request makes a Bot API call that does not return a message, respecting the rate limiter
+// Use it instead of the client to answer callbacks, change chat settings, etc.
+func (b *Bot) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	b.waitForAPI(chatIDOf(c))
+
+	return b.client.Request(c)
+}
+
+// startPolling returns the channel stopping the current updates polling, creating it if needed
+func (b *Bot) startPolling() chan struct{} {
+	b.pollMu.Lock()
+	defer b.pollMu.Unlock()
+
+	if b.stopPolling == nil {
+		b.stopPolling = make(chan struct{})
+	}
+	return b.stopPolling
+}
+
+// stopUpdatesPolling stops the current updates polling
+func (b *Bot) stopUpdatesPolling() {
+	b.pollMu.Lock()
+	defer b.pollMu.Unlock()
+
+	if b.stopPolling != nil {
+		close(b.stopPolling)
+		b.stopPolling = nil
+	}
+}
+
+// pollUpdates polls updates with the client until stop is closed
+// The returned channel is closed when polling stops.
+func (b *Bot) pollUpdates(config tgbotapi.UpdateConfig, stop <-chan struct{}) tgbotapi.UpdatesChannel {
+	updates := make(chan tgbotapi.Update, 100)
+
+	go func() {
+		defer close(updates)
+		b.pollStarted(updates)
+		defer b.pollStopped(updates)
+
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			received, err := b.client.GetUpdates(config)
+			b.pollDone(err)
+			if err != nil {
+				b.Logger().Error("failed to get updates, retrying", ErrorField(err), LogField("delay", pollRetryDelay))
+				select {
+				case <-stop:
+					return
+				case <-time.After(pollRetryDelay):
+				}
+				continue
+			}
+
+			// Updates received after Stop are left for the next polling
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			for _, update := range received {
+				if update.UpdateID < config.Offset {
+					continue
+				}
+				config.Offset = update.UpdateID + 1
+				select {
+				case updates <- update:
+				case <-stop:
+					return
+				}
+			}
+		}
+	}()
+
+	return updates
+}
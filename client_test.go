@@ -0,0 +1,156 @@
+package tgfsm_test
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"tgfsm"
+	"tgfsm/tgfsmtest"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const pollUserID = 11
+
+// pollClient is a client of the fake server recording the offsets of getUpdates calls
+// GetUpdates fails while err is set.
+type pollClient struct {
+	tgfsm.Client
+
+	mu      sync.Mutex
+	offsets []int
+	err     error
+}
+
+func (c *pollClient) GetUpdates(config tgbotapi.UpdateConfig) ([]tgbotapi.Update, error) {
+	c.mu.Lock()
+	c.offsets = append(c.offsets, config.Offset)
+	err := c.err
+	c.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	return c.Client.GetUpdates(config)
+}
+
+// calls returns the offsets of getUpdates calls so far
+func (c *pollClient) calls() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]int(nil), c.offsets...)
+}
+
+// newPollBot creates a bot polling the fake server through pollClient, it answers "echo" to every message
+func newPollBot(t *testing.T, err error) (*tgfsmtest.Server, *tgfsm.Bot, *pollClient) {
+	t.Helper()
+
+	server := tgfsmtest.NewServer()
+	t.Cleanup(server.Close)
+
+	api, apiErr := tgbotapi.NewBotAPIWithClient(tgfsmtest.Token, server.Endpoint(), &http.Client{})
+	if apiErr != nil {
+		t.Fatal(apiErr)
+	}
+	client := &pollClient{Client: api, err: err}
+
+	states := map[string]tgfsm.State{"home": {
+		MessageHandlers: map[string]tgfsm.Handler{},
+		CatchAllFunc:    &tgfsm.Handler{Handle: reply("echo")},
+	}}
+	bot, botErr := tgfsmtest.NewBot(server, tgfsm.WithClient(client), tgfsm.WithStates(states), tgfsm.WithInitialState("home"))
+	if botErr != nil {
+		t.Fatal(botErr)
+	}
+	return server, bot, client
+}
+
+// stopped reports whether the polling loop of the bot has ended
+func stopped(bot *tgfsm.Bot) func() bool {
+	return func() bool { return !bot.Health().PollingAlive }
+}
+
+func TestPollUpdatesOffset(t *testing.T) {
+	server, bot, client := newPollBot(t, nil)
+
+	var updates []tgbotapi.Update
+	for _, text := range []string{"one", "two", "three"} {
+		update := tgfsmtest.NewMessageUpdate(pollUserID, text)
+		updates = append(updates, update)
+		server.PushUpdate(update)
+	}
+	bot.Start(0, 0)
+	t.Cleanup(bot.Stop)
+
+	if _, ok := server.WaitFor(3, time.Second, "sendMessage"); !ok {
+		t.Fatalf("sent %q, want every update handled", sentTexts(server))
+	}
+	// The offset moves past the handled updates, so they are not received again
+	last := updates[len(updates)-1].UpdateID
+	eventually(t, "the offset after the last update", func() bool {
+		offsets := client.calls()
+		return offsets[len(offsets)-1] == last+1
+	})
+	time.Sleep(150 * time.Millisecond)
+	if texts := sentTexts(server); len(texts) != 3 {
+		t.Fatalf("sent %q, want each update handled once", texts)
+	}
+
+	offsets := client.calls()
+	if offsets[0] != 0 {
+		t.Errorf("first offset %d, want the offset of Start", offsets[0])
+	}
+	for i := 1; i < len(offsets); i++ {
+		if offsets[i] < offsets[i-1] {
+			t.Fatalf("offsets %v go back", offsets)
+		}
+	}
+}
+
+func TestPollUpdatesStop(t *testing.T) {
+	server, bot, _ := newPollBot(t, nil)
+
+	bot.Start(0, 0)
+	eventually(t, "polling", func() bool { return bot.Health().PollingAlive })
+	bot.Stop()
+	eventually(t, "polling stopped", stopped(bot))
+
+	// Updates arriving after Stop are not handled
+	server.PushUpdate(tgfsmtest.NewMessageUpdate(pollUserID, "late"))
+	time.Sleep(150 * time.Millisecond)
+	if texts := sentTexts(server); len(texts) != 0 {
+		t.Fatalf("sent %q after Stop, want nothing", texts)
+	}
+
+	// and are received by the next polling
+	bot.Start(0, 0)
+	t.Cleanup(bot.Stop)
+	if _, ok := server.WaitFor(1, time.Second, "sendMessage"); !ok {
+		t.Fatal("the update left by the stopped polling is not handled after Start")
+	}
+}
+
+func TestPollUpdatesErrorBackoff(t *testing.T) {
+	errPoll := errors.New("connection refused")
+	_, bot, client := newPollBot(t, errPoll)
+
+	bot.Start(0, 0)
+	eventually(t, "the poll error reported", func() bool { return bot.Health().LastPollError == errPoll.Error() })
+
+	// The failed call is not retried at once
+	time.Sleep(150 * time.Millisecond)
+	if offsets := client.calls(); !reflect.DeepEqual(offsets, []int{0}) {
+		t.Fatalf("getUpdates called with offsets %v, want one call during the retry delay", offsets)
+	}
+
+	// Stop does not wait for the retry delay
+	start := time.Now()
+	bot.Stop()
+	eventually(t, "polling stopped", stopped(bot))
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("polling stopped after %v, want the retry delay interrupted", elapsed)
+	}
+}
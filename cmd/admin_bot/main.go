@@ -0,0 +1,77 @@
+package main
+
+import (
+	"log"
+	"os"
+	"tgfsm"
+	"tgfsm/admin"
+	"tgfsm/metrics"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+/*
+A bot with an admin HTTP server on http://localhost:8081 serving Kubernetes probes
+(/healthz, /readyz), metrics (/metrics) and the admin API (/api/...) authenticated
+with the ADMIN_TOKEN environment variable:
+
+	curl -H "Authorization: Bearer $ADMIN_TOKEN" localhost:8081/api/users/123
+	curl -H "Authorization: Bearer $ADMIN_TOKEN" -X PUT -d '{"state":"echo","force":true}' localhost:8081/api/users/123/state
+
+/start moves the user to the "echo" state, where text messages are echoed back.
+*/
+func main() {
+	token := "YOUR_BOT_TOKEN"
+
+	states := map[string]tgfsm.State{
+		"start": {
+			Global: true,
+			MessageHandlers: map[string]tgfsm.Handler{
+				"/start": {Handle: start},
+			},
+		},
+		"echo": {
+			MessageHandlers: map[string]tgfsm.Handler{},
+			CatchAllFunc:    &tgfsm.Handler{Handle: echo},
+		},
+	}
+
+	collector := metrics.New()
+	bot, err := tgfsm.NewBot(token,
+		tgfsm.WithStates(states),
+		tgfsm.WithMetrics(collector),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	collector.WatchBot(bot)
+
+	server := admin.New(bot,
+		admin.WithToken(os.Getenv("ADMIN_TOKEN")),
+		admin.WithHandler(metrics.DefaultPath, collector),
+		// Not ready while more than 100 updates wait for handlers
+		admin.WithMaxPending(100),
+	)
+	go func() {
+		log.Fatal(server.ListenAndServe(":8081"))
+	}()
+
+	bot.Start(0, 10)
+
+	select {}
+}
+
+// start greets the user and moves them to the echo state
+func start(b *tgfsm.Bot, u tgbotapi.Update) error {
+	if err := b.SetUserState(u.SentFrom().ID, "echo"); err != nil {
+		return err
+	}
+	_, err := b.SendMessage(tgbotapi.NewMessage(u.Message.Chat.ID, "Send me anything"))
+	return err
+}
+
+// echo sends the message back to the user
+func echo(b *tgfsm.Bot, u tgbotapi.Update) error {
+	_, err := b.SendMessage(tgbotapi.NewMessage(u.Message.Chat.ID, u.Message.Text))
+	return err
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"tgfsm"
+	"tgfsm/events"
+)
+
+/*
+Checklist bot demonstrating ChecklistEvent.
+/topics opens a list of topics; the user can pick from one to three of them.
+*/
+func main() {
+	token := "YOUR_BOT_TOKEN"
+
+	states, err := events.NewChecklistEvent(
+		events.WithChecklistMessageTriggers("/start", "/topics"),
+		events.WithChecklistPromptText("На какие темы подписаться?"),
+		events.WithChecklistItems(
+			events.ChecklistItem{ID: "news", Text: "Новости"},
+			events.ChecklistItem{ID: "sport", Text: "Спорт"},
+			events.ChecklistItem{ID: "tech", Text: "Технологии"},
+			events.ChecklistItem{ID: "music", Text: "Музыка"},
+		),
+		events.WithChecklistBounds(1, 3),
+		events.WithChecklistColumns(2),
+		events.WithChecklistDoneText("Готово"),
+		events.WithChecklistSelectAllText("", "Снять все"),
+		events.WithChecklistBoundsText("Выберите хотя бы %d.", "Можно выбрать не больше %d."),
+		events.WithOnChecklistDone(func(selected []string, userId int64) error {
+			fmt.Printf("User %d subscribed to %v\n", userId, selected)
+			return nil
+		}),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bot, err := tgfsm.NewBot(token, tgfsm.WithStates(states))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bot.Start(0, 10)
+
+	select {}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"tgfsm"
+	"tgfsm/events"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+/*
+Bot demonstrating ConfirmDialog.
+/delete asks for confirmation; the answer (or its absence within 30 seconds) is reported back.
+*/
+func main() {
+	token := "YOUR_BOT_TOKEN"
+
+	dialog, err := events.NewConfirmDialog(
+		events.WithConfirmButtonsText("Да, удалить", "Нет"),
+		events.WithConfirmResolvedText("✅ Аккаунт удален", "❌ Отменено", "⌛ Время вышло"),
+		events.WithConfirmTimeout(30*time.Second),
+		events.WithOnConfirmYes(func(b *tgfsm.Bot, userId int64) error {
+			fmt.Printf("User %d deleted the account\n", userId)
+			return nil
+		}),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	states := dialog.States()
+	states["start"] = tgfsm.State{
+		Global: true,
+		MessageHandlers: map[string]tgfsm.Handler{
+			"/delete": {Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+				return dialog.Ask(b, u, "Удалить аккаунт? Это действие нельзя отменить.")
+			}},
+		},
+	}
+
+	bot, err := tgfsm.NewBot(token, tgfsm.WithStates(states))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bot.Start(0, 10)
+
+	select {}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"flag"
+	"fmt"
+	"log"
+	"tgfsm"
+	"tgfsm/definition"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+/*
+Guide bot built from the declarative definition in bot.yaml.
+Texts, keyboards and transitions live in the file; Go code provides only the registered handlers.
+
+By default the embedded bot.yaml is used. With -definition the file is read from disk
+and reloaded into the running bot whenever it changes.
+*/
+
+//go:embed bot.yaml
+var botDefinition []byte
+
+func main() {
+	token := "YOUR_BOT_TOKEN"
+	path := flag.String("definition", "", "definition file to load and watch for changes")
+	flag.Parse()
+
+	registry := definition.NewRegistry().
+		RegisterHandler("save_feedback", SaveFeedback).
+		RegisterHook("feedback_timeout", FeedbackTimeout)
+
+	var def *definition.Definition
+	var err error
+	if *path != "" {
+		def, err = definition.Load(*path, registry)
+	} else {
+		def, err = definition.Parse(botDefinition, definition.FormatYAML, registry)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bot, err := tgfsm.NewBot(token, def.Options()...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *path != "" {
+		go definition.Watch(context.Background(), *path, registry, 0, func(d *definition.Definition) error {
+			return bot.Reload(d.Options()...)
+		}, func(err error) {
+			log.Println("failed to reload definition:", err)
+		})
+	}
+
+	bot.Start(0, 10)
+
+	select {}
+}
+
+// SaveFeedback stores the user's feedback
+func SaveFeedback(b *tgfsm.Bot, u tgbotapi.Update) error {
+	if u.Message == nil {
+		return nil
+	}
+	fmt.Printf("Feedback from %d: %s\n", u.Message.From.ID, u.Message.Text)
+	return nil
+}
+
+// FeedbackTimeout tells the user that the feedback input was cancelled
+func FeedbackTimeout(b *tgfsm.Bot, t tgfsm.Transition) error {
+	_, err := b.SendMessage(tgbotapi.NewMessage(t.UserID, "⌛ Ввод отзыва отменен"))
+	return err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"tgfsm"
+	"tgfsm/events"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+/*
+Guide bot built with MenuTreeEvent instead of hand-wired states.
+Every screen is described declaratively; navigation buttons are added automatically.
+*/
+func main() {
+	token := "YOUR_BOT_TOKEN"
+
+	states, err := events.NewMenuTreeEvent(
+		events.WithMenuTreeMessageTriggers("/start", "/menu"),
+		events.WithMenuTreeRoot(Menu),
+		events.WithMenuTreeNavigationText("⬅️ Назад", "🏠 Главная"),
+		events.WithMenuTreeCloseText("✖️ Закрыть"),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bot, err := tgfsm.NewBot(token, tgfsm.WithStates(states))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bot.Start(0, 10)
+
+	select {}
+}
+
+var Menu = &events.MenuNode{
+	Title:     "Главная",
+	Text:      "🤖 <b>Гид</b>\n\nВыберите раздел:",
+	ParseMode: "HTML",
+	Children: []*events.MenuNode{
+		{
+			Title: "📖 О проекте",
+			Text:  "Надстройка над telegram-bot-api с FSM, ограничителем запросов и готовыми событиями.",
+		},
+		{
+			Title: "🧩 События",
+			Text:  "Готовые цепочки состояний:",
+			Children: []*events.MenuNode{
+				{Title: "Ввод данных", Text: "EnterDataEvent - сбор данных с валидацией и подтверждением."},
+				{Title: "Слайдер", Text: "SimpleSliderEvent - листание текстов inline кнопками."},
+				{Title: "Чек-лист", Text: "ChecklistEvent - множественный выбор пунктов."},
+			},
+		},
+		{
+			Title:  "🆔 Мой ID",
+			Action: HandleID,
+		},
+	},
+}
+
+// HandleID отправляет пользователю его ID, не покидая меню
+func HandleID(b *tgfsm.Bot, u tgbotapi.Update) error {
+	msg := tgbotapi.NewMessage(u.SentFrom().ID, fmt.Sprintf("Ваш ID: %d", u.SentFrom().ID))
+	_, err := b.SendImportantMessage(msg)
+	return err
+}
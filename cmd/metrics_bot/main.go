@@ -0,0 +1,64 @@
+package main
+
+import (
+	"log"
+	"tgfsm"
+	"tgfsm/metrics"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+/*
+A bot exposing its metrics in Prometheus text format on http://localhost:9090/metrics.
+/start moves the user to the "echo" state, where text messages are echoed back.
+*/
+func main() {
+	token := "YOUR_BOT_TOKEN"
+
+	states := map[string]tgfsm.State{
+		"start": {
+			Global: true,
+			MessageHandlers: map[string]tgfsm.Handler{
+				"/start": {Handle: start},
+			},
+		},
+		"echo": {
+			MessageHandlers: map[string]tgfsm.Handler{},
+			CatchAllFunc:    &tgfsm.Handler{Handle: echo},
+		},
+	}
+
+	collector := metrics.New()
+	bot, err := tgfsm.NewBot(token,
+		tgfsm.WithStates(states),
+		tgfsm.WithMetrics(collector),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Count users in each state on every scrape
+	collector.WatchBot(bot)
+
+	go func() {
+		log.Fatal(metrics.ListenAndServe(":9090", metrics.DefaultPath, collector))
+	}()
+
+	bot.Start(0, 10)
+
+	select {}
+}
+
+// start greets the user and moves them to the echo state
+func start(b *tgfsm.Bot, u tgbotapi.Update) error {
+	if err := b.SetUserState(u.SentFrom().ID, "echo"); err != nil {
+		return err
+	}
+	_, err := b.SendMessage(tgbotapi.NewMessage(u.Message.Chat.ID, "Send me anything"))
+	return err
+}
+
+// echo sends the message back to the user
+func echo(b *tgfsm.Bot, u tgbotapi.Update) error {
+	_, err := b.SendMessage(tgbotapi.NewMessage(u.Message.Chat.ID, u.Message.Text))
+	return err
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"tgfsm"
+	"tgfsm/events"
+	"time"
+)
+
+/*
+Reminder bot demonstrating TimePickerEvent and DurationPickerEvent.
+/at opens the time-of-day picker, /in opens the duration picker.
+*/
+func main() {
+	token := "YOUR_BOT_TOKEN"
+
+	timeStates, err := events.NewTimePickerEvent(
+		events.WithTimePickerMessageTriggers("/at"),
+		events.WithTimePickerPromptText("Когда напомнить?"),
+		events.WithTimePickerMinuteStep(15),
+		events.WithTimePickerInitialTime(9, 0),
+		events.WithOnTimeSelected(func(hour, minute int, userId int64) error {
+			fmt.Printf("User %d: remind at %02d:%02d\n", userId, hour, minute)
+			return nil
+		}),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	durationStates, err := events.NewDurationPickerEvent(
+		events.WithDurationPickerMessageTriggers("/in"),
+		events.WithDurationPickerPromptText("Через сколько напомнить?"),
+		events.WithDurationPickerSteps(time.Hour, 10*time.Minute),
+		events.WithDurationPickerRange(10*time.Minute, 12*time.Hour),
+		events.WithOnDurationSelected(func(duration time.Duration, userId int64) error {
+			fmt.Printf("User %d: remind in %s\n", userId, duration)
+			return nil
+		}),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Объединяем состояния обоих событий
+	states := make(map[string]tgfsm.State)
+	for id, state := range timeStates {
+		states[id] = state
+	}
+	for id, state := range durationStates {
+		states[id] = state
+	}
+
+	bot, err := tgfsm.NewBot(token, tgfsm.WithStates(states))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bot.Start(0, 10)
+
+	select {}
+}
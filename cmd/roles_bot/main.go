@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"tgfsm"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+/*
+Bot demonstrating the allowlist and roles.
+Only the team members and administrators of the team's group are served;
+/stats is available to everyone of them, /broadcast only to admins.
+*/
+func main() {
+	token := "YOUR_BOT_TOKEN"
+	var teamChatID int64 = -1001234567890
+
+	roles := tgfsm.NewMemoryRoleStore(map[int64][]string{
+		123456789: {"admin"},
+	})
+
+	bot, err := tgfsm.NewBot(token,
+		tgfsm.WithStates(States),
+		tgfsm.WithRoles(roles, tgfsm.ChatAdminRoles(teamChatID, "admin", 0)),
+		tgfsm.WithAllowlist([]int64{111111111, 222222222}),
+		tgfsm.WithAllowedRoles("admin"),
+		tgfsm.WithAccessDeniedHandler(HandleAccessDenied),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bot.Start(0, 10)
+
+	select {}
+}
+
+var States = map[string]tgfsm.State{
+	"team": {
+		Global: true,
+		MessageHandlers: map[string]tgfsm.Handler{
+			"/stats": {Handle: HandleStats},
+			"/broadcast": {
+				Handle: HandleBroadcast,
+				Roles:  []string{"admin"},
+			},
+		},
+	},
+}
+
+// HandleAccessDenied tells the user the command is not available
+func HandleAccessDenied(b *tgfsm.Bot, u tgbotapi.Update, roles []string) error {
+	if u.Message == nil {
+		return nil
+	}
+	_, err := b.SendMessage(tgbotapi.NewMessage(u.Message.Chat.ID, "⛔ Нет доступа"))
+	return err
+}
+
+// HandleStats shows the number of users in each state
+func HandleStats(b *tgfsm.Bot, u tgbotapi.Update) error {
+	text := "Пользователи по состояниям:"
+	for state, users := range b.ActiveUsers() {
+		text += "\n" + state + ": " + fmt.Sprint(users)
+	}
+	_, err := b.SendMessage(tgbotapi.NewMessage(u.Message.Chat.ID, text))
+	return err
+}
+
+// HandleBroadcast is available only to admins
+func HandleBroadcast(b *tgfsm.Bot, u tgbotapi.Update) error {
+	_, err := b.SendMessage(tgbotapi.NewMessage(u.Message.Chat.ID, "📣 Рассылка запущена"))
+	return err
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"tgfsm"
+)
+
+/*
+Tool rendering the states graph of a bot as a Graphviz DOT or Mermaid diagram.
+
+The graph is read as JSON produced by the bot itself, e.g.:
+
+	graph, _ := json.Marshal(tgfsm.NewGraph(states, ""))
+	os.WriteFile("graph.json", graph, 0o644)
+
+Usage:
+
+	tgfsm-graph -format dot graph.json | dot -Tsvg > graph.svg
+	tgfsm-graph -format mermaid < graph.json > graph.mmd
+*/
+func main() {
+	format := flag.String("format", "dot", "output format: dot or mermaid")
+	output := flag.String("o", "", "output file (stdout if empty)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-format dot|mermaid] [-o file] [graph.json]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var input io.Reader = os.Stdin
+	if flag.NArg() > 0 {
+		file, err := os.Open(flag.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	var graph tgfsm.Graph
+	if err := json.NewDecoder(input).Decode(&graph); err != nil {
+		log.Fatalf("failed to read graph: %v", err)
+	}
+
+	var diagram string
+	switch *format {
+	case "dot":
+		diagram = graph.DOT()
+	case "mermaid":
+		diagram = graph.Mermaid()
+	default:
+		log.Fatalf("unknown format %q", *format)
+	}
+
+	if *output == "" {
+		fmt.Print(diagram)
+		return
+	}
+	if err := os.WriteFile(*output, []byte(diagram), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"log"
+	"tgfsm"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+/*
+Bot demonstrating state lifecycle hooks.
+/name asks for the user's name; if no answer comes within 10 minutes the input is cancelled.
+*/
+func main() {
+	token := "YOUR_BOT_TOKEN"
+
+	bot, err := tgfsm.NewBot(token, tgfsm.WithStates(States))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bot.Start(0, 10)
+
+	select {}
+}
+
+var States = map[string]tgfsm.State{
+	"start": {
+		Global: true,
+		MessageHandlers: map[string]tgfsm.Handler{
+			"/name": {Handle: tgfsm.NewPushUserStateImmediateHandler("enter_name")},
+		},
+	},
+	"enter_name": {
+		TTL: 10 * time.Minute,
+		OnEnter: func(b *tgfsm.Bot, t tgfsm.Transition) error {
+			_, err := b.SendMessage(tgbotapi.NewMessage(t.UserID, "Как вас зовут?"))
+			return err
+		},
+		OnTimeout: func(b *tgfsm.Bot, t tgfsm.Transition) error {
+			_, err := b.SendMessage(tgbotapi.NewMessage(t.UserID, "⌛ Ввод отменен"))
+			return err
+		},
+		// The /name command itself is processed here, the name comes with the next message
+		AtEntranceFunc: &tgfsm.Handler{Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+			return nil
+		}},
+		CatchAllFunc: &tgfsm.Handler{Handle: HandleName},
+	},
+}
+
+// HandleName greets the user and leaves the input state
+func HandleName(b *tgfsm.Bot, u tgbotapi.Update) error {
+	if u.Message == nil || u.Message.Text == "" {
+		return nil
+	}
+
+	_, err := b.SendMessage(tgbotapi.NewMessage(u.Message.Chat.ID, "Приятно познакомиться, "+u.Message.Text+"!"))
+	if err != nil {
+		return err
+	}
+
+	_, err = b.PopUserState(u.SentFrom().ID)
+	return err
+}
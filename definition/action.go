@@ -0,0 +1,160 @@
+package definition
+
+import (
+	"tgfsm"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// transitionKind is the state change performed after an action
+type transitionKind int
+
+const (
+	transitionNone transitionKind = iota
+	// transitionGoto moves the user to the target state leaving pushed states (SetUserState)
+	transitionGoto
+	// transitionPush moves the user to the target state remembering the current one (PushUserState)
+	transitionPush
+	// transitionBack returns the user to the remembered state (PopUserState)
+	transitionBack
+	// transitionReset resets the user's state (ResetUserState)
+	transitionReset
+)
+
+// button is an inline keyboard button
+type button struct {
+	text     string
+	callback string
+	url      string
+}
+
+// action is a handler described in a definition
+// The parts run in order: registered handler, reply, transition.
+type action struct {
+	handler tgfsm.HandlerFunc
+
+	text           string
+	parseMode      string
+	keyboard       [][]button
+	replyKeyboard  [][]string
+	removeKeyboard bool
+	edit           bool
+	answer         string
+
+	transition transitionKind
+	target     string
+	immediate  bool
+}
+
+// handle runs the action
+func (a *action) handle(b *tgfsm.Bot, u tgbotapi.Update) error {
+	if u.CallbackQuery != nil {
+		callback := tgbotapi.NewCallback(u.CallbackQuery.ID, a.answer)
+		b.Request(callback)
+	}
+
+	if a.handler != nil {
+		if err := a.handler(b, u); err != nil {
+			return err
+		}
+	}
+
+	if a.text != "" {
+		if err := a.reply(b, u); err != nil {
+			return err
+		}
+	}
+
+	return a.transit(b, u)
+}
+
+// reply sends the text or edits the message with the pressed button
+func (a *action) reply(b *tgfsm.Bot, u tgbotapi.Update) error {
+	var inline *tgbotapi.InlineKeyboardMarkup
+	if a.keyboard != nil {
+		inline = a.inlineKeyboard()
+	}
+
+	if a.edit && u.CallbackQuery != nil && u.CallbackQuery.Message != nil && a.replyKeyboard == nil && !a.removeKeyboard {
+		message := u.CallbackQuery.Message
+		edit := tgbotapi.NewEditMessageText(message.Chat.ID, message.MessageID, a.text)
+		edit.ParseMode = a.parseMode
+		edit.ReplyMarkup = inline
+		_, err := b.EditMessage(edit)
+		return err
+	}
+
+	chatID := u.SentFrom().ID
+	if chat := u.FromChat(); chat != nil {
+		chatID = chat.ID
+	}
+	msg := tgbotapi.NewMessage(chatID, a.text)
+	msg.ParseMode = a.parseMode
+	switch {
+	case inline != nil:
+		msg.ReplyMarkup = inline
+	case a.replyKeyboard != nil:
+		msg.ReplyMarkup = a.replyKeyboardMarkup()
+	case a.removeKeyboard:
+		msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(true)
+	}
+	_, err := b.SendMessage(msg)
+	return err
+}
+
+// transit changes the user's state
+func (a *action) transit(b *tgfsm.Bot, u tgbotapi.Update) error {
+	userID := u.SentFrom().ID
+
+	switch a.transition {
+	case transitionGoto:
+		if a.immediate {
+			return b.SetUserStateImmediate(userID, a.target, u)
+		}
+		return b.SetUserState(userID, a.target)
+	case transitionPush:
+		if a.immediate {
+			return b.PushUserStateImmediate(userID, a.target, u)
+		}
+		return b.PushUserState(userID, a.target)
+	case transitionBack:
+		if a.immediate {
+			return b.PopUserStateImmediate(userID, u)
+		}
+		_, err := b.PopUserState(userID)
+		return err
+	case transitionReset:
+		b.ResetUserState(userID)
+	}
+	return nil
+}
+
+// inlineKeyboard builds the inline keyboard markup
+func (a *action) inlineKeyboard() *tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(a.keyboard))
+	for _, row := range a.keyboard {
+		buttons := make([]tgbotapi.InlineKeyboardButton, 0, len(row))
+		for _, btn := range row {
+			if btn.url != "" {
+				buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonURL(btn.text, btn.url))
+			} else {
+				buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(btn.text, btn.callback))
+			}
+		}
+		rows = append(rows, buttons)
+	}
+	return &tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// replyKeyboardMarkup builds the reply keyboard markup
+func (a *action) replyKeyboardMarkup() tgbotapi.ReplyKeyboardMarkup {
+	rows := make([][]tgbotapi.KeyboardButton, 0, len(a.replyKeyboard))
+	for _, row := range a.replyKeyboard {
+		buttons := make([]tgbotapi.KeyboardButton, 0, len(row))
+		for _, text := range row {
+			buttons = append(buttons, tgbotapi.NewKeyboardButton(text))
+		}
+		rows = append(rows, buttons)
+	}
+	return tgbotapi.NewReplyKeyboard(rows...)
+}
@@ -0,0 +1,135 @@
+// Package definition builds bot states from declarative YAML or JSON files
+//
+// A definition describes states, text replies, keyboards and transitions;
+// Go code is referenced by names registered in a Registry:
+//
+//	initial: menu
+//	states:
+//	  menu:
+//	    name: Main menu
+//	    transitions: [about]
+//	    messages:
+//	      /start:
+//	        text: Hello! Choose a section
+//	        keyboard:
+//	          - [{text: About, callback: about}]
+//	    callbacks:
+//	      about: {goto: about, immediate: true}
+//	  about:
+//	    entrance:
+//	      text: We are a small team
+//	      edit: true
+//	      back: true
+//
+// Syntax and schema errors are reported with line numbers (see Error).
+package definition
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"tgfsm"
+)
+
+// Format is the format of a definition
+type Format int
+
+const (
+	// FormatYAML is a definition in YAML
+	FormatYAML Format = iota + 1
+	// FormatJSON is a definition in JSON
+	FormatJSON
+)
+
+// FormatFromPath returns the format of the file by its extension: .yaml, .yml or .json
+func FormatFromPath(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".json":
+		return FormatJSON, nil
+	default:
+		return 0, tgfsm.NewSFMError(ErrUnknownFormat, path)
+	}
+}
+
+// Definition is the result of loading a definition
+type Definition struct {
+	// States are the states built from the definition
+	States map[string]tgfsm.State
+	// InitialState is the initial state, empty if not set in the definition
+	InitialState string
+}
+
+// Options returns bot options applying the definition (see tgfsm.WithStates and tgfsm.WithInitialState)
+// The initial state is always set, so reloading a definition without it clears the initial state.
+func (d *Definition) Options() []tgfsm.Option {
+	return []tgfsm.Option{
+		tgfsm.WithStates(d.States),
+		tgfsm.WithInitialState(d.InitialState),
+	}
+}
+
+// Load reads and builds the definition file
+// The format is determined by the file extension (see FormatFromPath)
+func Load(path string, registry *Registry) (*Definition, error) {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	definition, err := Parse(data, format, registry)
+	return definition, withFile(err, path)
+}
+
+// Parse builds the definition from data in the format
+// All schema errors found are returned joined (see errors.Join), each of them is an *Error.
+func Parse(data []byte, format Format, registry *Registry) (*Definition, error) {
+	if registry == nil {
+		return nil, ErrRegistryNil
+	}
+
+	var root *node
+	var err error
+	switch format {
+	case FormatYAML:
+		root, err = parseYAML(data)
+	case FormatJSON:
+		root, err = parseJSON(data)
+	default:
+		return nil, tgfsm.NewSFMError(ErrUnknownFormat, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(root, registry)
+}
+
+// withFile sets the file name of definition errors
+func withFile(err error, path string) error {
+	if err == nil {
+		return nil
+	}
+
+	var errs []error
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	} else {
+		errs = []error{err}
+	}
+
+	for _, e := range errs {
+		var definitionErr *Error
+		if errors.As(e, &definitionErr) {
+			definitionErr.File = path
+		}
+	}
+	return err
+}
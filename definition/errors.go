@@ -0,0 +1,44 @@
+package definition
+
+import (
+	"fmt"
+)
+
+var (
+	// ErrUnknownFormat is returned when the definition format cannot be determined
+	ErrUnknownFormat = fmt.Errorf("unknown definition format")
+
+	// ErrRegistryNil is returned when the handler registry is nil
+	ErrRegistryNil = fmt.Errorf("handler registry is nil")
+)
+
+// Error is a syntax or schema error in a definition with its position
+type Error struct {
+	// File is the name of the definition file, empty if the definition was not read from a file
+	File string
+	// Line is the 1-based line of the error, 0 if unknown
+	Line int
+	// Msg describes the error
+	Msg string
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.File != "" && e.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+	case e.Line > 0:
+		return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+	case e.File != "":
+		return fmt.Sprintf("%s: %s", e.File, e.Msg)
+	default:
+		return e.Msg
+	}
+}
+
+// newError creates an error at the line
+func newError(line int, format string, args ...interface{}) *Error {
+	return &Error{
+		Line: line,
+		Msg:  fmt.Sprintf(format, args...),
+	}
+}
@@ -0,0 +1,156 @@
+package definition
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+)
+
+// jsonParser parses a JSON document into nodes, keeping the lines of values
+type jsonParser struct {
+	data    []byte
+	dec     *json.Decoder
+	newline []int
+}
+
+// parseJSON parses a JSON document
+func parseJSON(data []byte) (*node, error) {
+	p := &jsonParser{
+		data: data,
+		dec:  json.NewDecoder(bytes.NewReader(data)),
+	}
+	p.dec.UseNumber()
+	for i, c := range data {
+		if c == '\n' {
+			p.newline = append(p.newline, i)
+		}
+	}
+
+	root, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.dec.Token(); err != io.EOF {
+		return nil, newError(p.line(p.start()), "unexpected content after the document")
+	}
+	return root, nil
+}
+
+// start returns the offset of the next token
+func (p *jsonParser) start() int {
+	offset := int(p.dec.InputOffset())
+	for offset < len(p.data) {
+		switch p.data[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+			continue
+		}
+		break
+	}
+	return offset
+}
+
+// line returns the 1-based line of the offset
+func (p *jsonParser) line(offset int) int {
+	return sort.SearchInts(p.newline, offset) + 1
+}
+
+// token reads the next token and returns it with its line
+func (p *jsonParser) token() (json.Token, int, error) {
+	line := p.line(p.start())
+	token, err := p.dec.Token()
+	if err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, p.line(int(syntaxErr.Offset)), newError(p.line(int(syntaxErr.Offset)), "%s", syntaxErr.Error())
+		}
+		if err == io.EOF {
+			return nil, line, newError(line, "unexpected end of document")
+		}
+		return nil, line, newError(line, "%s", err.Error())
+	}
+	return token, line, nil
+}
+
+// value parses the next value
+func (p *jsonParser) value() (*node, error) {
+	token, line, err := p.token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		if t == '{' {
+			return p.object(line)
+		}
+		if t == '[' {
+			return p.array(line)
+		}
+		return nil, newError(line, "unexpected %q", string(t))
+	case string:
+		return &node{kind: scalarNode, line: line, value: t}, nil
+	case json.Number:
+		return &node{kind: scalarNode, line: line, value: t.String()}, nil
+	case bool:
+		value := "false"
+		if t {
+			value = "true"
+		}
+		return &node{kind: scalarNode, line: line, value: value}, nil
+	default:
+		return &node{kind: scalarNode, line: line, null: true}, nil
+	}
+}
+
+// object parses the rest of an object
+func (p *jsonParser) object(line int) (*node, error) {
+	n := &node{kind: mappingNode, line: line}
+
+	for p.dec.More() {
+		token, keyLine, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, newError(keyLine, "object key must be a string")
+		}
+		if n.get(key) != nil {
+			return nil, newError(keyLine, "duplicate key %q", key)
+		}
+
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		n.entries = append(n.entries, entry{key: key, line: keyLine, value: value})
+	}
+
+	// Closing brace
+	if _, _, err := p.token(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// array parses the rest of an array
+func (p *jsonParser) array(line int) (*node, error) {
+	n := &node{kind: sequenceNode, line: line}
+
+	for p.dec.More() {
+		item, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		n.items = append(n.items, item)
+	}
+
+	// Closing bracket
+	if _, _, err := p.token(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
@@ -0,0 +1,56 @@
+package definition
+
+// nodeKind is the kind of a parsed document node
+type nodeKind int
+
+const (
+	scalarNode nodeKind = iota
+	mappingNode
+	sequenceNode
+)
+
+// String returns the kind name used in error messages
+func (k nodeKind) String() string {
+	switch k {
+	case mappingNode:
+		return "mapping"
+	case sequenceNode:
+		return "sequence"
+	default:
+		return "scalar"
+	}
+}
+
+// node is a document value with its position
+// YAML and JSON documents are both parsed into nodes, so the schema is decoded once
+type node struct {
+	kind nodeKind
+	line int
+
+	// value is the text of a scalar
+	value string
+	// null is true for YAML null, ~, empty values and JSON null
+	null bool
+
+	// entries are the key-value pairs of a mapping in document order
+	entries []entry
+	// items are the elements of a sequence
+	items []*node
+}
+
+// entry is a key-value pair of a mapping
+type entry struct {
+	key   string
+	line  int
+	value *node
+}
+
+// get returns the value of the mapping key, nil if there is no such key
+func (n *node) get(key string) *node {
+	for _, e := range n.entries {
+		if e.key == key {
+			return e.value
+		}
+	}
+	return nil
+}
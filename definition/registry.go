@@ -0,0 +1,42 @@
+package definition
+
+import (
+	"tgfsm"
+)
+
+// Registry holds the Go functions a definition refers to by name
+type Registry struct {
+	handlers map[string]tgfsm.HandlerFunc
+	hooks    map[string]tgfsm.HookFunc
+	guards   map[string]tgfsm.GuardFunc
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		handlers: make(map[string]tgfsm.HandlerFunc),
+		hooks:    make(map[string]tgfsm.HookFunc),
+		guards:   make(map[string]tgfsm.GuardFunc),
+	}
+}
+
+// RegisterHandler registers a handler used by "handler" of messages, callbacks, entrance and catch_all
+// Returns the registry for chaining
+func (r *Registry) RegisterHandler(name string, handler tgfsm.HandlerFunc) *Registry {
+	r.handlers[name] = handler
+	return r
+}
+
+// RegisterHook registers a hook used by on_enter, on_exit and on_timeout
+// Returns the registry for chaining
+func (r *Registry) RegisterHook(name string, hook tgfsm.HookFunc) *Registry {
+	r.hooks[name] = hook
+	return r
+}
+
+// RegisterGuard registers a guard used by guards
+// Returns the registry for chaining
+func (r *Registry) RegisterGuard(name string, guard tgfsm.GuardFunc) *Registry {
+	r.guards[name] = guard
+	return r
+}
@@ -0,0 +1,417 @@
+package definition
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"tgfsm"
+	"time"
+)
+
+// Keys allowed in a definition
+var (
+	rootKeys   = []string{"initial", "states"}
+	stateKeys  = []string{"name", "global", "priority", "only_in", "exclude_in", "ttl", "transitions", "guards", "on_enter", "on_exit", "on_timeout", "entrance", "catch_all", "messages", "callbacks"}
+	actionKeys = []string{"handler", "text", "parse_mode", "keyboard", "reply_keyboard", "remove_keyboard", "edit", "answer", "goto", "push", "back", "reset", "immediate"}
+	buttonKeys = []string{"text", "callback", "url"}
+)
+
+// decoder builds states from the document, collecting all errors
+type decoder struct {
+	registry *Registry
+	states   map[string]bool
+	errs     []error
+}
+
+// decode builds the definition from the document root
+func decode(root *node, registry *Registry) (*Definition, error) {
+	d := &decoder{
+		registry: registry,
+		states:   make(map[string]bool),
+	}
+
+	if !d.expect(root, mappingNode, "definition") {
+		return nil, errors.Join(d.errs...)
+	}
+	d.checkKeys(root, rootKeys)
+
+	statesNode := root.get("states")
+	if statesNode == nil || statesNode.null {
+		d.errorf(root.line, "states are required")
+		return nil, errors.Join(d.errs...)
+	}
+	if !d.expect(statesNode, mappingNode, "states") {
+		return nil, errors.Join(d.errs...)
+	}
+	// All names are known before decoding, so states may refer to states defined below them
+	for _, e := range statesNode.entries {
+		d.states[e.key] = true
+	}
+
+	definition := &Definition{
+		States: make(map[string]tgfsm.State, len(statesNode.entries)),
+	}
+	if initial := root.get("initial"); initial != nil {
+		definition.InitialState = d.stateRef(initial, "initial")
+	}
+	for _, e := range statesNode.entries {
+		if e.key == "" {
+			d.errorf(e.line, "state name cannot be empty")
+			continue
+		}
+		definition.States[e.key] = d.state(e.value)
+	}
+
+	if len(d.errs) > 0 {
+		return nil, errors.Join(d.errs...)
+	}
+	return definition, nil
+}
+
+// state decodes a state
+func (d *decoder) state(n *node) tgfsm.State {
+	var state tgfsm.State
+	if n.null {
+		return state
+	}
+	if !d.expect(n, mappingNode, "state") {
+		return state
+	}
+	d.checkKeys(n, stateKeys)
+
+	for _, e := range n.entries {
+		v := e.value
+		switch e.key {
+		case "name":
+			state.Name = d.str(v, e.key)
+		case "global":
+			state.Global = d.boolean(v, e.key)
+		case "priority":
+			state.Priority = d.integer(v, e.key)
+		case "only_in":
+			state.OnlyIn = d.scope(v, e.key)
+		case "exclude_in":
+			state.ExcludeIn = d.scope(v, e.key)
+		case "ttl":
+			state.TTL = d.duration(v, e.key)
+		case "transitions":
+			state.Transitions = []string{}
+			for _, item := range d.list(v, e.key) {
+				state.Transitions = append(state.Transitions, d.stateRef(item, e.key))
+			}
+		case "guards":
+			if !d.expect(v, mappingNode, e.key) {
+				continue
+			}
+			state.Guards = make(map[string]tgfsm.GuardFunc, len(v.entries))
+			for _, guard := range v.entries {
+				if !d.states[guard.key] {
+					d.errorf(guard.line, "guard for unknown state %q", guard.key)
+				}
+				state.Guards[guard.key] = d.guard(guard.value)
+			}
+		case "on_enter":
+			state.OnEnter = d.hook(v, e.key)
+		case "on_exit":
+			state.OnExit = d.hook(v, e.key)
+		case "on_timeout":
+			state.OnTimeout = d.hook(v, e.key)
+		case "entrance":
+			state.AtEntranceFunc = d.handler(v)
+		case "catch_all":
+			state.CatchAllFunc = d.handler(v)
+		case "messages":
+			state.MessageHandlers = d.handlers(v, e.key, true)
+		case "callbacks":
+			state.CallbackHandlers = d.handlers(v, e.key, false)
+		}
+	}
+
+	return state
+}
+
+// handlers decodes messages or callbacks
+// Message triggers are lowercased, because user text is matched in lowercase
+func (d *decoder) handlers(n *node, key string, lower bool) map[string]tgfsm.Handler {
+	if !d.expect(n, mappingNode, key) {
+		return nil
+	}
+
+	handlers := make(map[string]tgfsm.Handler, len(n.entries))
+	for _, e := range n.entries {
+		trigger := e.key
+		if lower {
+			trigger = strings.ToLower(strings.TrimSpace(trigger))
+		}
+		if _, ok := handlers[trigger]; ok {
+			d.errorf(e.line, "duplicate trigger %q", trigger)
+			continue
+		}
+		if handler := d.handler(e.value); handler != nil {
+			handlers[trigger] = *handler
+		}
+	}
+	return handlers
+}
+
+// handler decodes an action into a handler
+func (d *decoder) handler(n *node) *tgfsm.Handler {
+	a := d.action(n)
+	if a == nil {
+		return nil
+	}
+	return &tgfsm.Handler{Handle: a.handle}
+}
+
+// action decodes an action
+// A scalar is a shorthand for a text reply
+func (d *decoder) action(n *node) *action {
+	if n.kind == scalarNode && !n.null {
+		return &action{text: n.value}
+	}
+	if !d.expect(n, mappingNode, "action") {
+		return nil
+	}
+	d.checkKeys(n, actionKeys)
+
+	a := &action{}
+	transitions := 0
+	for _, e := range n.entries {
+		v := e.value
+		switch e.key {
+		case "handler":
+			name := d.str(v, e.key)
+			a.handler = d.registry.handlers[name]
+			if a.handler == nil && name != "" {
+				d.errorf(v.line, "handler %q is not registered", name)
+			}
+		case "text":
+			a.text = d.str(v, e.key)
+		case "parse_mode":
+			a.parseMode = d.str(v, e.key)
+		case "keyboard":
+			a.keyboard = d.keyboard(v)
+		case "reply_keyboard":
+			a.replyKeyboard = d.replyKeyboard(v)
+		case "remove_keyboard":
+			a.removeKeyboard = d.boolean(v, e.key)
+		case "edit":
+			a.edit = d.boolean(v, e.key)
+		case "answer":
+			a.answer = d.str(v, e.key)
+		case "goto":
+			transitions++
+			a.transition = transitionGoto
+			a.target = d.stateRef(v, e.key)
+		case "push":
+			transitions++
+			a.transition = transitionPush
+			a.target = d.stateRef(v, e.key)
+		case "back":
+			if d.boolean(v, e.key) {
+				transitions++
+				a.transition = transitionBack
+			}
+		case "reset":
+			if d.boolean(v, e.key) {
+				transitions++
+				a.transition = transitionReset
+			}
+		case "immediate":
+			a.immediate = d.boolean(v, e.key)
+		}
+	}
+
+	if transitions > 1 {
+		d.errorf(n.line, "only one of goto, push, back and reset can be set")
+	}
+	if a.text == "" && (a.keyboard != nil || a.replyKeyboard != nil || a.parseMode != "" || a.edit) {
+		d.errorf(n.line, "text is required for keyboard, reply_keyboard, parse_mode and edit")
+	}
+	if a.keyboard != nil && a.replyKeyboard != nil {
+		d.errorf(n.line, "keyboard and reply_keyboard cannot be set together")
+	}
+	if a.removeKeyboard && (a.keyboard != nil || a.replyKeyboard != nil) {
+		d.errorf(n.line, "remove_keyboard cannot be set with a keyboard")
+	}
+	return a
+}
+
+// keyboard decodes inline keyboard rows
+func (d *decoder) keyboard(n *node) [][]button {
+	var rows [][]button
+	for _, row := range d.list(n, "keyboard") {
+		var buttons []button
+		for _, b := range d.list(row, "keyboard row") {
+			if !d.expect(b, mappingNode, "button") {
+				continue
+			}
+			d.checkKeys(b, buttonKeys)
+
+			btn := button{
+				text:     d.optionalStr(b.get("text"), "text"),
+				callback: d.optionalStr(b.get("callback"), "callback"),
+				url:      d.optionalStr(b.get("url"), "url"),
+			}
+			switch {
+			case btn.text == "":
+				d.errorf(b.line, "button text is required")
+			case (btn.callback == "") == (btn.url == ""):
+				d.errorf(b.line, "button requires either callback or url")
+			case len(btn.callback) > 64:
+				d.errorf(b.line, "button callback is longer than 64 bytes")
+			}
+			buttons = append(buttons, btn)
+		}
+		rows = append(rows, buttons)
+	}
+	return rows
+}
+
+// replyKeyboard decodes reply keyboard rows
+func (d *decoder) replyKeyboard(n *node) [][]string {
+	var rows [][]string
+	for _, row := range d.list(n, "reply_keyboard") {
+		var buttons []string
+		for _, b := range d.list(row, "reply_keyboard row") {
+			buttons = append(buttons, d.str(b, "button"))
+		}
+		rows = append(rows, buttons)
+	}
+	return rows
+}
+
+// hook returns the registered hook
+func (d *decoder) hook(n *node, key string) tgfsm.HookFunc {
+	name := d.str(n, key)
+	hook := d.registry.hooks[name]
+	if hook == nil && name != "" {
+		d.errorf(n.line, "hook %q is not registered", name)
+	}
+	return hook
+}
+
+// guard returns the registered guard
+func (d *decoder) guard(n *node) tgfsm.GuardFunc {
+	name := d.str(n, "guard")
+	guard := d.registry.guards[name]
+	if guard == nil && name != "" {
+		d.errorf(n.line, "guard %q is not registered", name)
+	}
+	return guard
+}
+
+// stateRef decodes a name of a defined state
+func (d *decoder) stateRef(n *node, key string) string {
+	name := d.str(n, key)
+	if name != "" && !d.states[name] {
+		d.errorf(n.line, "%s refers to unknown state %q", key, name)
+	}
+	return name
+}
+
+// scope decodes a list of states of a global state scope; an empty string means "no state"
+func (d *decoder) scope(n *node, key string) []string {
+	states := []string{}
+	for _, item := range d.list(n, key) {
+		if item.kind == scalarNode && (item.null || item.value == "") {
+			states = append(states, "")
+			continue
+		}
+		states = append(states, d.stateRef(item, key))
+	}
+	return states
+}
+
+// list decodes a sequence; a single scalar is treated as a sequence of one item
+func (d *decoder) list(n *node, key string) []*node {
+	switch {
+	case n.kind == sequenceNode:
+		return n.items
+	case n.kind == scalarNode && !n.null:
+		return []*node{n}
+	default:
+		d.errorf(n.line, "%s must be a sequence", key)
+		return nil
+	}
+}
+
+// str decodes a required string
+func (d *decoder) str(n *node, key string) string {
+	if n.kind != scalarNode || n.null {
+		d.errorf(n.line, "%s must be a string", key)
+		return ""
+	}
+	return n.value
+}
+
+// optionalStr decodes a string that may be missing
+func (d *decoder) optionalStr(n *node, key string) string {
+	if n == nil || n.null {
+		return ""
+	}
+	return d.str(n, key)
+}
+
+// boolean decodes a boolean
+func (d *decoder) boolean(n *node, key string) bool {
+	if n.kind == scalarNode && !n.null {
+		if value, err := strconv.ParseBool(n.value); err == nil {
+			return value
+		}
+	}
+	d.errorf(n.line, "%s must be true or false", key)
+	return false
+}
+
+// integer decodes an integer
+func (d *decoder) integer(n *node, key string) int {
+	if n.kind == scalarNode && !n.null {
+		if value, err := strconv.Atoi(n.value); err == nil {
+			return value
+		}
+	}
+	d.errorf(n.line, "%s must be an integer", key)
+	return 0
+}
+
+// duration decodes a duration like "10m" or "1h30m"
+func (d *decoder) duration(n *node, key string) time.Duration {
+	value := d.str(n, key)
+	if value == "" {
+		return 0
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		d.errorf(n.line, "%s must be a non-negative duration like 10m, got %q", key, value)
+		return 0
+	}
+	return duration
+}
+
+// expect checks the kind of the node
+func (d *decoder) expect(n *node, kind nodeKind, what string) bool {
+	if n.kind != kind || n.null {
+		d.errorf(n.line, "%s must be a %s", what, kind)
+		return false
+	}
+	return true
+}
+
+// checkKeys reports keys of the mapping that are not allowed
+func (d *decoder) checkKeys(n *node, allowed []string) {
+	for _, e := range n.entries {
+		if !slices.Contains(allowed, e.key) {
+			sorted := append([]string(nil), allowed...)
+			sort.Strings(sorted)
+			d.errorf(e.line, "unknown key %q, expected one of: %s", e.key, strings.Join(sorted, ", "))
+		}
+	}
+}
+
+// errorf records an error at the line
+func (d *decoder) errorf(line int, format string, args ...interface{}) {
+	d.errs = append(d.errs, newError(line, format, args...))
+}
@@ -0,0 +1,80 @@
+package definition
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"time"
+)
+
+// DefaultWatchInterval is the default interval of checking the definition file for changes
+const DefaultWatchInterval = 2 * time.Second
+
+// Watch checks the definition file every interval and calls reload with the new definition
+// when the file content changes. Typically reload applies the definition to a running bot:
+//
+//	go definition.Watch(ctx, "bot.yaml", registry, 0, func(d *definition.Definition) error {
+//		return bot.Reload(d.Options()...)
+//	}, func(err error) {
+//		log.Println("definition reload failed:", err)
+//	})
+//
+// The file is polled rather than watched with OS notifications, so editors replacing the file
+// and network filesystems are handled the same way. The content at the moment Watch is called
+// is considered already applied. A definition with errors is not passed to reload: the error
+// goes to onError (may be nil) and the bot keeps working with the previous states.
+// Watch blocks until ctx is done.
+func Watch(ctx context.Context, path string, registry *Registry, interval time.Duration, reload func(*Definition) error, onError func(error)) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	report := func(err error) {
+		if onError != nil {
+			onError(err)
+		}
+	}
+
+	applied, err := os.ReadFile(path)
+	if err != nil {
+		report(err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			// The file may be missing for a moment while an editor replaces it
+			if !os.IsNotExist(err) {
+				report(err)
+			}
+			continue
+		}
+		if bytes.Equal(data, applied) {
+			continue
+		}
+		// The content is remembered even if it is invalid, so the error is reported once
+		applied = data
+
+		format, err := FormatFromPath(path)
+		if err != nil {
+			report(err)
+			continue
+		}
+		definition, err := Parse(data, format, registry)
+		if err != nil {
+			report(withFile(err, path))
+			continue
+		}
+		if err := reload(definition); err != nil {
+			report(err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package definition
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlErrorLine matches the position in syntax errors of yaml.v3: "yaml: line 3: ..."
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// parseYAML parses a YAML document
+// The document is parsed by yaml.v3 and converted to nodes keeping the lines of values.
+// Aliases and merge keys ("<<: *base") are resolved; multiple documents are reported as an error.
+// yaml.v3 does not support the "\/" escape of double-quoted scalars, use "/" instead.
+func parseYAML(data []byte) (*node, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+
+	var doc yaml.Node
+	if err := dec.Decode(&doc); err != nil {
+		if err == io.EOF {
+			return &node{kind: scalarNode, line: 1, null: true}, nil
+		}
+		return nil, yamlError(err)
+	}
+
+	var next yaml.Node
+	if err := dec.Decode(&next); err == nil {
+		line := next.Line
+		if len(next.Content) > 0 {
+			line = next.Content[0].Line
+		}
+		return nil, newError(line, "multiple documents are not supported")
+	} else if err != io.EOF {
+		return nil, yamlError(err)
+	}
+
+	if len(doc.Content) == 0 {
+		return &node{kind: scalarNode, line: doc.Line, null: true}, nil
+	}
+	return convertYAML(doc.Content[0], make(map[*yaml.Node]bool))
+}
+
+// convertYAML converts a yaml.v3 node to a document node
+// active are the anchored nodes being converted, an alias to one of them is recursive.
+func convertYAML(y *yaml.Node, active map[*yaml.Node]bool) (*node, error) {
+	switch y.Kind {
+	case yaml.AliasNode:
+		if active[y.Alias] {
+			return nil, newError(y.Line, "alias %q refers to its own value", y.Value)
+		}
+		active[y.Alias] = true
+		defer delete(active, y.Alias)
+		return convertYAML(y.Alias, active)
+
+	case yaml.ScalarNode:
+		n := &node{kind: scalarNode, line: y.Line, value: y.Value}
+		// Null is recognized by the resolved tag: null, ~ and empty values
+		n.null = y.ShortTag() == "!!null"
+		return n, nil
+
+	case yaml.MappingNode:
+		n := &node{kind: mappingNode, line: y.Line}
+		var merges []*yaml.Node
+		for i := 0; i+1 < len(y.Content); i += 2 {
+			key, value := y.Content[i], y.Content[i+1]
+			if key.Kind == yaml.AliasNode {
+				key = key.Alias
+			}
+			if key.Kind != yaml.ScalarNode {
+				return nil, newError(key.Line, "mapping key must be a scalar")
+			}
+			if key.ShortTag() == "!!merge" {
+				merges = append(merges, value)
+				continue
+			}
+			if n.get(key.Value) != nil {
+				return nil, newError(key.Line, "duplicate key %q", key.Value)
+			}
+
+			converted, err := convertYAML(value, active)
+			if err != nil {
+				return nil, err
+			}
+			n.entries = append(n.entries, entry{key: key.Value, line: key.Line, value: converted})
+		}
+		for _, merge := range merges {
+			if err := mergeYAML(n, merge, active); err != nil {
+				return nil, err
+			}
+		}
+		return n, nil
+
+	case yaml.SequenceNode:
+		n := &node{kind: sequenceNode, line: y.Line}
+		for _, item := range y.Content {
+			converted, err := convertYAML(item, active)
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, converted)
+		}
+		return n, nil
+
+	default:
+		return nil, newError(y.Line, "unexpected YAML node")
+	}
+}
+
+// mergeYAML adds the entries of a merge key ("<<: *base") to the mapping n
+// The value is a mapping or a sequence of mappings, usually aliases; keys already in n are kept,
+// and of the mappings in a sequence the earlier ones take precedence.
+func mergeYAML(n *node, merge *yaml.Node, active map[*yaml.Node]bool) error {
+	sources := []*yaml.Node{merge}
+	if merge.Kind == yaml.SequenceNode {
+		sources = merge.Content
+	}
+
+	for _, source := range sources {
+		converted, err := convertYAML(source, active)
+		if err != nil {
+			return err
+		}
+		if converted.kind != mappingNode {
+			return newError(source.Line, "merge key value must be a mapping or a sequence of mappings")
+		}
+		for _, e := range converted.entries {
+			if n.get(e.key) == nil {
+				n.entries = append(n.entries, e)
+			}
+		}
+	}
+	return nil
+}
+
+// yamlError converts a syntax error of yaml.v3 to an *Error with the line of the error
+func yamlError(err error) error {
+	match := yamlErrorLine.FindStringSubmatch(err.Error())
+	if match == nil {
+		return newError(0, "%s", err.Error())
+	}
+	line, _ := strconv.Atoi(match[1])
+	return newError(line, "%s", match[2])
+}
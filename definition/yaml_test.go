@@ -0,0 +1,203 @@
+package definition
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestParseYAMLScalars(t *testing.T) {
+	root, err := parseYAML([]byte(`# greeting bot
+plain: Hello, world
+single: 'it''s'
+double: "quote \" tab\t\u263A"
+number: 42
+empty:
+tilde: ~
+quoted_null: "null"
+literal: |
+  line 1
+  line 2
+folded: >-
+  one
+  two
+flow: [a, "b", {text: Hi, callback: hi}]
+`))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	tests := []struct {
+		key   string
+		value string
+		null  bool
+		line  int
+	}{
+		{"plain", "Hello, world", false, 2},
+		{"single", "it's", false, 3},
+		{"double", "quote \" tab\t☺", false, 4},
+		{"number", "42", false, 5},
+		{"empty", "", true, 6},
+		{"tilde", "~", true, 7},
+		{"quoted_null", "null", false, 8},
+		{"literal", "line 1\nline 2\n", false, 9},
+		{"folded", "one two", false, 12},
+	}
+	for _, tt := range tests {
+		n := root.get(tt.key)
+		if n == nil {
+			t.Fatalf("key %q not found", tt.key)
+		}
+		if n.kind != scalarNode || n.value != tt.value || n.null != tt.null || n.line != tt.line {
+			t.Errorf("%s: got %s %q null=%v line %d, want %q null=%v line %d",
+				tt.key, n.kind, n.value, n.null, n.line, tt.value, tt.null, tt.line)
+		}
+	}
+
+	flow := root.get("flow")
+	if flow.kind != sequenceNode || len(flow.items) != 3 {
+		t.Fatalf("flow is %s with %d items, want a sequence of 3", flow.kind, len(flow.items))
+	}
+	if button := flow.items[2]; button.get("callback") == nil || button.get("callback").value != "hi" {
+		t.Fatalf("flow mapping %+v, want callback hi", button)
+	}
+}
+
+func TestParseYAMLStructure(t *testing.T) {
+	root, err := parseYAML([]byte(`---
+defaults: &defaults
+  text: Back
+states:
+  menu:
+    keyboard:
+      - text: One
+      - <<: *defaults
+    copy: *defaults
+`))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	states := root.get("states")
+	if states.entries[0].key != "menu" || states.entries[0].line != 5 {
+		t.Fatalf("states %+v, want menu at line 5", states.entries)
+	}
+	menu := states.get("menu")
+	keyboard := menu.get("keyboard")
+	if keyboard.kind != sequenceNode || len(keyboard.items) != 2 || keyboard.items[0].line != 7 {
+		t.Fatalf("keyboard %+v, want a sequence of 2 starting at line 7", keyboard)
+	}
+	if merged := keyboard.items[1]; merged.get("text") == nil || merged.get("text").value != "Back" {
+		t.Fatalf("merge key resolved to %+v, want the anchored mapping", merged)
+	}
+	if copied := menu.get("copy"); copied.kind != mappingNode || copied.get("text").value != "Back" {
+		t.Fatalf("alias resolved to %+v, want the anchored mapping", copied)
+	}
+	if entries := menu.entries; entries[0].key != "keyboard" || entries[1].key != "copy" {
+		t.Fatalf("entries %+v, want document order", entries)
+	}
+}
+
+func TestParseYAMLMerge(t *testing.T) {
+	root, err := parseYAML([]byte(`base: &base
+  text: Back
+  callback: back
+extra: &extra
+  text: Extra
+  style: bold
+single:
+  <<: *base
+  callback: home
+several:
+  <<: [*extra, *base]
+nested:
+  <<: {<<: *base, style: plain}
+`))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	tests := []struct {
+		key    string
+		values map[string]string
+	}{
+		{"single", map[string]string{"text": "Back", "callback": "home"}},
+		{"several", map[string]string{"text": "Extra", "style": "bold", "callback": "back"}},
+		{"nested", map[string]string{"text": "Back", "callback": "back", "style": "plain"}},
+	}
+	for _, tt := range tests {
+		n := root.get(tt.key)
+		if n == nil || len(n.entries) != len(tt.values) {
+			t.Fatalf("%s: got %+v, want %d keys", tt.key, n, len(tt.values))
+		}
+		for key, value := range tt.values {
+			if v := n.get(key); v == nil || v.value != value {
+				t.Errorf("%s.%s: got %+v, want %q", tt.key, key, v, value)
+			}
+		}
+		if n.get("<<") != nil {
+			t.Errorf("%s: merge key kept as an entry", tt.key)
+		}
+	}
+	// Explicit keys keep their lines
+	if line := root.get("single").get("callback").line; line != 9 {
+		t.Errorf("explicit key at line %d, want 9", line)
+	}
+}
+
+func TestParseYAMLEmpty(t *testing.T) {
+	for _, data := range []string{"", "# only a comment\n", "---\n"} {
+		root, err := parseYAML([]byte(data))
+		if err != nil {
+			t.Fatalf("%q: parse failed: %v", data, err)
+		}
+		if root.kind != scalarNode || !root.null {
+			t.Fatalf("%q: got %+v, want null", data, root)
+		}
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		line int
+	}{
+		{"duplicate key", "a: 1\nb: 2\na: 3\n", 3},
+		{"bad indentation", "a:\n  b: 1\n c: 2\n", 2},
+		{"unclosed quote", "a: 1\nb: \"text\n", 2},
+		{"multiple documents", "a: 1\n---\nb: 2\n", 3},
+		{"mapping key", "? [a]\n: 1\n", 1},
+		{"tab", "a:\n\tb: 1\n", 2},
+		{"unsupported escape", "a: 1\nb: \"\\/\"\n", 2},
+		{"merge scalar", "a: 1\nb:\n  <<: text\n", 3},
+		{"merge sequence of scalars", "a:\n  <<: [x, y]\n", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseYAML([]byte(tt.data))
+			var defErr *Error
+			if !errors.As(err, &defErr) {
+				t.Fatalf("error %v, want *Error", err)
+			}
+			if defErr.Line != tt.line {
+				t.Fatalf("error %q at line %d, want line %d", defErr.Msg, defErr.Line, tt.line)
+			}
+		})
+	}
+}
+
+func TestParseYAMLExample(t *testing.T) {
+	data, err := os.ReadFile("../cmd/definition_bot/bot.yaml")
+	if err != nil {
+		t.Fatalf("failed to read example: %v", err)
+	}
+	root, err := parseYAML(data)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if root.kind != mappingNode {
+		t.Fatalf("root is %s, want mapping", root.kind)
+	}
+}
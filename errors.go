@@ -18,21 +18,54 @@ var (
 	// ErrNegativeCleanup is returned when cleanup interval is negative
 	ErrNegativeCleanup = fmt.Errorf("cleanup interval cannot be negative")
 
+	// ErrNegativeStateHistory is returned when maximum state history size is negative
+	ErrNegativeStateHistory = fmt.Errorf("state history size cannot be negative")
+
 	// ErrTelegramInit is returned when Telegram API initialization fails
 	ErrTelegramInit = fmt.Errorf("failed to initialize telegram bot api")
 
 	// ErrBotStarted is returned when trying to modify configuration of a running bot
 	ErrBotStarted = fmt.Errorf("cannot modify running bot")
 
+	// ErrOptionNotReloadable is returned by Reload for options it does not accept, e.g. WithExpiration
+	ErrOptionNotReloadable = fmt.Errorf("option cannot be applied by reload")
+
 	// ErrStateNotFound is returned when user state is not found in cache
 	ErrStateNotFound = fmt.Errorf("user state not found")
 
 	// ErrInvalidStateType is returned when state type assertion fails
 	ErrInvalidStateType = fmt.Errorf("invalid state type in cache")
 
+	// ErrInitialStateNotFound is returned when the initial state is not present in states map
+	ErrInitialStateNotFound = fmt.Errorf("initial state not found in states")
+
+	// ErrOrphanStateNotFound is returned when the orphan state is not present in states map
+	ErrOrphanStateNotFound = fmt.Errorf("orphan state not found in states")
+
 	// ErrStateHandlerNotFound is returned when handler for state is not found
 	ErrStateHandlerNotFound = fmt.Errorf("state handler not found")
 
+	// ErrTransitionNotAllowed is returned when the target state is not declared in State.Transitions
+	ErrTransitionNotAllowed = fmt.Errorf("transition not allowed")
+
+	// ErrTransitionRejected is returned when a transition guard rejects the transition
+	ErrTransitionRejected = fmt.Errorf("transition rejected by guard")
+
+	// ErrDanglingTransition is reported when a transition refers to a state that does not exist
+	ErrDanglingTransition = fmt.Errorf("transition to unknown state")
+
+	// ErrDuplicateGlobalTrigger is reported when several global states handle the same trigger
+	ErrDuplicateGlobalTrigger = fmt.Errorf("trigger is handled by several global states")
+
+	// ErrUnknownScopeState is reported when OnlyIn or ExcludeIn refers to a state that does not exist
+	ErrUnknownScopeState = fmt.Errorf("scope refers to unknown state")
+
+	// ErrUnreachableState is reported when no state declares a transition to the state
+	ErrUnreachableState = fmt.Errorf("state is unreachable")
+
+	// ErrInvalidStates is returned by NewBot in strict validation mode when states have problems
+	ErrInvalidStates = fmt.Errorf("invalid states configuration")
+
 	// ErrSendMessageFailed is returned when all attempts to send message failed
 	ErrSendMessageFailed = fmt.Errorf("all attempts to send message failed")
 
@@ -44,6 +77,15 @@ var (
 
 	// ErrEmptyTriggers is returned when messageTriggers and callBackTriggers are empty
 	ErrEmptyTriggers = fmt.Errorf("messageTriggers and callBackTriggers are empty")
+
+	// ErrStorageUnavailable is returned by CheckStorage when a storage of the bot is unreachable
+	ErrStorageUnavailable = fmt.Errorf("storage unavailable")
+
+	// ErrBlacklistStore is returned when the blacklist store fails
+	ErrBlacklistStore = fmt.Errorf("blacklist store failed")
+
+	// ErrAccessDenied is returned when the user lacks the roles required by a state or handler
+	ErrAccessDenied = fmt.Errorf("access denied")
 )
 
 // SFMError represents an error with additional context information
@@ -0,0 +1,470 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"tgfsm"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/google/uuid"
+)
+
+// Значения по умолчанию для конфигурации чек-листа
+const (
+	ChecklistPromptText    = "Choose all that apply:"
+	ChecklistDoneText      = "Done"
+	ChecklistSelectAllText = "Select all"
+	ChecklistClearText     = "Clear"
+	ChecklistCheckedMark   = "✅"
+	ChecklistUncheckedMark = "⬜"
+	ChecklistMinText       = "Select at least %d."
+	ChecklistMaxText       = "You can select at most %d."
+	ChecklistColumns       = 1
+)
+
+// Callback данные кнопок чек-листа, к ним добавляется префикс экземпляра чек-листа
+const (
+	checklistCallbackToggle    = "toggle:"
+	checklistCallbackSelectAll = "select_all"
+	checklistCallbackClear     = "clear"
+	checklistCallbackDone      = "done"
+)
+
+var (
+	ErrEmptyChecklistItems          = errors.New("checklist items are required and cannot be empty")
+	ErrDuplicateChecklistItem       = errors.New("checklist item IDs must be unique")
+	ErrChecklistItemIDTooLong       = errors.New("checklist item ID is too long for callback data")
+	ErrInvalidChecklistBounds       = errors.New("checklist selection bounds are invalid")
+	ErrChecklistDoneActionRequired  = errors.New("checklist done action is required")
+	ErrInvalidChecklistColumns      = errors.New("checklist columns must be positive")
+	ErrUnknownChecklistInitialValue = errors.New("checklist initial selection contains unknown item")
+)
+
+// ===== Опции для ChecklistConfig =====
+
+// ChecklistOption опция для конфигурации чек-листа
+type ChecklistOption func(*ChecklistConfig)
+
+// ChecklistItem представляет пункт чек-листа.
+// ID передается в функцию завершения и используется в callback данных,
+// поэтому должен быть коротким (не более 45 байт).
+type ChecklistItem struct {
+	ID   string
+	Text string
+}
+
+// ChecklistConfig конфигурация для чек-листа с множественным выбором
+type ChecklistConfig struct {
+	MessageTriggers  []string
+	CallbackTriggers []string
+	Items            []ChecklistItem
+	InitialSelected  []string
+	PromptText       string
+	DoneText         string
+	SelectAllText    string
+	ClearText        string
+	CheckedMark      string
+	UncheckedMark    string
+	MinText          string
+	MaxText          string
+	MinSelected      int
+	MaxSelected      int
+	Columns          int
+	OnChecklistDone  func(selected []string, userId int64) error
+	SelectedKey      string
+
+	// callbackPrefix отличает callback данные чек-листа от callback данных бота и других событий
+	callbackPrefix string
+	// locks сериализует изменения выбора одного пользователя
+	locks userLocks
+}
+
+// WithChecklistMessageTriggers устанавливает глобальные триггеры для входа в режим чек-листа.
+// При указании пользователь сможет войти в состояние чек-листа, отправив триггер.
+func WithChecklistMessageTriggers(triggers ...string) ChecklistOption {
+	return func(config *ChecklistConfig) {
+		config.MessageTriggers = triggers
+	}
+}
+
+// WithChecklistCallbackTriggers устанавливает глобальные триггеры для входа в режим чек-листа.
+// При указании пользователь сможет войти в состояние чек-листа, отправив callback.
+func WithChecklistCallbackTriggers(triggers ...string) ChecklistOption {
+	return func(config *ChecklistConfig) {
+		config.CallbackTriggers = triggers
+	}
+}
+
+// WithChecklistItems устанавливает пункты чек-листа.
+// Обязательный параметр.
+func WithChecklistItems(items ...ChecklistItem) ChecklistOption {
+	return func(config *ChecklistConfig) {
+		config.Items = items
+	}
+}
+
+// WithChecklistInitialSelected устанавливает пункты, отмеченные при входе в событие.
+func WithChecklistInitialSelected(ids ...string) ChecklistOption {
+	return func(config *ChecklistConfig) {
+		config.InitialSelected = ids
+	}
+}
+
+// WithChecklistPromptText устанавливает текст над чек-листом.
+// Если не установить, используется значение по умолчанию:
+//
+//	`ChecklistPromptText = "Choose all that apply:"`
+func WithChecklistPromptText(text string) ChecklistOption {
+	return func(config *ChecklistConfig) {
+		config.PromptText = text
+	}
+}
+
+// WithChecklistDoneText устанавливает текст кнопки завершения выбора.
+// Если не установить, используется значение по умолчанию:
+//
+//	`ChecklistDoneText = "Done"`
+func WithChecklistDoneText(text string) ChecklistOption {
+	return func(config *ChecklistConfig) {
+		config.DoneText = text
+	}
+}
+
+// WithChecklistSelectAllText устанавливает тексты кнопок "выбрать все" и "снять все".
+// Если установить пустое значение, соответствующая кнопка не показывается.
+// Кнопка "выбрать все" также скрывается, если ограничение WithChecklistBounds меньше количества пунктов.
+//
+// Если не установить, используются значения по умолчанию:
+//
+//	`ChecklistSelectAllText = "Select all"`
+//	`ChecklistClearText = "Clear"`
+func WithChecklistSelectAllText(selectAll, clear string) ChecklistOption {
+	return func(config *ChecklistConfig) {
+		config.SelectAllText = selectAll
+		config.ClearText = clear
+	}
+}
+
+// WithChecklistMarks устанавливает отметки выбранного и невыбранного пункта.
+// Если не установить, используются значения по умолчанию:
+//
+//	`ChecklistCheckedMark = "✅"`
+//	`ChecklistUncheckedMark = "⬜"`
+func WithChecklistMarks(checked, unchecked string) ChecklistOption {
+	return func(config *ChecklistConfig) {
+		config.CheckedMark = checked
+		config.UncheckedMark = unchecked
+	}
+}
+
+// WithChecklistBounds устанавливает минимальное и максимальное количество выбранных пунктов.
+// Значение 0 для max означает отсутствие ограничения.
+func WithChecklistBounds(min, max int) ChecklistOption {
+	return func(config *ChecklistConfig) {
+		config.MinSelected = min
+		config.MaxSelected = max
+	}
+}
+
+// WithChecklistBoundsText устанавливает тексты всплывающих предупреждений о нарушении ограничений.
+// Использует форматирование: %d будет заменен на минимум или максимум соответственно.
+// Если не установить, используются значения по умолчанию:
+//
+//	`ChecklistMinText = "Select at least %d."`
+//	`ChecklistMaxText = "You can select at most %d."`
+func WithChecklistBoundsText(minText, maxText string) ChecklistOption {
+	return func(config *ChecklistConfig) {
+		config.MinText = minText
+		config.MaxText = maxText
+	}
+}
+
+// WithChecklistColumns устанавливает количество пунктов в одной строке клавиатуры.
+// Если не установить, каждый пункт выводится на отдельной строке.
+func WithChecklistColumns(columns int) ChecklistOption {
+	return func(config *ChecklistConfig) {
+		config.Columns = columns
+	}
+}
+
+// WithOnChecklistDone устанавливает функцию, которая получит ID выбранных пунктов
+// в порядке их объявления. Обязательный параметр.
+func WithOnChecklistDone(action func(selected []string, userId int64) error) ChecklistOption {
+	return func(config *ChecklistConfig) {
+		config.OnChecklistDone = action
+	}
+}
+
+// NewChecklistEvent создает цепочку состояний для чек-листа с множественным выбором.
+//
+// Нажатие на пункт переключает отметку ✅/⬜, сообщение редактируется на месте.
+// Кнопка завершения передает выбранные ID в функцию WithOnChecklistDone.
+func NewChecklistEvent(opts ...ChecklistOption) (map[string]tgfsm.State, error) {
+	config := &ChecklistConfig{
+		PromptText:    ChecklistPromptText,
+		DoneText:      ChecklistDoneText,
+		SelectAllText: ChecklistSelectAllText,
+		ClearText:     ChecklistClearText,
+		CheckedMark:   ChecklistCheckedMark,
+		UncheckedMark: ChecklistUncheckedMark,
+		MinText:       ChecklistMinText,
+		MaxText:       ChecklistMaxText,
+		Columns:       ChecklistColumns,
+		SelectedKey:   uuid.New().String(),
+
+		callbackPrefix: newCallbackPrefix("cl"),
+	}
+
+	// Применяем все опции
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	// Валидация обязательных полей
+	if len(config.MessageTriggers) == 0 && len(config.CallbackTriggers) == 0 {
+		return nil, tgfsm.ErrEmptyTriggers
+	}
+
+	if len(config.Items) == 0 {
+		return nil, ErrEmptyChecklistItems
+	}
+
+	if config.OnChecklistDone == nil {
+		return nil, ErrChecklistDoneActionRequired
+	}
+
+	if config.Columns <= 0 {
+		return nil, ErrInvalidChecklistColumns
+	}
+
+	known := make(map[string]bool, len(config.Items))
+	for _, item := range config.Items {
+		if known[item.ID] {
+			return nil, tgfsm.NewSFMError(ErrDuplicateChecklistItem, item.ID)
+		}
+		// Лимит callback данных Telegram - 64 байта
+		if len(config.callbackPrefix+checklistCallbackToggle+item.ID) > 64 {
+			return nil, tgfsm.NewSFMError(ErrChecklistItemIDTooLong, item.ID)
+		}
+		known[item.ID] = true
+	}
+
+	if config.MinSelected < 0 || config.MaxSelected < 0 ||
+		config.MinSelected > len(config.Items) ||
+		(config.MaxSelected > 0 && config.MaxSelected < config.MinSelected) {
+		return nil, ErrInvalidChecklistBounds
+	}
+
+	for _, id := range config.InitialSelected {
+		if !known[id] {
+			return nil, tgfsm.NewSFMError(ErrUnknownChecklistInitialValue, id)
+		}
+	}
+	if config.MaxSelected > 0 && len(config.InitialSelected) > config.MaxSelected {
+		return nil, ErrInvalidChecklistBounds
+	}
+
+	return buildChecklistStates(config)
+}
+
+// buildChecklistStates создает состояния для чек-листа
+func buildChecklistStates(config *ChecklistConfig) (map[string]tgfsm.State, error) {
+	var checklistStateID = uuid.New().String()
+
+	// Обработчики читают и сохраняют выбор под блокировкой пользователя,
+	// чтобы быстрые нажатия не перезаписывали изменения друг друга
+	callbackHandlers := map[string]tgfsm.Handler{
+		config.callbackPrefix + checklistCallbackSelectAll: {
+			Handle: config.locks.locked(func(b *tgfsm.Bot, u tgbotapi.Update) error {
+				// Кнопка скрыта, но устаревшее сообщение или подделанный callback может ее нажать
+				if config.MaxSelected > 0 && len(config.Items) > config.MaxSelected {
+					answerCallback(b, u, fmt.Sprintf(config.MaxText, config.MaxSelected))
+					return nil
+				}
+				answerCallback(b, u, "")
+
+				selected := make(map[string]bool, len(config.Items))
+				for _, item := range config.Items {
+					selected[item.ID] = true
+				}
+				return saveAndRenderChecklist(b, u, config, selected)
+			}),
+		},
+		config.callbackPrefix + checklistCallbackClear: {
+			Handle: config.locks.locked(func(b *tgfsm.Bot, u tgbotapi.Update) error {
+				answerCallback(b, u, "")
+				return saveAndRenderChecklist(b, u, config, map[string]bool{})
+			}),
+		},
+		config.callbackPrefix + checklistCallbackDone: {
+			Handle: config.locks.locked(func(b *tgfsm.Bot, u tgbotapi.Update) error {
+				userID := u.SentFrom().ID
+				selected := getChecklistSelection(b, config, userID)
+
+				if len(selected) < config.MinSelected {
+					answerCallback(b, u, fmt.Sprintf(config.MinText, config.MinSelected))
+					return nil
+				}
+				answerCallback(b, u, "")
+
+				ids := orderedChecklistSelection(config, selected)
+				if err := config.OnChecklistDone(ids, userID); err != nil {
+					return err
+				}
+
+				b.GetCache().Delete(userKey(config.SelectedKey, userID))
+
+				// Оставляем сообщение с итоговым выбором без клавиатуры
+				if err := renderInline(b, u, checklistSummaryText(config, selected), nil); err != nil {
+					return err
+				}
+
+				return finishEvent(b, userID)
+			}),
+		},
+	}
+
+	for _, item := range config.Items {
+		id := item.ID
+		callbackHandlers[config.callbackPrefix+checklistCallbackToggle+id] = tgfsm.Handler{
+			Handle: config.locks.locked(func(b *tgfsm.Bot, u tgbotapi.Update) error {
+				selected := getChecklistSelection(b, config, u.SentFrom().ID)
+
+				if selected[id] {
+					delete(selected, id)
+				} else {
+					if config.MaxSelected > 0 && len(selected) >= config.MaxSelected {
+						answerCallback(b, u, fmt.Sprintf(config.MaxText, config.MaxSelected))
+						return nil
+					}
+					selected[id] = true
+				}
+				answerCallback(b, u, "")
+
+				return saveAndRenderChecklist(b, u, config, selected)
+			}),
+		}
+	}
+
+	name := eventName("Checklist", config.MessageTriggers, config.CallbackTriggers)
+
+	// Фаза выбора пунктов
+	var checklistPhase tgfsm.State = tgfsm.State{
+		Name:   name,
+		Global: false,
+		AtEntranceFunc: &tgfsm.Handler{Handle: config.locks.locked(func(b *tgfsm.Bot, u tgbotapi.Update) error {
+			selected := make(map[string]bool, len(config.InitialSelected))
+			for _, id := range config.InitialSelected {
+				selected[id] = true
+			}
+			b.GetCache().Set(userKey(config.SelectedKey, u.SentFrom().ID), selected, b.GetExpiration())
+
+			msg := tgbotapi.NewMessage(u.SentFrom().ID, config.PromptText)
+			msg.ReplyMarkup = buildChecklistKeyboard(config, selected)
+			_, err := b.SendMessage(msg)
+			return err
+		})},
+		CallbackHandlers: callbackHandlers,
+	}
+
+	return map[string]tgfsm.State{
+		checklistStateID:    checklistPhase,
+		uuid.New().String(): newEnterInState(name, checklistStateID, config.MessageTriggers, config.CallbackTriggers),
+	}, nil
+}
+
+// getChecklistSelection возвращает копию множества выбранных пользователем пунктов
+func getChecklistSelection(b *tgfsm.Bot, config *ChecklistConfig, userID int64) map[string]bool {
+	selected := make(map[string]bool)
+
+	cached, found := b.GetCache().Get(userKey(config.SelectedKey, userID))
+	if !found {
+		return selected
+	}
+
+	stored, ok := cached.(map[string]bool)
+	if !ok {
+		return selected
+	}
+
+	// Копируем, чтобы не менять значение в кеше до сохранения
+	for id := range stored {
+		selected[id] = true
+	}
+	return selected
+}
+
+// saveAndRenderChecklist сохраняет выбор пользователя и обновляет клавиатуру
+func saveAndRenderChecklist(b *tgfsm.Bot, u tgbotapi.Update, config *ChecklistConfig, selected map[string]bool) error {
+	b.GetCache().Set(userKey(config.SelectedKey, u.SentFrom().ID), selected, b.GetExpiration())
+	return renderInline(b, u, config.PromptText, buildChecklistKeyboard(config, selected))
+}
+
+// orderedChecklistSelection возвращает ID выбранных пунктов в порядке их объявления
+func orderedChecklistSelection(config *ChecklistConfig, selected map[string]bool) []string {
+	ids := make([]string, 0, len(selected))
+	for _, item := range config.Items {
+		if selected[item.ID] {
+			ids = append(ids, item.ID)
+		}
+	}
+	return ids
+}
+
+// checklistSummaryText формирует текст с итоговым выбором пользователя
+func checklistSummaryText(config *ChecklistConfig, selected map[string]bool) string {
+	text := config.PromptText + "\n"
+	for _, item := range config.Items {
+		if selected[item.ID] {
+			text += "\n" + config.CheckedMark + " " + item.Text
+		}
+	}
+	return text
+}
+
+// buildChecklistKeyboard создает клавиатуру чек-листа
+func buildChecklistKeyboard(config *ChecklistConfig, selected map[string]bool) *tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+
+	// Пункты чек-листа по config.Columns в строке
+	var row []tgbotapi.InlineKeyboardButton
+	for _, item := range config.Items {
+		mark := config.UncheckedMark
+		if selected[item.ID] {
+			mark = config.CheckedMark
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(mark+" "+item.Text, config.callbackPrefix+checklistCallbackToggle+item.ID))
+		if len(row) == config.Columns {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	// Кнопки "выбрать все" и "снять все"
+	var bulkButtons []tgbotapi.InlineKeyboardButton
+	canSelectAll := config.MaxSelected == 0 || config.MaxSelected >= len(config.Items)
+	if config.SelectAllText != "" && canSelectAll {
+		bulkButtons = append(bulkButtons, tgbotapi.NewInlineKeyboardButtonData(config.SelectAllText, config.callbackPrefix+checklistCallbackSelectAll))
+	}
+	if config.ClearText != "" {
+		bulkButtons = append(bulkButtons, tgbotapi.NewInlineKeyboardButtonData(config.ClearText, config.callbackPrefix+checklistCallbackClear))
+	}
+	if len(bulkButtons) > 0 {
+		rows = append(rows, bulkButtons)
+	}
+
+	doneText := config.DoneText
+	if len(selected) > 0 {
+		doneText = fmt.Sprintf("%s (%d)", config.DoneText, len(selected))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(doneText, config.callbackPrefix+checklistCallbackDone),
+	))
+
+	return &tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: rows,
+	}
+}
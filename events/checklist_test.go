@@ -0,0 +1,187 @@
+package events_test
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"tgfsm"
+	"tgfsm/events"
+	"tgfsm/tgfsmtest"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// newDrinksChecklist creates a checklist of drinks on /drinks that records the selection
+func newDrinksChecklist(t *testing.T, done *[]string, opts ...events.ChecklistOption) map[string]tgfsm.State {
+	t.Helper()
+
+	opts = append([]events.ChecklistOption{
+		events.WithChecklistMessageTriggers("/drinks"),
+		events.WithChecklistItems(
+			events.ChecklistItem{ID: "tea", Text: "Tea"},
+			events.ChecklistItem{ID: "coffee", Text: "Coffee"},
+			events.ChecklistItem{ID: "juice", Text: "Juice"},
+		),
+		events.WithOnChecklistDone(func(selected []string, userId int64) error {
+			*done = selected
+			return nil
+		}),
+	}, opts...)
+	return mustEvent(t)(events.NewChecklistEvent(opts...))
+}
+
+func TestChecklist(t *testing.T) {
+	var done []string
+	server, bot := newTestBot(t, newDrinksChecklist(t, &done))
+
+	tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/drinks").
+		ExpectMessage(events.ChecklistPromptText).
+		ExpectKeyboard("⬜ Tea", "⬜ Coffee", "⬜ Juice", "Select all", "Clear", "Done").
+		Press("⬜ Juice").
+		ExpectAnswer("").
+		ExpectKeyboard("⬜ Tea", "⬜ Coffee", "✅ Juice", "Select all", "Clear", "Done (1)").
+		Press("⬜ Tea").
+		ExpectKeyboard("✅ Tea", "⬜ Coffee", "✅ Juice", "Select all", "Clear", "Done (2)").
+		Press("Done (2)").
+		ExpectEdit(events.ChecklistPromptText + "\n\n✅ Tea\n✅ Juice").
+		ExpectState("home")
+
+	if !slices.Equal(done, []string{"tea", "juice"}) {
+		t.Fatalf("selected %q, want tea and juice in order of declaration", done)
+	}
+}
+
+func TestChecklistSelectAllAndClear(t *testing.T) {
+	var done []string
+	server, bot := newTestBot(t, newDrinksChecklist(t, &done))
+
+	tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/drinks").
+		Press("Select all").
+		ExpectKeyboard("✅ Tea", "✅ Coffee", "✅ Juice", "Select all", "Clear", "Done (3)").
+		Press("Clear").
+		ExpectKeyboard("⬜ Tea", "⬜ Coffee", "⬜ Juice", "Select all", "Clear", "Done").
+		Press("Done").
+		ExpectState("home")
+
+	if len(done) != 0 {
+		t.Fatalf("selected %q, want nothing", done)
+	}
+}
+
+func TestChecklistBounds(t *testing.T) {
+	var done []string
+	server, bot := newTestBot(t, newDrinksChecklist(t, &done, events.WithChecklistBounds(1, 2)))
+
+	s := tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/drinks").
+		ExpectKeyboard("⬜ Tea", "⬜ Coffee", "⬜ Juice", "Clear", "Done").
+		Press("Done").
+		ExpectAnswer("Select at least 1.").
+		ExpectStateName("Checklist (/drinks)").
+		Press("⬜ Tea").
+		Press("⬜ Coffee").
+		Press("⬜ Juice").
+		ExpectAnswer("You can select at most 2.").
+		ExpectNoMessages()
+	s.Press("Done (2)").ExpectState("home")
+
+	if !slices.Equal(done, []string{"tea", "coffee"}) {
+		t.Fatalf("selected %q, want tea and coffee", done)
+	}
+}
+
+func TestChecklistSelectAllRespectsMax(t *testing.T) {
+	var done []string
+	server, bot := newTestBot(t, newDrinksChecklist(t, &done, events.WithChecklistBounds(0, 2)))
+
+	s := tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/drinks").
+		ExpectMessage(events.ChecklistPromptText)
+
+	// The button is hidden, a stale message or a forged callback still sends its data
+	clear := callbackData(t, s.Last(), "Clear")
+	s.PressData(strings.TrimSuffix(clear, "clear") + "select_all").
+		ExpectAnswer("You can select at most 2.").
+		ExpectNoMessages()
+	s.Press("Done").ExpectState("home")
+
+	if len(done) != 0 {
+		t.Fatalf("selected %q, want nothing", done)
+	}
+}
+
+func TestChecklistConcurrentToggles(t *testing.T) {
+	var done []string
+	server, bot := newTestBot(t, newDrinksChecklist(t, &done))
+
+	s := tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/drinks").
+		ExpectMessage(events.ChecklistPromptText)
+	message := s.Last()
+
+	// Fast presses are processed concurrently; none of them may be lost
+	var wg sync.WaitGroup
+	for _, item := range []string{"⬜ Tea", "⬜ Coffee", "⬜ Juice"} {
+		data := callbackData(t, message, item)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bot.ProcessUpdate(tgfsmtest.NewCallbackUpdate(testUserID, data, message.MessageID))
+		}()
+	}
+	wg.Wait()
+
+	s.Press("Done (3)").ExpectState("home")
+	if !slices.Equal(done, []string{"tea", "coffee", "juice"}) {
+		t.Fatalf("selected %q, want all drinks", done)
+	}
+}
+
+func TestChecklistCallbackNamespace(t *testing.T) {
+	var done []string
+	var hostDone int
+	host := map[string]tgfsm.State{
+		"host": {
+			Global: true,
+			CallbackHandlers: map[string]tgfsm.Handler{
+				"done": {Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+					hostDone++
+					return nil
+				}},
+			},
+		},
+	}
+	server, bot := newTestBot(t, host, newDrinksChecklist(t, &done))
+
+	s := tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/drinks").
+		ExpectMessage(events.ChecklistPromptText)
+	if data := callbackData(t, s.Last(), "Done"); !strings.HasPrefix(data, "cl:") {
+		t.Fatalf("done button data %q, want the checklist prefix", data)
+	}
+	s.Press("⬜ Tea").
+		Press("Done (1)").
+		ExpectState("home")
+
+	if hostDone != 0 || !slices.Equal(done, []string{"tea"}) {
+		t.Fatalf("host handled %d callbacks, selected %q; want the checklist to handle its buttons", hostDone, done)
+	}
+}
+
+// callbackData returns the callback data of the inline button with the text
+func callbackData(t *testing.T, message *tgfsmtest.Request, text string) string {
+	t.Helper()
+
+	for _, row := range message.InlineKeyboard() {
+		for _, button := range row {
+			if button.Text == text && button.CallbackData != nil {
+				return *button.CallbackData
+			}
+		}
+	}
+	t.Fatalf("no button %q in %q", text, message.Buttons())
+	return ""
+}
@@ -0,0 +1,361 @@
+package events
+
+import (
+	"errors"
+	"sync"
+	"tgfsm"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/google/uuid"
+)
+
+// Значения по умолчанию для конфигурации диалога подтверждения
+const (
+	ConfirmYesText         = "Yes"
+	ConfirmNoText          = "No"
+	ConfirmYesResolvedText = "✅ Confirmed"
+	ConfirmNoResolvedText  = "❌ Cancelled"
+	ConfirmTimeoutText     = "⌛ Time is up"
+	ConfirmTimeout         = time.Minute
+)
+
+// Callback данные кнопок диалога подтверждения
+const (
+	confirmCallbackYes = "confirm_yes"
+	confirmCallbackNo  = "confirm_no"
+)
+
+var (
+	ErrConfirmQuestionRequired = errors.New("confirm question is required")
+	ErrConfirmButtonsRequired  = errors.New("confirm buttons text is required")
+	ErrNegativeConfirmTimeout  = errors.New("confirm timeout cannot be negative")
+)
+
+// ===== Опции для ConfirmConfig =====
+
+// ConfirmOption опция для конфигурации диалога подтверждения
+type ConfirmOption func(*ConfirmConfig)
+
+// ConfirmConfig конфигурация диалога подтверждения
+type ConfirmConfig struct {
+	YesText         string
+	NoText          string
+	YesResolvedText string
+	NoResolvedText  string
+	TimeoutText     string
+	Timeout         time.Duration
+	OnYes           func(b *tgfsm.Bot, userId int64) error
+	OnNo            func(b *tgfsm.Bot, userId int64) error
+	OnTimeout       func(b *tgfsm.Bot, userId int64) error
+}
+
+// WithConfirmButtonsText устанавливает тексты кнопок согласия и отказа.
+// Если не установить, используются значения по умолчанию:
+//
+//	`ConfirmYesText = "Yes"`
+//	`ConfirmNoText = "No"`
+func WithConfirmButtonsText(yes, no string) ConfirmOption {
+	return func(config *ConfirmConfig) {
+		config.YesText = yes
+		config.NoText = no
+	}
+}
+
+// WithConfirmResolvedText устанавливает тексты, добавляемые к вопросу после ответа или истечения времени.
+// Если установить пустое значение, вопрос остается без изменений, удаляется только клавиатура.
+//
+// Если не установить, используются значения по умолчанию:
+//
+//	`ConfirmYesResolvedText = "✅ Confirmed"`
+//	`ConfirmNoResolvedText = "❌ Cancelled"`
+//	`ConfirmTimeoutText = "⌛ Time is up"`
+func WithConfirmResolvedText(yes, no, timeout string) ConfirmOption {
+	return func(config *ConfirmConfig) {
+		config.YesResolvedText = yes
+		config.NoResolvedText = no
+		config.TimeoutText = timeout
+	}
+}
+
+// WithConfirmTimeout устанавливает время ожидания ответа.
+// Значение 0 отключает ожидание: диалог завершится только ответом пользователя.
+// Если не установить, используется значение по умолчанию:
+//
+//	`ConfirmTimeout = time.Minute`
+func WithConfirmTimeout(timeout time.Duration) ConfirmOption {
+	return func(config *ConfirmConfig) {
+		config.Timeout = timeout
+	}
+}
+
+// WithOnConfirmYes устанавливает функцию, выполняемую при согласии пользователя.
+func WithOnConfirmYes(action func(b *tgfsm.Bot, userId int64) error) ConfirmOption {
+	return func(config *ConfirmConfig) {
+		config.OnYes = action
+	}
+}
+
+// WithOnConfirmNo устанавливает функцию, выполняемую при отказе пользователя.
+func WithOnConfirmNo(action func(b *tgfsm.Bot, userId int64) error) ConfirmOption {
+	return func(config *ConfirmConfig) {
+		config.OnNo = action
+	}
+}
+
+// WithOnConfirmTimeout устанавливает функцию, выполняемую, если пользователь не ответил вовремя.
+func WithOnConfirmTimeout(action func(b *tgfsm.Bot, userId int64) error) ConfirmOption {
+	return func(config *ConfirmConfig) {
+		config.OnTimeout = action
+	}
+}
+
+// ConfirmDialog диалог подтверждения "Да/Нет", который можно вызвать из любого обработчика.
+//
+// Состояния диалога нужно зарегистрировать в боте вместе с остальными состояниями (см. States).
+// После ответа или истечения времени клавиатура удаляется, а пользователь возвращается
+// в состояние, в котором находился до вызова Ask.
+type ConfirmDialog struct {
+	config  *ConfirmConfig
+	stateID string
+	pending sync.Map // userID -> *confirmPending
+
+	// callbackPrefix отличает callback данные диалога от callback данных бота и других событий
+	callbackPrefix string
+}
+
+// confirmPending ожидающий ответа вопрос пользователя
+type confirmPending struct {
+	token    string
+	question string
+	chatID   int64
+	timer    *time.Timer
+
+	// Сообщение с вопросом известно только после отправки, а ответ может прийти раньше.
+	// Клавиатуру убирает тот, кто узнает о сообщении и ответе последним.
+	mu        sync.Mutex
+	messageID int
+	resolved  bool
+	text      string // Текст сообщения после ответа
+}
+
+// sent запоминает отправленное сообщение с вопросом.
+// Если вопрос уже решен, возвращает текст, которым нужно заменить сообщение.
+func (p *confirmPending) sent(messageID int) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.messageID = messageID
+	return p.text, p.resolved
+}
+
+// resolve отмечает вопрос решенным с текстом сообщения.
+// Возвращает сообщение с вопросом, 0 если оно еще не отправлено.
+func (p *confirmPending) resolve(text string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.resolved = true
+	p.text = text
+	return p.messageID
+}
+
+// NewConfirmDialog создает диалог подтверждения с использованием опций.
+//
+// Пример:
+//
+//	dialog, _ := events.NewConfirmDialog(
+//		events.WithOnConfirmYes(deleteAccount),
+//	)
+//	// в обработчике:
+//	return dialog.Ask(b, u, "Delete your account?")
+func NewConfirmDialog(opts ...ConfirmOption) (*ConfirmDialog, error) {
+	config := &ConfirmConfig{
+		YesText:         ConfirmYesText,
+		NoText:          ConfirmNoText,
+		YesResolvedText: ConfirmYesResolvedText,
+		NoResolvedText:  ConfirmNoResolvedText,
+		TimeoutText:     ConfirmTimeoutText,
+		Timeout:         ConfirmTimeout,
+	}
+
+	// Применяем все опции
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	// Валидация обязательных полей
+	if config.YesText == "" || config.NoText == "" {
+		return nil, ErrConfirmButtonsRequired
+	}
+
+	if config.Timeout < 0 {
+		return nil, ErrNegativeConfirmTimeout
+	}
+
+	return &ConfirmDialog{
+		config:         config,
+		stateID:        uuid.New().String(),
+		callbackPrefix: newCallbackPrefix("cf"),
+	}, nil
+}
+
+// States возвращает состояния диалога для регистрации в боте
+func (d *ConfirmDialog) States() map[string]tgfsm.State {
+	return map[string]tgfsm.State{
+		d.stateID: {
+			Name:   "ConfirmDialog",
+			Global: false,
+			// Пустая карта нужна, чтобы текстовые сообщения доходили до CatchAllFunc
+			MessageHandlers: map[string]tgfsm.Handler{},
+			// Любой ввод, кроме кнопок диалога, считается отказом
+			CatchAllFunc: &tgfsm.Handler{Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+				answerCallback(b, u, "")
+				return d.resolve(b, u.SentFrom().ID, "", d.config.NoResolvedText, d.config.OnNo)
+			}},
+			CallbackHandlers: map[string]tgfsm.Handler{
+				d.callbackPrefix + confirmCallbackYes: {
+					Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+						answerCallback(b, u, "")
+						return d.resolve(b, u.SentFrom().ID, "", d.config.YesResolvedText, d.config.OnYes)
+					},
+				},
+				d.callbackPrefix + confirmCallbackNo: {
+					Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+						answerCallback(b, u, "")
+						return d.resolve(b, u.SentFrom().ID, "", d.config.NoResolvedText, d.config.OnNo)
+					},
+				},
+			},
+		},
+	}
+}
+
+// StateID возвращает имя состояния диалога.
+// Если состояния бота объявляют Transitions, добавьте его в Transitions состояний,
+// из обработчиков которых вызывается Ask.
+func (d *ConfirmDialog) StateID() string {
+	return d.stateID
+}
+
+// Ask задает пользователю вопрос и переводит его в состояние диалога.
+// Предыдущий вопрос этого диалога, если он еще не решен, заменяется новым без вызова функций.
+func (d *ConfirmDialog) Ask(b *tgfsm.Bot, u tgbotapi.Update, question string) error {
+	if question == "" {
+		return ErrConfirmQuestionRequired
+	}
+
+	userID := u.SentFrom().ID
+
+	if value, ok := d.pending.LoadAndDelete(userID); ok {
+		previous := value.(*confirmPending)
+		if previous.timer != nil {
+			previous.timer.Stop()
+		}
+
+		// Убираем клавиатуру у старого вопроса, чтобы его кнопки не решили новый
+		if messageID := previous.resolve(previous.question); messageID != 0 {
+			editMsg := tgbotapi.NewEditMessageText(previous.chatID, messageID, previous.question)
+			_, _ = b.EditMessage(editMsg)
+		}
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(d.config.YesText, d.callbackPrefix+confirmCallbackYes),
+			tgbotapi.NewInlineKeyboardButtonData(d.config.NoText, d.callbackPrefix+confirmCallbackNo),
+		),
+	)
+	msg := tgbotapi.NewMessage(userID, question)
+	msg.ReplyMarkup = keyboard
+
+	// Текущее состояние запоминается, чтобы вернуть в него пользователя после ответа.
+	// Если пользователь уже в диалоге, история не меняется и новый вопрос вернет его туда же.
+	if err := b.PushUserState(userID, d.stateID); err != nil {
+		return err
+	}
+
+	// Вопрос сохраняется до отправки клавиатуры: нажатие кнопки может прийти раньше,
+	// чем SendMessage вернет управление
+	pending := &confirmPending{
+		token:    uuid.New().String(),
+		question: question,
+		chatID:   userID,
+	}
+	if d.config.Timeout > 0 {
+		token := pending.token
+		pending.timer = time.AfterFunc(d.config.Timeout, func() {
+			_ = d.resolve(b, userID, token, d.config.TimeoutText, d.config.OnTimeout)
+		})
+	}
+	d.pending.Store(userID, pending)
+
+	sentMsg, err := b.SendMessage(msg)
+	if err != nil {
+		// Вопрос не задан: забываем его и возвращаем пользователя, если он еще в диалоге
+		if d.pending.CompareAndDelete(userID, pending) {
+			if pending.timer != nil {
+				pending.timer.Stop()
+			}
+			if current, stateErr := b.GetUserState(userID); stateErr == nil && current == d.stateID {
+				_ = finishEvent(b, userID)
+			}
+		}
+		return err
+	}
+	// Ответ пришел раньше, чем SendMessage вернул сообщение: клавиатуру убираем здесь
+	if text, resolved := pending.sent(sentMsg.MessageID); resolved {
+		editMsg := tgbotapi.NewEditMessageText(pending.chatID, sentMsg.MessageID, text)
+		_, _ = b.EditMessage(editMsg)
+	}
+
+	return nil
+}
+
+// resolve завершает вопрос пользователя: удаляет клавиатуру, вызывает action
+// и возвращает пользователя в предыдущее состояние.
+// Если token не пустой, вопрос завершается только при совпадении токена,
+// чтобы таймер старого вопроса не закрыл новый.
+func (d *ConfirmDialog) resolve(b *tgfsm.Bot, userID int64, token, resolvedText string, action func(b *tgfsm.Bot, userId int64) error) error {
+	value, ok := d.pending.Load(userID)
+	if !ok {
+		return nil
+	}
+	pending := value.(*confirmPending)
+	if token != "" && pending.token != token {
+		return nil
+	}
+	// Ответ и таймер могут сработать одновременно, выполняется только первый
+	if !d.pending.CompareAndDelete(userID, pending) {
+		return nil
+	}
+	if pending.timer != nil {
+		pending.timer.Stop()
+	}
+
+	// Удаляем клавиатуру, оставляя вопрос и итог в тексте
+	text := pending.question
+	if resolvedText != "" {
+		text += "\n\n" + resolvedText
+	}
+	var editErr error
+	if messageID := pending.resolve(text); messageID != 0 {
+		editMsg := tgbotapi.NewEditMessageText(pending.chatID, messageID, text)
+		_, editErr = b.EditMessage(editMsg)
+	}
+
+	// Возвращаем пользователя до вызова action, чтобы action мог сам перевести его в другое состояние.
+	// Если пользователь уже покинул диалог (например, по глобальному триггеру), его состояние не трогаем.
+	var stateErr error
+	if current, err := b.GetUserState(userID); err == nil && current == d.stateID {
+		stateErr = finishEvent(b, userID)
+	}
+
+	if action != nil {
+		if err := action(b, userID); err != nil {
+			return err
+		}
+	}
+
+	return errors.Join(editErr, stateErr)
+}
@@ -0,0 +1,129 @@
+package events_test
+
+import (
+	"testing"
+	"tgfsm"
+	"tgfsm/events"
+	"tgfsm/tgfsmtest"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// confirmHome returns the "home" state asking the question of the dialog on /delete
+func confirmHome(dialog *events.ConfirmDialog) map[string]tgfsm.State {
+	return map[string]tgfsm.State{
+		"home": {
+			Transitions: []string{dialog.StateID()},
+			MessageHandlers: map[string]tgfsm.Handler{
+				"/delete": {Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+					return dialog.Ask(b, u, "Delete?")
+				}},
+			},
+		},
+	}
+}
+
+// confirmResults records the answers of the dialog
+type confirmResults struct {
+	yes, no int
+}
+
+func newConfirmDialog(t *testing.T, results *confirmResults) *events.ConfirmDialog {
+	t.Helper()
+
+	dialog, err := events.NewConfirmDialog(
+		events.WithOnConfirmYes(func(b *tgfsm.Bot, userID int64) error {
+			results.yes++
+			return nil
+		}),
+		events.WithOnConfirmNo(func(b *tgfsm.Bot, userID int64) error {
+			results.no++
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create dialog: %v", err)
+	}
+	return dialog
+}
+
+func TestConfirmDialogYes(t *testing.T) {
+	var results confirmResults
+	dialog := newConfirmDialog(t, &results)
+	server, bot := newTestBot(t, confirmHome(dialog), dialog.States())
+
+	tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/delete").
+		ExpectMessage("Delete?").
+		ExpectKeyboard(events.ConfirmYesText, events.ConfirmNoText).
+		ExpectState(dialog.StateID()).
+		Press(events.ConfirmYesText).
+		ExpectEdit("Delete?\n\n" + events.ConfirmYesResolvedText).
+		ExpectState("home")
+
+	if results != (confirmResults{yes: 1}) {
+		t.Fatalf("results %+v, want one yes", results)
+	}
+}
+
+func TestConfirmDialogTextIsNo(t *testing.T) {
+	var results confirmResults
+	dialog := newConfirmDialog(t, &results)
+	server, bot := newTestBot(t, confirmHome(dialog), dialog.States())
+
+	tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/delete").
+		ExpectKeyboard(events.ConfirmYesText).
+		Send("whatever").
+		ExpectEdit("Delete?\n\n" + events.ConfirmNoResolvedText).
+		ExpectState("home")
+
+	if results != (confirmResults{no: 1}) {
+		t.Fatalf("results %+v, want one no", results)
+	}
+}
+
+// pressingClient presses the first button of the first message with an inline keyboard
+// before Send returns, as a fast user could
+type pressingClient struct {
+	tgfsm.Client
+	bot     *tgfsm.Bot
+	pressed bool
+}
+
+func (c *pressingClient) Send(chattable tgbotapi.Chattable) (tgbotapi.Message, error) {
+	message, err := c.Client.Send(chattable)
+	config, ok := chattable.(tgbotapi.MessageConfig)
+	if err != nil || c.pressed || !ok {
+		return message, err
+	}
+	keyboard, ok := config.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+	if !ok {
+		return message, err
+	}
+
+	c.pressed = true
+	c.bot.ProcessUpdate(tgfsmtest.NewCallbackUpdate(testUserID, *keyboard.InlineKeyboard[0][0].CallbackData, message.MessageID))
+	return message, err
+}
+
+func TestConfirmDialogAnswerBeforeSent(t *testing.T) {
+	var results confirmResults
+	dialog := newConfirmDialog(t, &results)
+	server, bot := newTestBot(t, confirmHome(dialog), dialog.States())
+
+	client := &pressingClient{Client: bot.Client(), bot: bot}
+	if err := bot.UpdateBot(tgfsm.WithClient(client)); err != nil {
+		t.Fatalf("failed to set client: %v", err)
+	}
+
+	tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/delete").
+		ExpectMessage("Delete?").
+		ExpectEdit("Delete?\n\n" + events.ConfirmYesResolvedText).
+		ExpectState("home")
+
+	if !client.pressed || results != (confirmResults{yes: 1}) {
+		t.Fatalf("pressed %v, results %+v, want one yes", client.pressed, results)
+	}
+}
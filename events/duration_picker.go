@@ -0,0 +1,440 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"tgfsm"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/google/uuid"
+)
+
+// Значения по умолчанию для конфигурации выбора длительности
+const (
+	DurationPickerPromptText  = "Select duration:"
+	DurationPickerDoneText    = "✅ Done"
+	DurationPickerSuccessText = "Selected duration: %s"
+	DurationPickerInvalidText = "Invalid duration. Use the buttons or send it as 2h 30m."
+	DurationPickerRangeText   = "Duration must be between %s and %s."
+	DurationPickerMax         = 24 * time.Hour
+)
+
+// Callback данные кнопок выбора длительности
+const (
+	durationPickerCallbackNoop = "noop"
+	durationPickerCallbackDone = "done"
+	durationPickerCallbackInc  = "inc:"
+	durationPickerCallbackDec  = "dec:"
+)
+
+var (
+	// DurationPickerSteps шаги изменения длительности по умолчанию
+	DurationPickerSteps = []time.Duration{time.Hour, 15 * time.Minute}
+
+	ErrDurationSelectedActionRequired = errors.New("duration selected action is required")
+	ErrInvalidDurationPickerStep      = errors.New("duration picker steps must be positive")
+	ErrInvalidDurationPickerRange     = errors.New("duration picker range is invalid")
+	ErrInvalidDuration                = errors.New("invalid duration")
+)
+
+// ===== Опции для DurationPickerConfig =====
+
+// DurationPickerOption опция для конфигурации выбора длительности
+type DurationPickerOption func(*DurationPickerConfig)
+
+// DurationPickerConfig конфигурация для выбора длительности
+type DurationPickerConfig struct {
+	MessageTriggers    []string
+	CallbackTriggers   []string
+	PromptText         string
+	DoneText           string
+	SuccessText        string
+	InvalidText        string
+	RangeText          string
+	Steps              []time.Duration
+	Min                time.Duration
+	Max                time.Duration
+	Initial            time.Duration
+	OnDurationSelected func(duration time.Duration, userId int64) error
+	ValueKey           string
+
+	// callbackPrefix отличает callback данные события от callback данных бота и других событий
+	callbackPrefix string
+}
+
+// WithDurationPickerMessageTriggers устанавливает глобальные триггеры для входа в режим выбора длительности.
+// При указании пользователь сможет войти в состояние выбора длительности, отправив триггер.
+func WithDurationPickerMessageTriggers(triggers ...string) DurationPickerOption {
+	return func(config *DurationPickerConfig) {
+		config.MessageTriggers = triggers
+	}
+}
+
+// WithDurationPickerCallbackTriggers устанавливает глобальные триггеры для входа в режим выбора длительности.
+// При указании пользователь сможет войти в состояние выбора длительности, отправив callback.
+func WithDurationPickerCallbackTriggers(triggers ...string) DurationPickerOption {
+	return func(config *DurationPickerConfig) {
+		config.CallbackTriggers = triggers
+	}
+}
+
+// WithDurationPickerPromptText устанавливает текст над клавиатурой выбора длительности.
+// Если не установить, используется значение по умолчанию:
+//
+//	`DurationPickerPromptText = "Select duration:"`
+func WithDurationPickerPromptText(text string) DurationPickerOption {
+	return func(config *DurationPickerConfig) {
+		config.PromptText = text
+	}
+}
+
+// WithDurationPickerDoneText устанавливает текст кнопки подтверждения.
+// Если не установить, используется значение по умолчанию:
+//
+//	`DurationPickerDoneText = "✅ Done"`
+func WithDurationPickerDoneText(text string) DurationPickerOption {
+	return func(config *DurationPickerConfig) {
+		config.DoneText = text
+	}
+}
+
+// WithDurationPickerSuccessText устанавливает текст, которым заменяется сообщение после подтверждения.
+// Использует форматирование: %s будет заменен на выбранную длительность.
+// Если установить пустое значение, сообщение будет удалено без замены текста.
+//
+// Если не установить, используется значение по умолчанию:
+//
+//	`DurationPickerSuccessText = "Selected duration: %s"`
+func WithDurationPickerSuccessText(text string) DurationPickerOption {
+	return func(config *DurationPickerConfig) {
+		config.SuccessText = text
+	}
+}
+
+// WithDurationPickerInvalidText устанавливает текст сообщения о некорректно введенной длительности.
+// Если не установить, используется значение по умолчанию:
+//
+//	`DurationPickerInvalidText = "Invalid duration. Use the buttons or send it as 2h 30m."`
+func WithDurationPickerInvalidText(text string) DurationPickerOption {
+	return func(config *DurationPickerConfig) {
+		config.InvalidText = text
+	}
+}
+
+// WithDurationPickerRangeText устанавливает текст сообщения о длительности вне допустимого диапазона.
+// Использует форматирование: первый %s будет заменен на минимум, второй - на максимум.
+// Если не установить, используется значение по умолчанию:
+//
+//	`DurationPickerRangeText = "Duration must be between %s and %s."`
+func WithDurationPickerRangeText(text string) DurationPickerOption {
+	return func(config *DurationPickerConfig) {
+		config.RangeText = text
+	}
+}
+
+// WithDurationPickerSteps устанавливает шаги изменения длительности.
+// Для каждого шага создается строка кнопок "−шаг" и "+шаг".
+// Если не установить, используются шаги 1h и 15m.
+func WithDurationPickerSteps(steps ...time.Duration) DurationPickerOption {
+	return func(config *DurationPickerConfig) {
+		config.Steps = steps
+	}
+}
+
+// WithDurationPickerRange устанавливает минимальную и максимальную длительность.
+// Если не установить, используется диапазон от 0 до 24h.
+func WithDurationPickerRange(min, max time.Duration) DurationPickerOption {
+	return func(config *DurationPickerConfig) {
+		config.Min = min
+		config.Max = max
+	}
+}
+
+// WithDurationPickerInitial устанавливает длительность, которая показывается при входе в событие.
+// Если не установить, используется минимальная длительность.
+func WithDurationPickerInitial(initial time.Duration) DurationPickerOption {
+	return func(config *DurationPickerConfig) {
+		config.Initial = initial
+	}
+}
+
+// WithOnDurationSelected устанавливает функцию, которая получит выбранную длительность.
+// Обязательный параметр.
+func WithOnDurationSelected(action func(duration time.Duration, userId int64) error) DurationPickerOption {
+	return func(config *DurationPickerConfig) {
+		config.OnDurationSelected = action
+	}
+}
+
+// NewDurationPickerEvent создает цепочку состояний для выбора длительности с использованием опций.
+//
+// Пользователь меняет длительность inline кнопками, сообщение редактируется на месте.
+// Длительность также можно отправить текстом, например "2h 30m" или "45m".
+func NewDurationPickerEvent(opts ...DurationPickerOption) (map[string]tgfsm.State, error) {
+	config := &DurationPickerConfig{
+		PromptText:     DurationPickerPromptText,
+		DoneText:       DurationPickerDoneText,
+		SuccessText:    DurationPickerSuccessText,
+		InvalidText:    DurationPickerInvalidText,
+		RangeText:      DurationPickerRangeText,
+		Steps:          DurationPickerSteps,
+		Max:            DurationPickerMax,
+		Initial:        -1,
+		ValueKey:       uuid.New().String(),
+		callbackPrefix: newCallbackPrefix("dp"),
+	}
+
+	// Применяем все опции
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	// Валидация обязательных полей
+	if len(config.MessageTriggers) == 0 && len(config.CallbackTriggers) == 0 {
+		return nil, tgfsm.ErrEmptyTriggers
+	}
+
+	if config.OnDurationSelected == nil {
+		return nil, ErrDurationSelectedActionRequired
+	}
+
+	if len(config.Steps) == 0 {
+		return nil, ErrInvalidDurationPickerStep
+	}
+	for _, step := range config.Steps {
+		if step <= 0 {
+			return nil, ErrInvalidDurationPickerStep
+		}
+	}
+
+	if config.Min < 0 || config.Max < config.Min {
+		return nil, ErrInvalidDurationPickerRange
+	}
+
+	if config.Initial < 0 {
+		config.Initial = config.Min
+	}
+	if config.Initial < config.Min || config.Initial > config.Max {
+		return nil, ErrInvalidDurationPickerRange
+	}
+
+	return buildDurationPickerStates(config)
+}
+
+// buildDurationPickerStates создает состояния для выбора длительности
+func buildDurationPickerStates(config *DurationPickerConfig) (map[string]tgfsm.State, error) {
+	var pickerStateID = uuid.New().String()
+
+	// shift меняет выбранную длительность на delta в пределах диапазона и перерисовывает клавиатуру
+	shift := func(delta time.Duration) tgfsm.Handler {
+		return tgfsm.Handler{Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+			answerCallback(b, u, "")
+
+			value := getDurationPickerValue(b, config, u.SentFrom().ID) + delta
+			if value < config.Min {
+				value = config.Min
+			}
+			if value > config.Max {
+				value = config.Max
+			}
+			b.GetCache().Set(userKey(config.ValueKey, u.SentFrom().ID), value, b.GetExpiration())
+
+			return renderDurationPicker(b, u, config, value)
+		}}
+	}
+
+	callbackHandlers := map[string]tgfsm.Handler{
+		config.callbackPrefix + durationPickerCallbackNoop: {
+			Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+				answerCallback(b, u, "")
+				return nil
+			},
+		},
+		config.callbackPrefix + durationPickerCallbackDone: {
+			Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+				answerCallback(b, u, "")
+
+				userID := u.SentFrom().ID
+				value := getDurationPickerValue(b, config, userID)
+
+				if err := config.OnDurationSelected(value, userID); err != nil {
+					return err
+				}
+
+				b.GetCache().Delete(userKey(config.ValueKey, userID))
+
+				// Убираем клавиатуру, оставляя выбранное значение в тексте
+				if config.SuccessText != "" {
+					text := fmt.Sprintf(config.SuccessText, formatDuration(value))
+					if err := renderInline(b, u, text, nil); err != nil {
+						return err
+					}
+				} else if u.CallbackQuery != nil && u.CallbackQuery.Message != nil {
+					deleteMsg := tgbotapi.NewDeleteMessage(u.CallbackQuery.Message.Chat.ID, u.CallbackQuery.Message.MessageID)
+					_ = b.DeleteMessage(deleteMsg)
+				}
+
+				return finishEvent(b, userID)
+			},
+		},
+	}
+	for i, step := range config.Steps {
+		callbackHandlers[config.callbackPrefix+durationPickerCallbackInc+strconv.Itoa(i)] = shift(step)
+		callbackHandlers[config.callbackPrefix+durationPickerCallbackDec+strconv.Itoa(i)] = shift(-step)
+	}
+
+	name := eventName("DurationPicker", config.MessageTriggers, config.CallbackTriggers)
+
+	// Фаза выбора длительности
+	var pickerPhase tgfsm.State = tgfsm.State{
+		Name:   name,
+		Global: false,
+		AtEntranceFunc: &tgfsm.Handler{Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+			b.GetCache().Set(userKey(config.ValueKey, u.SentFrom().ID), config.Initial, b.GetExpiration())
+
+			msg := tgbotapi.NewMessage(u.SentFrom().ID, durationPickerText(config, config.Initial))
+			msg.ReplyMarkup = buildDurationPickerKeyboard(config, config.Initial)
+			_, err := b.SendMessage(msg)
+			return err
+		}},
+		// Пустая карта нужна, чтобы текстовые сообщения доходили до CatchAllFunc
+		MessageHandlers: map[string]tgfsm.Handler{},
+		CatchAllFunc: &tgfsm.Handler{Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+			// Длительность можно ввести текстом вместо кнопок
+			if u.Message == nil || u.Message.Text == "" {
+				return nil
+			}
+
+			value, err := parseDuration(u.Message.Text)
+			if err != nil {
+				msg := tgbotapi.NewMessage(u.SentFrom().ID, config.InvalidText)
+				_, sendErr := b.SendMessage(msg)
+				return sendErr
+			}
+
+			if value < config.Min || value > config.Max {
+				msgText := fmt.Sprintf(config.RangeText, formatDuration(config.Min), formatDuration(config.Max))
+				msg := tgbotapi.NewMessage(u.SentFrom().ID, msgText)
+				_, sendErr := b.SendMessage(msg)
+				return sendErr
+			}
+
+			b.GetCache().Set(userKey(config.ValueKey, u.SentFrom().ID), value, b.GetExpiration())
+			return renderDurationPicker(b, u, config, value)
+		}},
+		CallbackHandlers: callbackHandlers,
+	}
+
+	return map[string]tgfsm.State{
+		pickerStateID:       pickerPhase,
+		uuid.New().String(): newEnterInState(name, pickerStateID, config.MessageTriggers, config.CallbackTriggers),
+	}, nil
+}
+
+// getDurationPickerValue возвращает выбранную пользователем длительность.
+// Если значение не найдено в кеше, возвращается начальная длительность.
+func getDurationPickerValue(b *tgfsm.Bot, config *DurationPickerConfig, userID int64) time.Duration {
+	cached, found := b.GetCache().Get(userKey(config.ValueKey, userID))
+	if !found {
+		return config.Initial
+	}
+
+	value, ok := cached.(time.Duration)
+	if !ok {
+		return config.Initial
+	}
+
+	return value
+}
+
+// renderDurationPicker обновляет сообщение с выбором длительности
+func renderDurationPicker(b *tgfsm.Bot, u tgbotapi.Update, config *DurationPickerConfig, value time.Duration) error {
+	return renderInline(b, u, durationPickerText(config, value), buildDurationPickerKeyboard(config, value))
+}
+
+// durationPickerText формирует текст сообщения с текущей выбранной длительностью
+func durationPickerText(config *DurationPickerConfig, value time.Duration) string {
+	return config.PromptText + "\n\n⏱ " + formatDuration(value)
+}
+
+// buildDurationPickerKeyboard создает клавиатуру для выбора длительности
+func buildDurationPickerKeyboard(config *DurationPickerConfig, value time.Duration) *tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+
+	for i, step := range config.Steps {
+		label := formatDuration(step)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("−"+label, config.callbackPrefix+durationPickerCallbackDec+strconv.Itoa(i)),
+			tgbotapi.NewInlineKeyboardButtonData("+"+label, config.callbackPrefix+durationPickerCallbackInc+strconv.Itoa(i)),
+		))
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(config.DoneText, config.callbackPrefix+durationPickerCallbackDone),
+	))
+
+	return &tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: rows,
+	}
+}
+
+// formatDuration форматирует длительность в виде "2h 30m", отбрасывая нулевые части
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return "0m"
+	}
+
+	var parts []string
+	if days := d / (24 * time.Hour); days > 0 {
+		parts = append(parts, fmt.Sprintf("%dd", days))
+		d -= days * 24 * time.Hour
+	}
+	if hours := d / time.Hour; hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+		d -= hours * time.Hour
+	}
+	if minutes := d / time.Minute; minutes > 0 {
+		parts = append(parts, fmt.Sprintf("%dm", minutes))
+		d -= minutes * time.Minute
+	}
+	if seconds := d / time.Second; seconds > 0 {
+		parts = append(parts, fmt.Sprintf("%ds", seconds))
+	}
+	if len(parts) == 0 {
+		return "0m"
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// parseDuration разбирает длительность, введенную текстом: "2h 30m", "90m", "1d 2h".
+// Помимо единиц time.ParseDuration поддерживаются дни ("d").
+func parseDuration(text string) (time.Duration, error) {
+	text = strings.ToLower(strings.Join(strings.Fields(text), ""))
+	if text == "" {
+		return 0, ErrInvalidDuration
+	}
+
+	var days time.Duration
+	if i := strings.Index(text, "d"); i >= 0 {
+		n, err := strconv.Atoi(text[:i])
+		if err != nil {
+			return 0, err
+		}
+		days = time.Duration(n) * 24 * time.Hour
+		text = text[i+1:]
+	}
+
+	if text == "" {
+		return days, nil
+	}
+
+	d, err := time.ParseDuration(text)
+	if err != nil {
+		return 0, err
+	}
+	return days + d, nil
+}
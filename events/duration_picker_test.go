@@ -0,0 +1,77 @@
+package events_test
+
+import (
+	"strings"
+	"testing"
+	"tgfsm/events"
+	"tgfsm/tgfsmtest"
+	"time"
+)
+
+func TestDurationPickerButtons(t *testing.T) {
+	var selected time.Duration
+	picker := mustEvent(t)(events.NewDurationPickerEvent(
+		events.WithDurationPickerMessageTriggers("/duration"),
+		events.WithOnDurationSelected(func(d time.Duration, userID int64) error {
+			selected = d
+			return nil
+		}),
+	))
+	server, bot := newTestBot(t, picker)
+
+	tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/duration").
+		ExpectMessageContains("0m").
+		ExpectKeyboard("+1h", "+15m", events.DurationPickerDoneText).
+		Press("+1h").
+		ExpectEditContains("1h").
+		Press("+15m").
+		ExpectEditContains("1h 15m").
+		Press(events.DurationPickerDoneText).
+		ExpectEdit("Selected duration: 1h 15m").
+		ExpectState("home")
+
+	if selected != 75*time.Minute {
+		t.Fatalf("selected %v, want 1h15m", selected)
+	}
+}
+
+func TestDurationPickerTypedInput(t *testing.T) {
+	var selected time.Duration
+	picker := mustEvent(t)(events.NewDurationPickerEvent(
+		events.WithDurationPickerMessageTriggers("/duration"),
+		events.WithOnDurationSelected(func(d time.Duration, userID int64) error {
+			selected = d
+			return nil
+		}),
+	))
+	server, bot := newTestBot(t, picker)
+
+	scenario := tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/duration").
+		ExpectKeyboard(events.DurationPickerDoneText).
+		Send("soon").
+		ExpectMessage(events.DurationPickerInvalidText).
+		Send("48h").
+		ExpectMessage("Duration must be between 0m and 1d.").
+		Send("2h 30m").
+		ExpectMessageContains("2h 30m").
+		ExpectKeyboard(events.DurationPickerDoneText)
+
+	for _, row := range scenario.Last().InlineKeyboard() {
+		for _, button := range row {
+			if !strings.HasPrefix(*button.CallbackData, "dp:") {
+				t.Fatalf("callback data %q has no event prefix", *button.CallbackData)
+			}
+		}
+	}
+
+	scenario.
+		Press(events.DurationPickerDoneText).
+		ExpectEdit("Selected duration: 2h 30m").
+		ExpectState("home")
+
+	if selected != 150*time.Minute {
+		t.Fatalf("selected %v, want 2h30m", selected)
+	}
+}
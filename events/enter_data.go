@@ -247,8 +247,11 @@ func buildStates(config *EnterDataConfig) (map[string]tgfsm.State, error) {
 	var enterPhaseStateID = uuid.New().String()
 	var cacheKey = uuid.New().String()
 
+	name := eventName("EnterData", config.MessageTriggers, config.CallbackTriggers)
+
 	// Фаза ввода данных
 	var enterPhase tgfsm.State = tgfsm.State{
+		Name:   name,
 		Global: false,
 		AtEntranceFunc: &tgfsm.Handler{Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
 			msg := tgbotapi.NewMessage(u.SentFrom().ID, config.PromptText)
@@ -288,7 +291,7 @@ func buildStates(config *EnterDataConfig) (map[string]tgfsm.State, error) {
 					return nil
 				}
 
-				b.GetCache().Set(cacheKey, u.Message.Text, b.GetExpiration())
+				b.GetCache().Set(userKey(cacheKey, u.SentFrom().ID), u.Message.Text, b.GetExpiration())
 
 				// Если ConfirmInputText не задан, не отправляем сообщение
 				if config.ConfirmInputText == "" {
@@ -318,7 +321,7 @@ func buildStates(config *EnterDataConfig) (map[string]tgfsm.State, error) {
 			strings.ToLower(strings.TrimSpace(config.SubmitText)): {
 				Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
 					// Проверяем наличие данных в кеше
-					cachedData, found := b.GetCache().Get(cacheKey)
+					cachedData, found := b.GetCache().Get(userKey(cacheKey, u.SentFrom().ID))
 					if !found {
 						msg := tgbotapi.NewMessage(u.SentFrom().ID, config.DataNotFoundText)
 						_, err := b.SendMessage(msg)
@@ -356,7 +359,7 @@ func buildStates(config *EnterDataConfig) (map[string]tgfsm.State, error) {
 					}
 
 					// Удаляем данные из кеша после успешной обработки
-					b.GetCache().Delete(cacheKey)
+					b.GetCache().Delete(userKey(cacheKey, u.SentFrom().ID))
 
 					// Отправляем сообщение об успехе
 					if config.SuccessText != "" {
@@ -367,35 +370,15 @@ func buildStates(config *EnterDataConfig) (map[string]tgfsm.State, error) {
 						}
 					}
 
-					// Возвращаем пользователя в начальное состояние
-					return b.SetUserState(u.SentFrom().ID, "")
+					// Возвращаем пользователя в состояние, из которого он начал ввод
+					return finishEvent(b, u.SentFrom().ID)
 				},
 			},
 		},
 	}
 
-	// Состояние для перехода в состояние ввода данных
-	var enterInState = tgfsm.State{
-		Global: true,
-	}
-	var enterInStateID = uuid.New().String()
-
-	if len(config.MessageTriggers) > 0 {
-		enterInState.MessageHandlers = make(map[string]tgfsm.Handler)
-		for _, t := range config.MessageTriggers {
-			enterInState.MessageHandlers[t] = tgfsm.Handler{Handle: tgfsm.NewSetUserStateImmediateHandler(enterPhaseStateID)}
-		}
-	}
-
-	if len(config.CallbackTriggers) > 0 {
-		enterInState.CallbackHandlers = make(map[string]tgfsm.Handler)
-		for _, t := range config.CallbackTriggers {
-			enterInState.CallbackHandlers[t] = tgfsm.Handler{Handle: tgfsm.NewSetUserStateImmediateHandler(enterPhaseStateID)}
-		}
-	}
-
 	return map[string]tgfsm.State{
-		enterPhaseStateID: enterPhase,
-		enterInStateID:    enterInState,
+		enterPhaseStateID:   enterPhase,
+		uuid.New().String(): newEnterInState(name, enterPhaseStateID, config.MessageTriggers, config.CallbackTriggers),
 	}, nil
 }
@@ -0,0 +1,137 @@
+package events_test
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"tgfsm"
+	"tgfsm/events"
+	"tgfsm/tgfsmtest"
+)
+
+// feedbackOptions configures an enter-data event on /feedback that records saved values by user
+func feedbackOptions(saved map[int64]string) []events.EnterDataOption {
+	return []events.EnterDataOption{
+		events.WithMessageTriggers("/feedback"),
+		events.WithValidator(func(value string) error {
+			if len(strings.TrimSpace(value)) < 3 {
+				return errors.New("too short")
+			}
+			return nil
+		}),
+		events.WithOnSuccessEnterAction(func(message string, userId int64) error {
+			saved[userId] = message
+			return nil
+		}),
+	}
+}
+
+func TestEnterData(t *testing.T) {
+	saved := map[int64]string{}
+	server, bot := newTestBot(t, mustEvent(t)(events.NewEnterDataEvent(feedbackOptions(saved)...)))
+
+	tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/feedback").
+		ExpectMessage(events.PromptText).
+		Send("Ann").
+		ExpectMessageContains("You entered:\n\nAnn").
+		ExpectKeyboard(events.SubmitText).
+		Press(events.SubmitText).
+		ExpectMessage(events.SuccessText).
+		ExpectState("home")
+
+	if saved[testUserID] != "Ann" {
+		t.Fatalf("saved %q, want Ann", saved[testUserID])
+	}
+	if stack := bot.GetUserStateStack(testUserID); len(stack) != 0 {
+		t.Fatalf("history %q left after the event", stack)
+	}
+}
+
+func TestEnterDataValidation(t *testing.T) {
+	saved := map[int64]string{}
+	server, bot := newTestBot(t, mustEvent(t)(events.NewEnterDataEvent(feedbackOptions(saved)...)))
+
+	tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/feedback").
+		ExpectMessage(events.PromptText).
+		Send("A").
+		ExpectMessage("Invalid data: too short\nPlease try again.").
+		Send(events.SubmitText).
+		ExpectMessage(events.DataNotFoundText).
+		Send("Bob").
+		ExpectMessageContains("Bob").
+		Send("Ann").
+		ExpectMessageContains("Ann").
+		Press(events.SubmitText).
+		ExpectMessage(events.SuccessText)
+
+	if saved[testUserID] != "Ann" {
+		t.Fatalf("saved %q, want the last entered value Ann", saved[testUserID])
+	}
+}
+
+func TestEnterDataUsers(t *testing.T) {
+	saved := map[int64]string{}
+	server, bot := newTestBot(t, mustEvent(t)(events.NewEnterDataEvent(feedbackOptions(saved)...)))
+
+	ann := tgfsmtest.NewScenario(t, server, bot, 1)
+	bob := tgfsmtest.NewScenario(t, server, bot, 2)
+
+	ann.Send("/feedback").Send("Ann")
+	bob.Send("/feedback").Send("Bob")
+	ann.Press(events.SubmitText).ExpectMessage(events.SuccessText)
+	bob.Press(events.SubmitText).ExpectMessage(events.SuccessText)
+
+	if saved[1] != "Ann" || saved[2] != "Bob" {
+		t.Fatalf("saved %v, want the value of each user", saved)
+	}
+}
+
+func TestEnterDataFinalReset(t *testing.T) {
+	saved := map[int64]string{}
+	server, bot := newTestBot(t, mustEvent(t)(events.NewEnterDataEvent(feedbackOptions(saved)...)), map[string]tgfsm.State{"menu": {}})
+
+	// Entered without a stored state, the user is reset to the initial state at the end
+	tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/feedback").
+		Send("Ann").
+		Press(events.SubmitText).
+		ExpectMessage(events.SuccessText).
+		ExpectState("home")
+
+	if _, ok := bot.GetCache().Get(strconv.Itoa(testUserID)); ok {
+		t.Fatal("state is kept in storage after the final reset")
+	}
+
+	// Entered from a stored state, the user returns there
+	if err := bot.SetUserState(testUserID, "menu"); err != nil {
+		t.Fatal(err)
+	}
+	tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/feedback").
+		Send("Bob").
+		Press(events.SubmitText).
+		ExpectMessage(events.SuccessText).
+		ExpectState("menu")
+
+	if stack := bot.GetUserStateStack(testUserID); len(stack) != 0 {
+		t.Fatalf("history %q left after the event", stack)
+	}
+}
+
+func TestEnterDataGraphLabels(t *testing.T) {
+	saved := map[int64]string{}
+	states := mustEvent(t)(events.NewEnterDataEvent(feedbackOptions(saved)...))
+
+	var labels []string
+	for _, node := range tgfsm.NewGraph(states, "").Nodes {
+		labels = append(labels, node.Label)
+	}
+	slices.Sort(labels)
+	if want := []string{"EnterData (/feedback)", "EnterData (/feedback): вход"}; !slices.Equal(labels, want) {
+		t.Fatalf("labels %q, want %q", labels, want)
+	}
+}
@@ -0,0 +1,150 @@
+package events
+
+import (
+	"sync"
+	"tgfsm"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/google/uuid"
+)
+
+// userKey формирует ключ кеша, уникальный для пользователя.
+// Используется, чтобы данные разных пользователей одного события не перезаписывали друг друга.
+// Данные по таким ключам видны как данные сессии пользователя (tgfsm.Bot.UserSession).
+func userKey(prefix string, userID int64) string {
+	return tgfsm.UserSessionKey(prefix, userID)
+}
+
+// answerCallback отвечает на callback query, чтобы у пользователя пропал индикатор загрузки.
+// Ошибки игнорируются: ответ на callback не влияет на логику события.
+func answerCallback(b *tgfsm.Bot, u tgbotapi.Update, text string) {
+	if u.CallbackQuery == nil {
+		return
+	}
+	callback := tgbotapi.NewCallback(u.CallbackQuery.ID, text)
+	b.Request(callback)
+}
+
+// renderInline редактирует сообщение, к которому привязан callback, или отправляет новое,
+// если обновление пришло не из inline кнопки.
+// Если keyboard равен nil, клавиатура у сообщения удаляется.
+func renderInline(b *tgfsm.Bot, u tgbotapi.Update, text string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+	if u.CallbackQuery != nil && u.CallbackQuery.Message != nil {
+		editMsg := tgbotapi.NewEditMessageText(u.CallbackQuery.Message.Chat.ID, u.CallbackQuery.Message.MessageID, text)
+		editMsg.ReplyMarkup = keyboard
+		_, err := b.EditMessage(editMsg)
+		return err
+	}
+
+	msg := tgbotapi.NewMessage(u.SentFrom().ID, text)
+	if keyboard != nil {
+		msg.ReplyMarkup = keyboard
+	}
+	_, err := b.SendMessage(msg)
+	return err
+}
+
+// finishEvent возвращает пользователя в состояние, из которого он вошел в событие.
+func finishEvent(b *tgfsm.Bot, userID int64) error {
+	_, err := b.PopUserState(userID)
+	return err
+}
+
+// eventName возвращает читаемое имя состояния события для диаграмм (см. tgfsm.Graph):
+// название события и первый из его триггеров
+func eventName(event string, messageTriggers, callbackTriggers []string) string {
+	switch {
+	case len(messageTriggers) > 0:
+		return event + " (" + messageTriggers[0] + ")"
+	case len(callbackTriggers) > 0:
+		return event + " (" + callbackTriggers[0] + ")"
+	default:
+		return event
+	}
+}
+
+// newEnterInState создает глобальное состояние, переводящее пользователя в состояние target
+// по одному из триггеров. Текущее состояние пользователя запоминается, чтобы вернуть
+// его туда после завершения события (см. finishEvent).
+// name - имя состояния события, у состояния входа к нему добавляется пометка "вход".
+func newEnterInState(name, target string, messageTriggers, callbackTriggers []string) tgfsm.State {
+	var enterInState = tgfsm.State{
+		Name:   name + ": вход",
+		Global: true,
+		// Объявляем переход, чтобы в событие можно было войти из состояний с ограниченными переходами
+		Transitions: []string{target},
+	}
+
+	if len(messageTriggers) > 0 {
+		enterInState.MessageHandlers = make(map[string]tgfsm.Handler)
+		for _, t := range messageTriggers {
+			enterInState.MessageHandlers[t] = tgfsm.Handler{Handle: tgfsm.NewPushUserStateImmediateHandler(target)}
+		}
+	}
+
+	if len(callbackTriggers) > 0 {
+		enterInState.CallbackHandlers = make(map[string]tgfsm.Handler)
+		for _, t := range callbackTriggers {
+			enterInState.CallbackHandlers[t] = tgfsm.Handler{Handle: tgfsm.NewPushUserStateImmediateHandler(target)}
+		}
+	}
+
+	return enterInState
+}
+
+// newCallbackPrefix возвращает префикс callback данных экземпляра события: event и случайный
+// идентификатор, например "tp:1a2b3c4d:". С префиксом кнопки события не совпадают с callback
+// триггерами бота (глобальные состояния проверяются раньше события) и других событий.
+func newCallbackPrefix(event string) string {
+	return event + ":" + uuid.New().String()[:8] + ":"
+}
+
+// userLocks сериализует обработку обновлений одного пользователя в событии.
+// Обновления обрабатываются параллельно, поэтому без блокировки два быстрых нажатия
+// могут прочитать одно значение из кеша и потерять изменение одного из них.
+type userLocks struct {
+	mu    sync.Mutex
+	locks map[int64]*userLock
+}
+
+// userLock блокировка пользователя; refs - количество обработчиков, ожидающих или держащих ее
+type userLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock блокирует пользователя и возвращает функцию разблокировки.
+// Блокировка удаляется из карты, когда ее больше никто не ждет.
+func (l *userLocks) lock(userID int64) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[int64]*userLock)
+	}
+	lock, ok := l.locks[userID]
+	if !ok {
+		lock = &userLock{}
+		l.locks[userID] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		l.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, userID)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// locked возвращает обработчик, выполняющий handle под блокировкой пользователя
+func (l *userLocks) locked(handle tgfsm.HandlerFunc) tgfsm.HandlerFunc {
+	return func(b *tgfsm.Bot, u tgbotapi.Update) error {
+		defer l.lock(u.SentFrom().ID)()
+		return handle(b, u)
+	}
+}
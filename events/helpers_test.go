@@ -0,0 +1,46 @@
+package events_test
+
+import (
+	"testing"
+	"tgfsm"
+	"tgfsm/tgfsmtest"
+)
+
+// testUserID is the user talking to the bot in scenarios
+const testUserID = 1
+
+// newTestBot creates a bot with the states of events connected to a fake server
+// Users start in the empty "home" state, so events return them there when they finish.
+func newTestBot(t *testing.T, events ...map[string]tgfsm.State) (*tgfsmtest.Server, *tgfsm.Bot) {
+	t.Helper()
+
+	server := tgfsmtest.NewServer()
+	t.Cleanup(server.Close)
+
+	states := map[string]tgfsm.State{"home": {}}
+	for _, event := range events {
+		for key, state := range event {
+			states[key] = state
+		}
+	}
+
+	bot, err := tgfsmtest.NewBot(server, tgfsm.WithStates(states), tgfsm.WithInitialState("home"))
+	if err != nil {
+		t.Fatalf("failed to create bot: %v", err)
+	}
+	return server, bot
+}
+
+// mustEvent returns the states of an event, failing the test if the event could not be created:
+//
+//	mustEvent(t)(events.NewTimePickerEvent(...))
+func mustEvent(t *testing.T) func(map[string]tgfsm.State, error) map[string]tgfsm.State {
+	return func(states map[string]tgfsm.State, err error) map[string]tgfsm.State {
+		t.Helper()
+
+		if err != nil {
+			t.Fatalf("failed to create event: %v", err)
+		}
+		return states
+	}
+}
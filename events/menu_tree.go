@@ -0,0 +1,412 @@
+package events
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"tgfsm"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/google/uuid"
+)
+
+// Значения по умолчанию для конфигурации дерева меню
+const (
+	MenuTreeBackText            = "⬅️ Back"
+	MenuTreeHomeText            = "🏠 Home"
+	MenuTreeBreadcrumbSeparator = " › "
+	MenuTreeColumns             = 1
+)
+
+// Callback данные кнопок дерева меню, к ним добавляется префикс экземпляра меню
+const (
+	menuTreeCallbackOpen  = "open:"
+	menuTreeCallbackClose = "close"
+)
+
+var (
+	ErrMenuRootRequired   = errors.New("menu root node is required")
+	ErrMenuRootChildren   = errors.New("menu root node must have children")
+	ErrMenuNodeTitle      = errors.New("menu node title is required")
+	ErrMenuNodeEmpty      = errors.New("menu node must have text, children or action")
+	ErrMenuNodeNil        = errors.New("menu node cannot be nil")
+	ErrMenuNodeAction     = errors.New("menu node with action cannot have children")
+	ErrInvalidMenuColumns = errors.New("menu columns must be positive")
+)
+
+// ===== Опции для MenuTreeConfig =====
+
+// MenuTreeOption опция для конфигурации дерева меню
+type MenuTreeOption func(*MenuTreeConfig)
+
+// MenuNode представляет узел дерева меню.
+//
+// Узел с дочерними элементами отображается как меню с кнопками.
+// Узел с Action выполняет действие при нажатии на кнопку и не отображается как отдельный экран.
+// Узел без дочерних элементов и без Action отображается как экран с текстом и кнопками навигации.
+type MenuNode struct {
+	// Текст кнопки в родительском меню и название узла в хлебных крошках
+	Title string
+	// Текст экрана узла. Если не задан, используется Title
+	Text string
+	// ParseMode для текста узла, например "HTML"
+	ParseMode string
+	// Необязательное изображение экрана: file ID или URL
+	Photo string
+	// Дочерние узлы
+	Children []*MenuNode
+	// Действие листового узла. Вызывается вместо отображения экрана,
+	// поэтому узел с Action не может иметь дочерних узлов
+	Action tgfsm.HandlerFunc
+}
+
+// MenuTreeConfig конфигурация для дерева меню
+type MenuTreeConfig struct {
+	MessageTriggers     []string
+	CallbackTriggers    []string
+	Root                *MenuNode
+	BackText            string
+	HomeText            string
+	CloseText           string
+	BreadcrumbSeparator string
+	ShowBreadcrumb      bool
+	Columns             int
+
+	// callbackPrefix отличает callback данные меню от callback данных бота, других событий
+	// и других деревьев меню: кнопки старого сообщения другого меню не открывают его узлы
+	callbackPrefix string
+}
+
+// WithMenuTreeMessageTriggers устанавливает глобальные триггеры для открытия меню.
+// При указании пользователь сможет открыть меню, отправив триггер.
+func WithMenuTreeMessageTriggers(triggers ...string) MenuTreeOption {
+	return func(config *MenuTreeConfig) {
+		config.MessageTriggers = triggers
+	}
+}
+
+// WithMenuTreeCallbackTriggers устанавливает глобальные триггеры для открытия меню.
+// При указании пользователь сможет открыть меню, отправив callback.
+func WithMenuTreeCallbackTriggers(triggers ...string) MenuTreeOption {
+	return func(config *MenuTreeConfig) {
+		config.CallbackTriggers = triggers
+	}
+}
+
+// WithMenuTreeRoot устанавливает корневой узел меню.
+// Title корня используется как название главного экрана в хлебных крошках.
+// Обязательный параметр.
+func WithMenuTreeRoot(root *MenuNode) MenuTreeOption {
+	return func(config *MenuTreeConfig) {
+		config.Root = root
+	}
+}
+
+// WithMenuTreeNavigationText устанавливает тексты кнопок "Назад" и "Домой".
+// Если установить пустое значение, соответствующая кнопка не показывается.
+//
+// Если не установить, используются значения по умолчанию:
+//
+//	`MenuTreeBackText = "⬅️ Back"`
+//	`MenuTreeHomeText = "🏠 Home"`
+func WithMenuTreeNavigationText(back, home string) MenuTreeOption {
+	return func(config *MenuTreeConfig) {
+		config.BackText = back
+		config.HomeText = home
+	}
+}
+
+// WithMenuTreeCloseText добавляет на главный экран кнопку закрытия меню.
+// При нажатии клавиатура удаляется, а пользователь покидает состояние меню.
+// По умолчанию кнопка не показывается.
+func WithMenuTreeCloseText(text string) MenuTreeOption {
+	return func(config *MenuTreeConfig) {
+		config.CloseText = text
+	}
+}
+
+// WithMenuTreeBreadcrumb включает или отключает строку хлебных крошек над текстом экрана.
+// separator разделяет названия узлов, пустое значение заменяется значением по умолчанию:
+//
+//	`MenuTreeBreadcrumbSeparator = " › "`
+//
+// По умолчанию хлебные крошки включены.
+func WithMenuTreeBreadcrumb(enabled bool, separator string) MenuTreeOption {
+	return func(config *MenuTreeConfig) {
+		config.ShowBreadcrumb = enabled
+		if separator != "" {
+			config.BreadcrumbSeparator = separator
+		}
+	}
+}
+
+// WithMenuTreeColumns устанавливает количество кнопок дочерних узлов в одной строке.
+// Если не установить, каждая кнопка выводится на отдельной строке.
+func WithMenuTreeColumns(columns int) MenuTreeOption {
+	return func(config *MenuTreeConfig) {
+		config.Columns = columns
+	}
+}
+
+// menuEntry узел дерева с вычисленной позицией
+type menuEntry struct {
+	node   *MenuNode
+	path   string
+	parent string
+	crumbs []string
+}
+
+// NewMenuTreeEvent создает состояние для иерархического меню на inline кнопках.
+//
+// Все экраны меню обслуживаются одним состоянием: переходы между узлами выполняются
+// редактированием сообщения, кнопки "Назад" и "Домой" добавляются автоматически.
+// Пример:
+//
+//	events.NewMenuTreeEvent(
+//		events.WithMenuTreeMessageTriggers("/start"),
+//		events.WithMenuTreeRoot(&events.MenuNode{
+//			Title: "Menu",
+//			Text:  "Choose a section:",
+//			Children: []*events.MenuNode{
+//				{Title: "About", Text: "We are ..."},
+//				{Title: "Contacts", Children: []*events.MenuNode{...}},
+//			},
+//		}),
+//	)
+func NewMenuTreeEvent(opts ...MenuTreeOption) (map[string]tgfsm.State, error) {
+	config := &MenuTreeConfig{
+		BackText:            MenuTreeBackText,
+		HomeText:            MenuTreeHomeText,
+		BreadcrumbSeparator: MenuTreeBreadcrumbSeparator,
+		ShowBreadcrumb:      true,
+		Columns:             MenuTreeColumns,
+
+		callbackPrefix: newCallbackPrefix("mt"),
+	}
+
+	// Применяем все опции
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	// Валидация обязательных полей
+	if len(config.MessageTriggers) == 0 && len(config.CallbackTriggers) == 0 {
+		return nil, tgfsm.ErrEmptyTriggers
+	}
+
+	if config.Root == nil {
+		return nil, ErrMenuRootRequired
+	}
+
+	if len(config.Root.Children) == 0 {
+		return nil, ErrMenuRootChildren
+	}
+
+	if config.Columns <= 0 {
+		return nil, ErrInvalidMenuColumns
+	}
+
+	entries := make(map[string]*menuEntry)
+	if err := collectMenuEntries(config.Root, "", "", nil, entries); err != nil {
+		return nil, err
+	}
+
+	return buildMenuTreeStates(config, entries)
+}
+
+// collectMenuEntries обходит дерево и сохраняет узлы по их пути.
+// Путь корня - пустая строка, путь дочернего узла - индексы через точку: "0", "0.2", "0.2.1".
+func collectMenuEntries(node *MenuNode, path, parent string, crumbs []string, entries map[string]*menuEntry) error {
+	if node == nil {
+		return tgfsm.NewSFMError(ErrMenuNodeNil, path)
+	}
+	if node.Title == "" {
+		return tgfsm.NewSFMError(ErrMenuNodeTitle, path)
+	}
+	if node.Text == "" && len(node.Children) == 0 && node.Action == nil {
+		return tgfsm.NewSFMError(ErrMenuNodeEmpty, node.Title)
+	}
+	// Action вызывается вместо отображения экрана, поэтому дочерние узлы были бы недостижимы
+	if node.Action != nil && len(node.Children) > 0 {
+		return tgfsm.NewSFMError(ErrMenuNodeAction, node.Title)
+	}
+
+	crumbs = append(append([]string(nil), crumbs...), node.Title)
+	entries[path] = &menuEntry{
+		node:   node,
+		path:   path,
+		parent: parent,
+		crumbs: crumbs,
+	}
+
+	for i, child := range node.Children {
+		childPath := strconv.Itoa(i)
+		if path != "" {
+			childPath = path + "." + childPath
+		}
+		if err := collectMenuEntries(child, childPath, path, crumbs, entries); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// buildMenuTreeStates создает состояния для дерева меню
+func buildMenuTreeStates(config *MenuTreeConfig, entries map[string]*menuEntry) (map[string]tgfsm.State, error) {
+	var menuStateID = uuid.New().String()
+
+	callbackHandlers := make(map[string]tgfsm.Handler, len(entries)+1)
+	for path, entry := range entries {
+		entry := entry
+		callbackHandlers[config.callbackPrefix+menuTreeCallbackOpen+path] = tgfsm.Handler{
+			Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+				answerCallback(b, u, "")
+
+				if entry.node.Action != nil {
+					return entry.node.Action(b, u)
+				}
+				return renderMenuNode(b, u, config, entry)
+			},
+		}
+	}
+
+	if config.CloseText != "" {
+		callbackHandlers[config.callbackPrefix+menuTreeCallbackClose] = tgfsm.Handler{
+			Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+				answerCallback(b, u, "")
+
+				if u.CallbackQuery != nil && u.CallbackQuery.Message != nil {
+					deleteMsg := tgbotapi.NewDeleteMessage(u.CallbackQuery.Message.Chat.ID, u.CallbackQuery.Message.MessageID)
+					_ = b.DeleteMessage(deleteMsg)
+				}
+
+				return finishEvent(b, u.SentFrom().ID)
+			},
+		}
+	}
+
+	name := eventName("MenuTree", config.MessageTriggers, config.CallbackTriggers)
+
+	// Фаза навигации по меню
+	var menuPhase tgfsm.State = tgfsm.State{
+		Name:   name,
+		Global: false,
+		AtEntranceFunc: &tgfsm.Handler{Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+			// Главный экран всегда отправляется новым сообщением
+			return sendMenuNode(b, u.SentFrom().ID, config, entries[""])
+		}},
+		CallbackHandlers: callbackHandlers,
+	}
+
+	return map[string]tgfsm.State{
+		menuStateID:         menuPhase,
+		uuid.New().String(): newEnterInState(name, menuStateID, config.MessageTriggers, config.CallbackTriggers),
+	}, nil
+}
+
+// renderMenuNode показывает экран узла, по возможности редактируя текущее сообщение.
+// Сообщения с изображением нельзя превратить в текстовые и наоборот,
+// поэтому в таком случае старое сообщение удаляется и отправляется новое.
+func renderMenuNode(b *tgfsm.Bot, u tgbotapi.Update, config *MenuTreeConfig, entry *menuEntry) error {
+	if u.CallbackQuery == nil || u.CallbackQuery.Message == nil {
+		return sendMenuNode(b, u.SentFrom().ID, config, entry)
+	}
+
+	current := u.CallbackQuery.Message
+	if entry.node.Photo == "" && len(current.Photo) == 0 {
+		editMsg := tgbotapi.NewEditMessageText(current.Chat.ID, current.MessageID, menuNodeText(config, entry))
+		editMsg.ParseMode = entry.node.ParseMode
+		editMsg.ReplyMarkup = buildMenuKeyboard(config, entry)
+		_, err := b.EditMessage(editMsg)
+		return err
+	}
+
+	deleteMsg := tgbotapi.NewDeleteMessage(current.Chat.ID, current.MessageID)
+	_ = b.DeleteMessage(deleteMsg)
+
+	return sendMenuNode(b, current.Chat.ID, config, entry)
+}
+
+// sendMenuNode отправляет экран узла новым сообщением
+func sendMenuNode(b *tgfsm.Bot, chatID int64, config *MenuTreeConfig, entry *menuEntry) error {
+	text := menuNodeText(config, entry)
+	keyboard := buildMenuKeyboard(config, entry)
+
+	if entry.node.Photo != "" {
+		var file tgbotapi.RequestFileData = tgbotapi.FileID(entry.node.Photo)
+		if strings.HasPrefix(entry.node.Photo, "http://") || strings.HasPrefix(entry.node.Photo, "https://") {
+			file = tgbotapi.FileURL(entry.node.Photo)
+		}
+
+		photo := tgbotapi.NewPhoto(chatID, file)
+		photo.Caption = text
+		photo.ParseMode = entry.node.ParseMode
+		photo.ReplyMarkup = keyboard
+		_, err := b.SendPhoto(photo)
+		return err
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = entry.node.ParseMode
+	msg.ReplyMarkup = keyboard
+	_, err := b.SendMessage(msg)
+	return err
+}
+
+// menuNodeText формирует текст экрана узла с хлебными крошками
+func menuNodeText(config *MenuTreeConfig, entry *menuEntry) string {
+	text := entry.node.Text
+	if text == "" {
+		text = entry.node.Title
+	}
+
+	// На главном экране хлебные крошки не нужны
+	if !config.ShowBreadcrumb || entry.path == "" {
+		return text
+	}
+
+	return strings.Join(entry.crumbs, config.BreadcrumbSeparator) + "\n\n" + text
+}
+
+// buildMenuKeyboard создает клавиатуру экрана: кнопки дочерних узлов и навигацию
+func buildMenuKeyboard(config *MenuTreeConfig, entry *menuEntry) *tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+
+	// Кнопки дочерних узлов по config.Columns в строке
+	var row []tgbotapi.InlineKeyboardButton
+	for i, child := range entry.node.Children {
+		childPath := strconv.Itoa(i)
+		if entry.path != "" {
+			childPath = entry.path + "." + childPath
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(child.Title, config.callbackPrefix+menuTreeCallbackOpen+childPath))
+		if len(row) == config.Columns {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	// Навигация: "Назад" ведет к родителю, "Домой" - на главный экран.
+	// "Домой" не показывается, если родитель и есть главный экран.
+	var navigation []tgbotapi.InlineKeyboardButton
+	if entry.path != "" && config.BackText != "" {
+		navigation = append(navigation, tgbotapi.NewInlineKeyboardButtonData(config.BackText, config.callbackPrefix+menuTreeCallbackOpen+entry.parent))
+	}
+	if entry.parent != "" && config.HomeText != "" {
+		navigation = append(navigation, tgbotapi.NewInlineKeyboardButtonData(config.HomeText, config.callbackPrefix+menuTreeCallbackOpen))
+	}
+	if entry.path == "" && config.CloseText != "" {
+		navigation = append(navigation, tgbotapi.NewInlineKeyboardButtonData(config.CloseText, config.callbackPrefix+menuTreeCallbackClose))
+	}
+	if len(navigation) > 0 {
+		rows = append(rows, navigation)
+	}
+
+	return &tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: rows,
+	}
+}
@@ -0,0 +1,138 @@
+package events_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"tgfsm"
+	"tgfsm/events"
+	"tgfsm/tgfsmtest"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// newHelpMenu creates a menu on /help with a nested section and an action
+func newHelpMenu(t *testing.T, called *int) map[string]tgfsm.State {
+	t.Helper()
+
+	return mustEvent(t)(events.NewMenuTreeEvent(
+		events.WithMenuTreeMessageTriggers("/help"),
+		events.WithMenuTreeCloseText("Close"),
+		events.WithMenuTreeRoot(&events.MenuNode{
+			Title: "Help",
+			Text:  "Choose a section:",
+			Children: []*events.MenuNode{
+				{Title: "About", Text: "We are a bot."},
+				{Title: "Contacts", Children: []*events.MenuNode{
+					{Title: "Email", Text: "bot@example.com"},
+					{Title: "Call me", Action: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+						*called++
+						return nil
+					}},
+				}},
+			},
+		}),
+	))
+}
+
+func TestMenuTreeNavigation(t *testing.T) {
+	var called int
+	server, bot := newTestBot(t, newHelpMenu(t, &called))
+
+	tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/help").
+		ExpectMessage("Choose a section:").
+		ExpectKeyboard("About", "Contacts", "Close").
+		Press("Contacts").
+		ExpectEdit("Help › Contacts\n\nContacts").
+		ExpectKeyboard("Email", "Call me", events.MenuTreeBackText).
+		Press("Email").
+		ExpectEdit("Help › Contacts › Email\n\nbot@example.com").
+		ExpectKeyboard(events.MenuTreeBackText, events.MenuTreeHomeText).
+		Press(events.MenuTreeBackText).
+		ExpectEditContains("Help › Contacts").
+		Press("Call me").
+		ExpectNoMessages().
+		Press(events.MenuTreeBackText).
+		ExpectEdit("Choose a section:").
+		ExpectStateName("MenuTree (/help)")
+
+	if called != 1 {
+		t.Fatalf("action called %d times, want 1", called)
+	}
+}
+
+func TestMenuTreeHomeAndClose(t *testing.T) {
+	var called int
+	server, bot := newTestBot(t, newHelpMenu(t, &called))
+
+	tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/help").
+		Press("Contacts").
+		Press("Email").
+		Press(events.MenuTreeHomeText).
+		ExpectEdit("Choose a section:").
+		Press("Close").
+		ExpectDeleted().
+		ExpectState("home")
+}
+
+func TestMenuTreeValidation(t *testing.T) {
+	action := func(b *tgfsm.Bot, u tgbotapi.Update) error { return nil }
+	tests := []struct {
+		name string
+		root *events.MenuNode
+		err  error
+	}{
+		{"no children", &events.MenuNode{Title: "Help"}, events.ErrMenuRootChildren},
+		{"no title", &events.MenuNode{Title: "Help", Children: []*events.MenuNode{{Text: "text"}}}, events.ErrMenuNodeTitle},
+		{"empty node", &events.MenuNode{Title: "Help", Children: []*events.MenuNode{{Title: "About"}}}, events.ErrMenuNodeEmpty},
+		{"nil node", &events.MenuNode{Title: "Help", Children: []*events.MenuNode{nil}}, events.ErrMenuNodeNil},
+		{"action with children", &events.MenuNode{Title: "Help", Children: []*events.MenuNode{
+			{Title: "Call", Action: action, Children: []*events.MenuNode{{Title: "Phone", Text: "123"}}},
+		}}, events.ErrMenuNodeAction},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := events.NewMenuTreeEvent(
+				events.WithMenuTreeMessageTriggers("/help"),
+				events.WithMenuTreeRoot(tt.root),
+			)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestMenuTreeCallbackNamespace(t *testing.T) {
+	var called int
+	shop := mustEvent(t)(events.NewMenuTreeEvent(
+		events.WithMenuTreeMessageTriggers("/shop"),
+		events.WithMenuTreeRoot(&events.MenuNode{
+			Title: "Shop",
+			Text:  "Choose a category:",
+			Children: []*events.MenuNode{
+				{Title: "Books", Text: "No books yet."},
+				{Title: "Music", Text: "No music yet."},
+			},
+		}),
+	))
+	server, bot := newTestBot(t, newHelpMenu(t, &called), shop)
+
+	s := tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/help").
+		ExpectMessage("Choose a section:")
+	contacts := callbackData(t, s.Last(), "Contacts")
+	if !strings.HasPrefix(contacts, "mt:") {
+		t.Fatalf("button data %q, want the menu prefix", contacts)
+	}
+
+	// A button of the old help menu message does not open a node of the shop menu
+	s.Send("/shop").
+		ExpectMessage("Choose a category:").
+		PressData(contacts).
+		ExpectNoMessages().
+		ExpectStateName("MenuTree (/shop)")
+}
@@ -131,8 +131,11 @@ func NewSimpleSliderEvent(opts ...SimpleSliderOption) (map[string]tgfsm.State, e
 func buildSimpleSliderStates(config *SimpleSliderConfig) (map[string]tgfsm.State, error) {
 	var sliderStateID = uuid.New().String()
 
+	name := eventName("SimpleSlider", config.MessageTriggers, config.CallbackTriggers)
+
 	// Фаза листания текстов
 	var sliderPhase tgfsm.State = tgfsm.State{
+		Name:   name,
 		Global: false,
 		AtEntranceFunc: &tgfsm.Handler{Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
 			// Устанавливаем начальный индекс
@@ -147,7 +150,7 @@ func buildSimpleSliderStates(config *SimpleSliderConfig) (map[string]tgfsm.State
 					// Отвечаем на callback query
 					if u.CallbackQuery != nil {
 						callback := tgbotapi.NewCallback(u.CallbackQuery.ID, "")
-						b.BotAPI.Request(callback)
+						b.Request(callback)
 					}
 
 					// Получаем текущий индекс
@@ -179,7 +182,7 @@ func buildSimpleSliderStates(config *SimpleSliderConfig) (map[string]tgfsm.State
 					// Отвечаем на callback query
 					if u.CallbackQuery != nil {
 						callback := tgbotapi.NewCallback(u.CallbackQuery.ID, "")
-						b.BotAPI.Request(callback)
+						b.Request(callback)
 					}
 
 					// Получаем текущий индекс
@@ -209,29 +212,9 @@ func buildSimpleSliderStates(config *SimpleSliderConfig) (map[string]tgfsm.State
 		},
 	}
 
-	// Состояние для перехода в состояние слайдера
-	var enterInState = tgfsm.State{
-		Global: true,
-	}
-	var enterInStateID = uuid.New().String()
-
-	if len(config.MessageTriggers) > 0 {
-		enterInState.MessageHandlers = make(map[string]tgfsm.Handler)
-		for _, t := range config.MessageTriggers {
-			enterInState.MessageHandlers[t] = tgfsm.Handler{Handle: tgfsm.NewSetUserStateImmediateHandler(sliderStateID)}
-		}
-	}
-
-	if len(config.CallbackTriggers) > 0 {
-		enterInState.CallbackHandlers = make(map[string]tgfsm.Handler)
-		for _, t := range config.CallbackTriggers {
-			enterInState.CallbackHandlers[t] = tgfsm.Handler{Handle: tgfsm.NewSetUserStateImmediateHandler(sliderStateID)}
-		}
-	}
-
 	return map[string]tgfsm.State{
-		sliderStateID:  sliderPhase,
-		enterInStateID: enterInState,
+		sliderStateID:       sliderPhase,
+		uuid.New().String(): newEnterInState(name, sliderStateID, config.MessageTriggers, config.CallbackTriggers),
 	}, nil
 }
 
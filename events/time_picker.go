@@ -0,0 +1,453 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"tgfsm"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/google/uuid"
+)
+
+// Значения по умолчанию для конфигурации выбора времени
+const (
+	TimePickerPromptText    = "Select time:"
+	TimePickerDoneText      = "✅ Done"
+	TimePickerSuccessText   = "Selected time: %s"
+	TimePickerInvalidText   = "Invalid time. Use the buttons or send time as HH:MM."
+	TimePickerMinuteStep    = 5
+	TimePickerHourStep      = 1
+	TimePickerInitialHour   = 12
+	TimePickerInitialMinute = 0
+)
+
+// Callback данные кнопок выбора времени
+const (
+	timePickerMinutesPerDay  = 24 * 60
+	timePickerCallbackNoop   = "noop"
+	timePickerCallbackDone   = "done"
+	timePickerCallbackAmPm   = "ampm"
+	timePickerCallbackHourUp = "hour_inc"
+	timePickerCallbackHourDn = "hour_dec"
+	timePickerCallbackMinUp  = "minute_inc"
+	timePickerCallbackMinDn  = "minute_dec"
+)
+
+var (
+	ErrTimeSelectedActionRequired = errors.New("time selected action is required")
+	ErrInvalidTimePickerStep      = errors.New("time picker step must be positive")
+	ErrInvalidTimePickerInitial   = errors.New("time picker initial time is out of range")
+	ErrInvalidClock               = errors.New("time is out of range")
+)
+
+// ===== Опции для TimePickerConfig =====
+
+// TimePickerOption опция для конфигурации выбора времени
+type TimePickerOption func(*TimePickerConfig)
+
+// TimePickerConfig конфигурация для выбора времени суток
+type TimePickerConfig struct {
+	MessageTriggers  []string
+	CallbackTriggers []string
+	PromptText       string
+	DoneText         string
+	SuccessText      string
+	InvalidText      string
+	HourStep         int
+	MinuteStep       int
+	Use12Hour        bool
+	InitialHour      int
+	InitialMinute    int
+	OnTimeSelected   func(hour, minute int, userId int64) error
+	ValueKey         string
+
+	// callbackPrefix отличает callback данные события от callback данных бота и других событий
+	callbackPrefix string
+}
+
+// WithTimePickerMessageTriggers устанавливает глобальные триггеры для входа в режим выбора времени.
+// При указании пользователь сможет войти в состояние выбора времени, отправив триггер.
+func WithTimePickerMessageTriggers(triggers ...string) TimePickerOption {
+	return func(config *TimePickerConfig) {
+		config.MessageTriggers = triggers
+	}
+}
+
+// WithTimePickerCallbackTriggers устанавливает глобальные триггеры для входа в режим выбора времени.
+// При указании пользователь сможет войти в состояние выбора времени, отправив callback.
+func WithTimePickerCallbackTriggers(triggers ...string) TimePickerOption {
+	return func(config *TimePickerConfig) {
+		config.CallbackTriggers = triggers
+	}
+}
+
+// WithTimePickerPromptText устанавливает текст над клавиатурой выбора времени.
+// Если не установить, используется значение по умолчанию:
+//
+//	`TimePickerPromptText = "Select time:"`
+func WithTimePickerPromptText(text string) TimePickerOption {
+	return func(config *TimePickerConfig) {
+		config.PromptText = text
+	}
+}
+
+// WithTimePickerDoneText устанавливает текст кнопки подтверждения.
+// Если не установить, используется значение по умолчанию:
+//
+//	`TimePickerDoneText = "✅ Done"`
+func WithTimePickerDoneText(text string) TimePickerOption {
+	return func(config *TimePickerConfig) {
+		config.DoneText = text
+	}
+}
+
+// WithTimePickerSuccessText устанавливает текст, которым заменяется сообщение после подтверждения.
+// Использует форматирование: %s будет заменен на выбранное время.
+// Если установить пустое значение, сообщение будет удалено без замены текста.
+//
+// Если не установить, используется значение по умолчанию:
+//
+//	`TimePickerSuccessText = "Selected time: %s"`
+func WithTimePickerSuccessText(text string) TimePickerOption {
+	return func(config *TimePickerConfig) {
+		config.SuccessText = text
+	}
+}
+
+// WithTimePickerInvalidText устанавливает текст сообщения о некорректно введенном времени.
+// Если не установить, используется значение по умолчанию:
+//
+//	`TimePickerInvalidText = "Invalid time. Use the buttons or send time as HH:MM."`
+func WithTimePickerInvalidText(text string) TimePickerOption {
+	return func(config *TimePickerConfig) {
+		config.InvalidText = text
+	}
+}
+
+// WithTimePickerMinuteStep устанавливает шаг изменения минут кнопками.
+// Если не установить, используется значение по умолчанию:
+//
+//	`TimePickerMinuteStep = 5`
+func WithTimePickerMinuteStep(step int) TimePickerOption {
+	return func(config *TimePickerConfig) {
+		config.MinuteStep = step
+	}
+}
+
+// WithTimePickerHourStep устанавливает шаг изменения часов кнопками.
+// Если не установить, используется значение по умолчанию:
+//
+//	`TimePickerHourStep = 1`
+func WithTimePickerHourStep(step int) TimePickerOption {
+	return func(config *TimePickerConfig) {
+		config.HourStep = step
+	}
+}
+
+// WithTimePicker12Hour включает 12-часовой формат с кнопкой переключения AM/PM.
+// По умолчанию используется 24-часовой формат.
+func WithTimePicker12Hour(enabled bool) TimePickerOption {
+	return func(config *TimePickerConfig) {
+		config.Use12Hour = enabled
+	}
+}
+
+// WithTimePickerInitialTime устанавливает время, которое показывается при входе в событие.
+// Часы задаются в 24-часовом формате независимо от WithTimePicker12Hour.
+// Если не установить, используется 12:00.
+func WithTimePickerInitialTime(hour, minute int) TimePickerOption {
+	return func(config *TimePickerConfig) {
+		config.InitialHour = hour
+		config.InitialMinute = minute
+	}
+}
+
+// WithOnTimeSelected устанавливает функцию, которая получит выбранное время.
+// Часы передаются в 24-часовом формате. Обязательный параметр.
+func WithOnTimeSelected(action func(hour, minute int, userId int64) error) TimePickerOption {
+	return func(config *TimePickerConfig) {
+		config.OnTimeSelected = action
+	}
+}
+
+// NewTimePickerEvent создает цепочку состояний для выбора времени суток с использованием опций.
+//
+// Пользователь меняет часы и минуты inline кнопками, сообщение редактируется на месте.
+// Время также можно отправить текстом в формате "14:30" или "2:30 pm".
+func NewTimePickerEvent(opts ...TimePickerOption) (map[string]tgfsm.State, error) {
+	config := &TimePickerConfig{
+		PromptText:     TimePickerPromptText,
+		DoneText:       TimePickerDoneText,
+		SuccessText:    TimePickerSuccessText,
+		InvalidText:    TimePickerInvalidText,
+		HourStep:       TimePickerHourStep,
+		MinuteStep:     TimePickerMinuteStep,
+		InitialHour:    TimePickerInitialHour,
+		InitialMinute:  TimePickerInitialMinute,
+		ValueKey:       uuid.New().String(),
+		callbackPrefix: newCallbackPrefix("tp"),
+	}
+
+	// Применяем все опции
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	// Валидация обязательных полей
+	if len(config.MessageTriggers) == 0 && len(config.CallbackTriggers) == 0 {
+		return nil, tgfsm.ErrEmptyTriggers
+	}
+
+	if config.OnTimeSelected == nil {
+		return nil, ErrTimeSelectedActionRequired
+	}
+
+	if config.HourStep <= 0 || config.MinuteStep <= 0 {
+		return nil, ErrInvalidTimePickerStep
+	}
+
+	if config.InitialHour < 0 || config.InitialHour > 23 || config.InitialMinute < 0 || config.InitialMinute > 59 {
+		return nil, ErrInvalidTimePickerInitial
+	}
+
+	return buildTimePickerStates(config)
+}
+
+// buildTimePickerStates создает состояния для выбора времени
+func buildTimePickerStates(config *TimePickerConfig) (map[string]tgfsm.State, error) {
+	var pickerStateID = uuid.New().String()
+
+	// shift меняет выбранное время на delta минут и перерисовывает клавиатуру
+	shift := func(delta int) tgfsm.Handler {
+		return tgfsm.Handler{Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+			answerCallback(b, u, "")
+
+			value := getTimePickerValue(b, config, u.SentFrom().ID)
+			value = ((value+delta)%timePickerMinutesPerDay + timePickerMinutesPerDay) % timePickerMinutesPerDay
+			b.GetCache().Set(userKey(config.ValueKey, u.SentFrom().ID), value, b.GetExpiration())
+
+			return renderTimePicker(b, u, config, value)
+		}}
+	}
+
+	name := eventName("TimePicker", config.MessageTriggers, config.CallbackTriggers)
+
+	// Фаза выбора времени
+	var pickerPhase tgfsm.State = tgfsm.State{
+		Name:   name,
+		Global: false,
+		AtEntranceFunc: &tgfsm.Handler{Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+			value := config.InitialHour*60 + config.InitialMinute
+			b.GetCache().Set(userKey(config.ValueKey, u.SentFrom().ID), value, b.GetExpiration())
+
+			msg := tgbotapi.NewMessage(u.SentFrom().ID, timePickerText(config, value))
+			msg.ReplyMarkup = buildTimePickerKeyboard(config, value)
+			_, err := b.SendMessage(msg)
+			return err
+		}},
+		// Пустая карта нужна, чтобы текстовые сообщения доходили до CatchAllFunc
+		MessageHandlers: map[string]tgfsm.Handler{},
+		CatchAllFunc: &tgfsm.Handler{Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+			// Время можно ввести текстом вместо кнопок
+			if u.Message == nil || u.Message.Text == "" {
+				return nil
+			}
+
+			value, err := parseClock(u.Message.Text)
+			if err != nil {
+				msg := tgbotapi.NewMessage(u.SentFrom().ID, config.InvalidText)
+				_, sendErr := b.SendMessage(msg)
+				return sendErr
+			}
+
+			b.GetCache().Set(userKey(config.ValueKey, u.SentFrom().ID), value, b.GetExpiration())
+			return renderTimePicker(b, u, config, value)
+		}},
+		CallbackHandlers: map[string]tgfsm.Handler{
+			config.callbackPrefix + timePickerCallbackHourUp: shift(config.HourStep * 60),
+			config.callbackPrefix + timePickerCallbackHourDn: shift(-config.HourStep * 60),
+			config.callbackPrefix + timePickerCallbackMinUp:  shift(config.MinuteStep),
+			config.callbackPrefix + timePickerCallbackMinDn:  shift(-config.MinuteStep),
+			config.callbackPrefix + timePickerCallbackAmPm:   shift(12 * 60),
+			config.callbackPrefix + timePickerCallbackNoop: {
+				Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+					answerCallback(b, u, "")
+					return nil
+				},
+			},
+			config.callbackPrefix + timePickerCallbackDone: {
+				Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+					answerCallback(b, u, "")
+
+					userID := u.SentFrom().ID
+					value := getTimePickerValue(b, config, userID)
+
+					if err := config.OnTimeSelected(value/60, value%60, userID); err != nil {
+						return err
+					}
+
+					b.GetCache().Delete(userKey(config.ValueKey, userID))
+
+					// Убираем клавиатуру, оставляя выбранное значение в тексте
+					if config.SuccessText != "" {
+						text := fmt.Sprintf(config.SuccessText, formatClock(value, config.Use12Hour))
+						if err := renderInline(b, u, text, nil); err != nil {
+							return err
+						}
+					} else if u.CallbackQuery != nil && u.CallbackQuery.Message != nil {
+						deleteMsg := tgbotapi.NewDeleteMessage(u.CallbackQuery.Message.Chat.ID, u.CallbackQuery.Message.MessageID)
+						_ = b.DeleteMessage(deleteMsg)
+					}
+
+					return finishEvent(b, userID)
+				},
+			},
+		},
+	}
+
+	return map[string]tgfsm.State{
+		pickerStateID:       pickerPhase,
+		uuid.New().String(): newEnterInState(name, pickerStateID, config.MessageTriggers, config.CallbackTriggers),
+	}, nil
+}
+
+// getTimePickerValue возвращает выбранное пользователем время в минутах от начала суток.
+// Если значение не найдено в кеше, возвращается начальное время.
+func getTimePickerValue(b *tgfsm.Bot, config *TimePickerConfig, userID int64) int {
+	cached, found := b.GetCache().Get(userKey(config.ValueKey, userID))
+	if !found {
+		return config.InitialHour*60 + config.InitialMinute
+	}
+
+	value, ok := cached.(int)
+	if !ok {
+		return config.InitialHour*60 + config.InitialMinute
+	}
+
+	return value
+}
+
+// renderTimePicker обновляет сообщение с выбором времени
+func renderTimePicker(b *tgfsm.Bot, u tgbotapi.Update, config *TimePickerConfig, value int) error {
+	return renderInline(b, u, timePickerText(config, value), buildTimePickerKeyboard(config, value))
+}
+
+// timePickerText формирует текст сообщения с текущим выбранным временем
+func timePickerText(config *TimePickerConfig, value int) string {
+	return config.PromptText + "\n\n🕒 " + formatClock(value, config.Use12Hour)
+}
+
+// buildTimePickerKeyboard создает клавиатуру для выбора времени
+func buildTimePickerKeyboard(config *TimePickerConfig, value int) *tgbotapi.InlineKeyboardMarkup {
+	hour, minute := value/60, value%60
+
+	hourLabel := fmt.Sprintf("%02d", hour)
+	if config.Use12Hour {
+		hourLabel = fmt.Sprintf("%02d", to12Hour(hour))
+	}
+
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("−%dh", config.HourStep), config.callbackPrefix+timePickerCallbackHourDn),
+			tgbotapi.NewInlineKeyboardButtonData(hourLabel, config.callbackPrefix+timePickerCallbackNoop),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("+%dh", config.HourStep), config.callbackPrefix+timePickerCallbackHourUp),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("−%dm", config.MinuteStep), config.callbackPrefix+timePickerCallbackMinDn),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%02d", minute), config.callbackPrefix+timePickerCallbackNoop),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("+%dm", config.MinuteStep), config.callbackPrefix+timePickerCallbackMinUp),
+		),
+	}
+
+	if config.Use12Hour {
+		label := "AM → PM"
+		if hour >= 12 {
+			label = "PM → AM"
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, config.callbackPrefix+timePickerCallbackAmPm),
+		))
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(config.DoneText, config.callbackPrefix+timePickerCallbackDone),
+	))
+
+	return &tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: rows,
+	}
+}
+
+// to12Hour переводит час из 24-часового формата в 12-часовой
+func to12Hour(hour int) int {
+	if hour%12 == 0 {
+		return 12
+	}
+	return hour % 12
+}
+
+// formatClock форматирует время, заданное в минутах от начала суток
+func formatClock(value int, use12Hour bool) string {
+	hour, minute := value/60, value%60
+	if !use12Hour {
+		return fmt.Sprintf("%02d:%02d", hour, minute)
+	}
+
+	suffix := "AM"
+	if hour >= 12 {
+		suffix = "PM"
+	}
+	return fmt.Sprintf("%d:%02d %s", to12Hour(hour), minute, suffix)
+}
+
+// parseClock разбирает время, введенное текстом: "14:30", "9.05", "2:30 pm", "12 am".
+// Возвращает количество минут от начала суток.
+func parseClock(text string) (int, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+
+	suffix := ""
+	for _, s := range []string{"am", "pm"} {
+		if strings.HasSuffix(text, s) {
+			suffix = s
+			text = strings.TrimSpace(strings.TrimSuffix(text, s))
+			break
+		}
+	}
+
+	hourPart, minutePart := text, "0"
+	if i := strings.IndexAny(text, ":."); i >= 0 {
+		hourPart, minutePart = text[:i], text[i+1:]
+	}
+
+	hour, err := strconv.Atoi(hourPart)
+	if err != nil {
+		return 0, err
+	}
+	minute, err := strconv.Atoi(minutePart)
+	if err != nil {
+		return 0, err
+	}
+
+	if minute < 0 || minute > 59 {
+		return 0, ErrInvalidClock
+	}
+
+	switch suffix {
+	case "":
+		if hour < 0 || hour > 23 {
+			return 0, ErrInvalidClock
+		}
+	default:
+		if hour < 1 || hour > 12 {
+			return 0, ErrInvalidClock
+		}
+		hour %= 12
+		if suffix == "pm" {
+			hour += 12
+		}
+	}
+
+	return hour*60 + minute, nil
+}
@@ -0,0 +1,110 @@
+package events_test
+
+import (
+	"strings"
+	"testing"
+	"tgfsm"
+	"tgfsm/events"
+	"tgfsm/tgfsmtest"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestTimePickerButtons(t *testing.T) {
+	var hour, minute int
+	picker := mustEvent(t)(events.NewTimePickerEvent(
+		events.WithTimePickerMessageTriggers("/time"),
+		events.WithOnTimeSelected(func(h, m int, userID int64) error {
+			hour, minute = h, m
+			return nil
+		}),
+	))
+	server, bot := newTestBot(t, picker)
+
+	tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/time").
+		ExpectMessageContains("12:00").
+		ExpectKeyboard("+1h", "−5m", events.TimePickerDoneText).
+		Press("+1h").
+		ExpectEditContains("13:00").
+		Press("−5m").
+		ExpectEditContains("12:55").
+		Press(events.TimePickerDoneText).
+		ExpectEdit("Selected time: 12:55").
+		ExpectState("home")
+
+	if hour != 12 || minute != 55 {
+		t.Fatalf("selected %02d:%02d, want 12:55", hour, minute)
+	}
+}
+
+func TestTimePickerTypedInput(t *testing.T) {
+	var hour, minute int
+	picker := mustEvent(t)(events.NewTimePickerEvent(
+		events.WithTimePickerMessageTriggers("/time"),
+		events.WithTimePicker12Hour(true),
+		events.WithOnTimeSelected(func(h, m int, userID int64) error {
+			hour, minute = h, m
+			return nil
+		}),
+	))
+	server, bot := newTestBot(t, picker)
+
+	tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/time").
+		ExpectMessageContains("12:00 PM").
+		Send("25:00").
+		ExpectMessage(events.TimePickerInvalidText).
+		Send("7:45 pm").
+		ExpectMessageContains("7:45 PM").
+		ExpectKeyboard(events.TimePickerDoneText).
+		Press(events.TimePickerDoneText).
+		ExpectEdit("Selected time: 7:45 PM").
+		ExpectState("home")
+
+	if hour != 19 || minute != 45 {
+		t.Fatalf("selected %02d:%02d, want 19:45", hour, minute)
+	}
+}
+
+func TestTimePickerCallbackNamespace(t *testing.T) {
+	hostDone := false
+	selected := false
+	picker := mustEvent(t)(events.NewTimePickerEvent(
+		events.WithTimePickerMessageTriggers("/time"),
+		events.WithOnTimeSelected(func(h, m int, userID int64) error {
+			selected = true
+			return nil
+		}),
+	))
+	// A global callback of the bot with the same name as a button of the picker
+	host := map[string]tgfsm.State{
+		"host": {
+			Global: true,
+			CallbackHandlers: map[string]tgfsm.Handler{
+				"done": {Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+					hostDone = true
+					return nil
+				}},
+			},
+		},
+	}
+	server, bot := newTestBot(t, picker, host)
+
+	scenario := tgfsmtest.NewScenario(t, server, bot, testUserID).
+		Send("/time").
+		ExpectKeyboard(events.TimePickerDoneText)
+
+	for _, row := range scenario.Last().InlineKeyboard() {
+		for _, button := range row {
+			if !strings.HasPrefix(*button.CallbackData, "tp:") {
+				t.Fatalf("callback data %q has no event prefix", *button.CallbackData)
+			}
+		}
+	}
+
+	scenario.Press(events.TimePickerDoneText).ExpectState("home")
+	if hostDone || !selected {
+		t.Fatalf("done pressed: host handler %v, picker %v", hostDone, selected)
+	}
+}
@@ -0,0 +1,203 @@
+package tgfsm
+
+import (
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// Default values of FloodControl
+	DefaultFloodRate        = 1                                        // Updates per second allowed from a user
+	DefaultFloodBurst       = 5                                        // Updates allowed at once from a user
+	DefaultFloodBanDuration = 10 * time.Minute                         // Duration of bans made by FloodBan
+	DefaultFloodWarning     = "⚠️ Too many messages, please slow down" // Warning sent by FloodWarn
+
+	// FloodBanReason is the reason of bans made by FloodBan
+	FloodBanReason = "flood"
+)
+
+// FloodAction is the action taken when a user exceeds the flood limit
+type FloodAction int
+
+// Actions on flood
+const (
+	FloodDrop FloodAction = iota // Excess updates are dropped silently
+	FloodWarn                    // Excess updates are dropped, the chat is warned once per flood
+	FloodBan                     // The user (the chat with FloodControl.PerChat) is banned temporarily
+)
+
+// FloodViolation describes an update dropped by the flood limit
+type FloodViolation struct {
+	UserID int64
+	ChatID int64
+	// Key is the user or chat (with FloodControl.PerChat) the limit is counted for
+	Key int64
+	// Dropped is the number of updates dropped in a row including this one, 1 when the flood starts
+	Dropped int
+	Action  FloodAction
+}
+
+// FloodHook is called for every update dropped by the flood limit (see FloodControl.Hook)
+type FloodHook func(b *Bot, update tgbotapi.Update, violation FloodViolation)
+
+// FloodControl limits incoming updates per user or chat with a token bucket (see WithFloodControl)
+// Updates exceeding the limit are dropped before processing; the action is taken
+// once per flood, i.e. until updates are allowed again.
+type FloodControl struct {
+	Rate  float64 // Updates per second, DefaultFloodRate if zero
+	Burst int     // Updates allowed at once, DefaultFloodBurst if zero
+	// PerChat counts updates per chat instead of per user, e.g. for busy groups
+	PerChat bool
+	Action  FloodAction
+	// Warning is sent by FloodWarn, DefaultFloodWarning if empty
+	Warning string
+	// BanDuration is the duration of bans made by FloodBan, DefaultFloodBanDuration if zero
+	BanDuration time.Duration
+	// Hook is called for every dropped update in addition to the action, e.g. with FloodDrop
+	// for custom handling. It runs in its own goroutine.
+	Hook FloodHook
+}
+
+// floodBucket is the token bucket of a user or chat
+type floodBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+	dropped  int // Updates dropped since the bucket allowed the last one
+}
+
+// floodLimiter counts incoming updates for FloodControl
+type floodLimiter struct {
+	control FloodControl
+	// Buckets idle for this long are full again and are evicted
+	idle time.Duration
+
+	mu        sync.Mutex
+	buckets   map[int64]*floodBucket
+	lastSweep time.Time
+}
+
+// newFloodLimiter creates a limiter with the control, filling in default values
+func newFloodLimiter(control FloodControl) *floodLimiter {
+	if control.Rate <= 0 {
+		control.Rate = DefaultFloodRate
+	}
+	if control.Burst <= 0 {
+		control.Burst = DefaultFloodBurst
+	}
+	if control.Warning == "" {
+		control.Warning = DefaultFloodWarning
+	}
+	if control.BanDuration <= 0 {
+		control.BanDuration = DefaultFloodBanDuration
+	}
+
+	idle := time.Duration(float64(control.Burst) / control.Rate * float64(time.Second))
+	if idle < time.Minute {
+		idle = time.Minute
+	}
+
+	return &floodLimiter{
+		control:   control,
+		idle:      idle,
+		buckets:   make(map[int64]*floodBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow takes a token from the bucket of the key
+// Returns the number of updates dropped in a row, 0 if the update is allowed.
+func (f *floodLimiter) allow(key int64, now time.Time) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if now.Sub(f.lastSweep) >= f.idle {
+		f.sweep(now)
+	}
+
+	bucket, ok := f.buckets[key]
+	if !ok {
+		bucket = &floodBucket{limiter: rate.NewLimiter(rate.Limit(f.control.Rate), f.control.Burst)}
+		f.buckets[key] = bucket
+	}
+	bucket.lastSeen = now
+
+	if bucket.limiter.AllowN(now, 1) {
+		bucket.dropped = 0
+		return 0
+	}
+	bucket.dropped++
+	return bucket.dropped
+}
+
+// sweep evicts idle buckets, so memory does not grow with every user ever seen
+func (f *floodLimiter) sweep(now time.Time) {
+	for key, bucket := range f.buckets {
+		if now.Sub(bucket.lastSeen) >= f.idle {
+			delete(f.buckets, key)
+		}
+	}
+	f.lastSweep = now
+}
+
+// checkFlood counts the update against the flood limit, nil if flood control is off
+// Returns the violation and true if the update must be dropped.
+func checkFlood(flood *floodLimiter, update tgbotapi.Update) (FloodViolation, bool) {
+	if flood == nil {
+		return FloodViolation{}, false
+	}
+	user := update.SentFrom()
+	chat := update.FromChat()
+	if user == nil || chat == nil {
+		return FloodViolation{}, false
+	}
+
+	key := user.ID
+	if flood.control.PerChat {
+		key = chat.ID
+	}
+	dropped := flood.allow(key, time.Now())
+	if dropped == 0 {
+		return FloodViolation{}, false
+	}
+
+	return FloodViolation{
+		UserID:  user.ID,
+		ChatID:  chat.ID,
+		Key:     key,
+		Dropped: dropped,
+		Action:  flood.control.Action,
+	}, true
+}
+
+// handleFlood takes the action of FloodControl for the dropped update and calls the hook
+func (b *Bot) handleFlood(update tgbotapi.Update, control FloodControl, violation FloodViolation) {
+	if violation.Dropped == 1 {
+		logger := b.updateLogger(update, "", "")
+		logger.Warn("flood limit exceeded", LogField("key", violation.Key))
+
+		switch control.Action {
+		case FloodWarn:
+			b.waitForMessage(violation.ChatID)
+			if _, err := b.client.Send(tgbotapi.NewMessage(violation.ChatID, control.Warning)); err != nil {
+				logger.Error("failed to send flood warning", ErrorField(err))
+			}
+		case FloodBan:
+			var err error
+			if control.PerChat {
+				err = b.BanChat(violation.ChatID, FloodBanReason, control.BanDuration)
+			} else {
+				err = b.BanUser(violation.UserID, FloodBanReason, control.BanDuration)
+			}
+			if err != nil {
+				logger.Error("failed to ban flooding user", ErrorField(err))
+			}
+		}
+	}
+
+	if control.Hook != nil {
+		control.Hook(b, update, violation)
+	}
+}
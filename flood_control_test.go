@@ -0,0 +1,158 @@
+package tgfsm_test
+
+import (
+	"sync"
+	"testing"
+	"tgfsm"
+	"tgfsm/tgfsmtest"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const floodUserID = 11
+
+// newFloodBot creates a bot answering "ok" in the home state with the flood control
+// allowing two updates at once and refilling slowly
+func newFloodBot(t *testing.T, control tgfsm.FloodControl) (*tgfsmtest.Server, *tgfsm.Bot) {
+	t.Helper()
+
+	server := tgfsmtest.NewServer()
+	t.Cleanup(server.Close)
+
+	control.Rate = 0.001
+	control.Burst = 2
+	states := map[string]tgfsm.State{
+		"home": {CatchAllFunc: &tgfsm.Handler{Handle: reply("ok")}, MessageHandlers: map[string]tgfsm.Handler{}},
+	}
+	bot, err := tgfsmtest.NewBot(server,
+		tgfsm.WithStates(states),
+		tgfsm.WithInitialState("home"),
+		tgfsm.WithFloodControl(control),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, bot
+}
+
+// eventually fails the test if the condition does not hold within a second
+func eventually(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// countTexts returns how many sent messages have the text
+func countTexts(server *tgfsmtest.Server, text string) int {
+	count := 0
+	for _, request := range server.Sent() {
+		if request.Text() == text {
+			count++
+		}
+	}
+	return count
+}
+
+func TestFloodDrop(t *testing.T) {
+	server, bot := newFloodBot(t, tgfsm.FloodControl{Action: tgfsm.FloodDrop})
+
+	for i := 0; i < 4; i++ {
+		bot.ProcessUpdate(tgfsmtest.NewMessageUpdate(floodUserID, "hi"))
+	}
+
+	if sent := len(server.Sent()); sent != 2 {
+		t.Fatalf("%d messages sent, want 2 for the burst", sent)
+	}
+}
+
+func TestFloodWarn(t *testing.T) {
+	server, bot := newFloodBot(t, tgfsm.FloodControl{Action: tgfsm.FloodWarn, Warning: "slow down"})
+
+	for i := 0; i < 5; i++ {
+		bot.ProcessUpdate(tgfsmtest.NewMessageUpdate(floodUserID, "hi"))
+	}
+
+	eventually(t, "the warning", func() bool { return countTexts(server, "slow down") > 0 })
+	time.Sleep(20 * time.Millisecond)
+	if warnings := countTexts(server, "slow down"); warnings != 1 {
+		t.Fatalf("%d warnings sent, want one per flood", warnings)
+	}
+	if answers := countTexts(server, "ok"); answers != 2 {
+		t.Fatalf("%d updates handled, want 2", answers)
+	}
+}
+
+func TestFloodBan(t *testing.T) {
+	const group = -200
+
+	tests := []struct {
+		name    string
+		perChat bool
+		kind    tgfsm.BanKind
+		id      int64
+	}{
+		{name: "user", kind: tgfsm.BanUser, id: floodUserID},
+		{name: "chat", perChat: true, kind: tgfsm.BanChat, id: group},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, bot := newFloodBot(t, tgfsm.FloodControl{Action: tgfsm.FloodBan, PerChat: tt.perChat, BanDuration: time.Hour})
+
+			for i := 0; i < 3; i++ {
+				bot.ProcessUpdate(tgfsmtest.NewChatMessageUpdate(group, floodUserID, "hi"))
+			}
+
+			eventually(t, "the ban", func() bool {
+				_, banned := bot.GetBan(tt.kind, tt.id)
+				return banned
+			})
+			ban, _ := bot.GetBan(tt.kind, tt.id)
+			if ban.Reason != tgfsm.FloodBanReason || ban.Until.IsZero() {
+				t.Fatalf("ban %+v, want a temporary flood ban", ban)
+			}
+		})
+	}
+}
+
+func TestFloodHook(t *testing.T) {
+	var mu sync.Mutex
+	var violations []tgfsm.FloodViolation
+	_, bot := newFloodBot(t, tgfsm.FloodControl{
+		Action: tgfsm.FloodDrop,
+		Hook: func(b *tgfsm.Bot, u tgbotapi.Update, violation tgfsm.FloodViolation) {
+			mu.Lock()
+			defer mu.Unlock()
+			violations = append(violations, violation)
+		},
+	})
+
+	for i := 0; i < 5; i++ {
+		bot.ProcessUpdate(tgfsmtest.NewMessageUpdate(floodUserID, "hi"))
+	}
+
+	eventually(t, "three dropped updates", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(violations) == 3
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	seen := make(map[int]bool)
+	for _, violation := range violations {
+		if violation.UserID != floodUserID || violation.Key != floodUserID {
+			t.Errorf("violation %+v, want the flooding user", violation)
+		}
+		seen[violation.Dropped] = true
+	}
+	if !seen[1] || !seen[2] || !seen[3] {
+		t.Errorf("violations %+v, want dropped counts 1, 2 and 3", violations)
+	}
+}
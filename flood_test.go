@@ -0,0 +1,65 @@
+package tgfsm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFloodLimiterAllow(t *testing.T) {
+	f := newFloodLimiter(FloodControl{Rate: 1, Burst: 2})
+	now := time.Now()
+
+	steps := []struct {
+		after   time.Duration
+		dropped int
+	}{
+		{0, 0},
+		{0, 0},
+		{0, 1}, // The burst is spent
+		{100 * time.Millisecond, 2},
+		{time.Second, 0}, // A token is refilled, the flood is over
+		{time.Second, 1}, // A new flood starts
+	}
+	for i, step := range steps {
+		if dropped := f.allow(1, now.Add(step.after)); dropped != step.dropped {
+			t.Fatalf("step %d: dropped %d, want %d", i, dropped, step.dropped)
+		}
+	}
+
+	// Buckets are counted per key
+	if dropped := f.allow(2, now); dropped != 0 {
+		t.Fatalf("another key dropped %d, want 0", dropped)
+	}
+}
+
+func TestFloodLimiterSweep(t *testing.T) {
+	f := newFloodLimiter(FloodControl{Rate: 1, Burst: 1})
+	now := time.Now()
+	if f.idle != time.Minute {
+		t.Fatalf("idle %s, want at least a minute", f.idle)
+	}
+
+	f.allow(1, now)
+	f.allow(2, now.Add(30*time.Second))
+	f.lastSweep = now
+
+	// The sweep runs once the idle time passed since the last one and evicts only idle buckets
+	f.allow(3, now.Add(time.Minute))
+	if _, ok := f.buckets[1]; ok {
+		t.Error("idle bucket is not evicted")
+	}
+	if _, ok := f.buckets[2]; !ok {
+		t.Error("recent bucket is evicted")
+	}
+	if len(f.buckets) != 2 {
+		t.Errorf("%d buckets, want 2", len(f.buckets))
+	}
+}
+
+func TestNewFloodLimiterDefaults(t *testing.T) {
+	f := newFloodLimiter(FloodControl{})
+	if f.control.Rate != DefaultFloodRate || f.control.Burst != DefaultFloodBurst ||
+		f.control.Warning != DefaultFloodWarning || f.control.BanDuration != DefaultFloodBanDuration {
+		t.Fatalf("control %+v, want the defaults", f.control)
+	}
+}
@@ -1,15 +1,13 @@
 package tgfsm
 
 import (
-	"context"
-
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 func (b *Bot) SendDeleteMessage(msg tgbotapi.DeleteMessageConfig) (*tgbotapi.APIResponse, error) {
-	b.limiter.WaitForAPI(context.Background())
+	b.waitForAPI(msg.ChatID)
 
-	sendedMsg, err := b.BotAPI.Request(msg)
+	sendedMsg, err := b.client.Request(msg)
 	if err != nil {
 		return nil, err
 	}
@@ -19,21 +17,11 @@ func (b *Bot) SendDeleteMessage(msg tgbotapi.DeleteMessageConfig) (*tgbotapi.API
 
 func (b *Bot) SendMessage(msg tgbotapi.MessageConfig) (tgbotapi.Message, error) {
 	// Auto-delete last message if enabled
-	if b.autoDeleteEnabled && b.lastMessageCache != nil {
-		lastMsgInfo, err := b.lastMessageCache.GetLastMessageInfo(msg.ChatID)
-		if err == nil && lastMsgInfo != nil && lastMsgInfo.MessageID > 0 {
-			// Delete only if last message was not important
-			if !lastMsgInfo.Important {
-				// Try to delete last message (ignore errors)
-				deleteMsg := tgbotapi.NewDeleteMessage(msg.ChatID, lastMsgInfo.MessageID)
-				_ = b.DeleteMessage(deleteMsg)
-			}
-		}
-	}
+	b.autoDeleteLast(msg.ChatID)
 
-	b.limiter.WaitForMessage(context.Background(), msg.ChatID)
+	b.waitForMessage(msg.ChatID)
 
-	sendedMsg, err := b.BotAPI.Send(msg)
+	sendedMsg, err := b.client.Send(msg)
 	if err != nil {
 		return sendedMsg, err
 	}
@@ -51,6 +39,31 @@ func (b *Bot) SendMessage(msg tgbotapi.MessageConfig) (tgbotapi.Message, error)
 	return sendedMsg, nil
 }
 
+// autoDeleteLast deletes the last message sent to the chat unless it is important
+// Does nothing if auto-deletion is disabled. Errors are ignored and only reported to metrics.
+func (b *Bot) autoDeleteLast(chatID int64) {
+	if !b.autoDeleteEnabled || b.lastMessageCache == nil {
+		return
+	}
+
+	lastMsgInfo, err := b.lastMessageCache.GetLastMessageInfo(chatID)
+	if err != nil || lastMsgInfo == nil || lastMsgInfo.MessageID <= 0 {
+		return
+	}
+	// Delete only if last message was not important
+	if lastMsgInfo.Important {
+		b.metrics.AutoDeleted(AutoDeleteImportant)
+		return
+	}
+	// Try to delete last message (ignore errors)
+	deleteMsg := tgbotapi.NewDeleteMessage(chatID, lastMsgInfo.MessageID)
+	if err := b.DeleteMessage(deleteMsg); err != nil {
+		b.metrics.AutoDeleted(AutoDeleteFailed)
+		return
+	}
+	b.metrics.AutoDeleted(AutoDeleteDeleted)
+}
+
 // isImportantMessage determines if a message is important
 // Important messages are not automatically deleted
 // A message is considered important if it has ReplyMarkup (keyboard)
@@ -63,21 +76,11 @@ func isImportantMessage(msg tgbotapi.MessageConfig) bool {
 // This method doesn't require ReplyMarkup - message is marked as important explicitly
 func (b *Bot) SendImportantMessage(msg tgbotapi.MessageConfig) (tgbotapi.Message, error) {
 	// Auto-delete last message if enabled
-	if b.autoDeleteEnabled && b.lastMessageCache != nil {
-		lastMsgInfo, err := b.lastMessageCache.GetLastMessageInfo(msg.ChatID)
-		if err == nil && lastMsgInfo != nil && lastMsgInfo.MessageID > 0 {
-			// Delete only if last message was not important
-			if !lastMsgInfo.Important {
-				// Try to delete last message (ignore errors)
-				deleteMsg := tgbotapi.NewDeleteMessage(msg.ChatID, lastMsgInfo.MessageID)
-				_ = b.DeleteMessage(deleteMsg)
-			}
-		}
-	}
+	b.autoDeleteLast(msg.ChatID)
 
-	b.limiter.WaitForMessage(context.Background(), msg.ChatID)
+	b.waitForMessage(msg.ChatID)
 
-	sendedMsg, err := b.BotAPI.Send(msg)
+	sendedMsg, err := b.client.Send(msg)
 	if err != nil {
 		return sendedMsg, err
 	}
@@ -113,7 +116,7 @@ func NewImportantMessage(chatID int64, text string) tgbotapi.MessageConfig {
 }
 
 func (b *Bot) SendPinMessageEvent(messageID int, ChatID int64, disableNotification bool) (*tgbotapi.APIResponse, error) {
-	b.limiter.WaitForAPI(context.Background())
+	b.waitForAPI(ChatID)
 
 	pinConfig := tgbotapi.PinChatMessageConfig{
 		ChatID:              ChatID,
@@ -121,7 +124,7 @@ func (b *Bot) SendPinMessageEvent(messageID int, ChatID int64, disableNotificati
 		DisableNotification: disableNotification,
 	}
 
-	APIResponse, err := b.BotAPI.Request(pinConfig)
+	APIResponse, err := b.client.Request(pinConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -129,11 +132,11 @@ func (b *Bot) SendPinMessageEvent(messageID int, ChatID int64, disableNotificati
 }
 
 func (b *Bot) SendSticker(stickerID string, chatID int64) (*tgbotapi.Message, error) {
-	b.limiter.WaitForMessage(context.Background(), chatID)
+	b.waitForMessage(chatID)
 
 	msg := tgbotapi.NewSticker(chatID, tgbotapi.FileID(stickerID))
 
-	sendedMsg, err := b.BotAPI.Send(msg)
+	sendedMsg, err := b.client.Send(msg)
 	if err != nil {
 		return nil, err
 	}
@@ -141,15 +144,27 @@ func (b *Bot) SendSticker(stickerID string, chatID int64) (*tgbotapi.Message, er
 	return &sendedMsg, nil
 }
 
+// SendPhoto sends a photo, optionally with a caption and keyboard
+func (b *Bot) SendPhoto(photo tgbotapi.PhotoConfig) (tgbotapi.Message, error) {
+	b.waitForMessage(photo.ChatID)
+
+	sendedMsg, err := b.client.Send(photo)
+	if err != nil {
+		return sendedMsg, err
+	}
+
+	return sendedMsg, nil
+}
+
 func (b *Bot) SendUnPinAllMessageEvent(ChannelUsername string, chatID int64) (*tgbotapi.APIResponse, error) {
-	b.limiter.WaitForAPI(context.Background())
+	b.waitForAPI(chatID)
 
 	unpinConfig := tgbotapi.UnpinAllChatMessagesConfig{
 		ChatID:          chatID,
 		ChannelUsername: ChannelUsername,
 	}
 
-	APIresponse, err := b.BotAPI.Request(unpinConfig)
+	APIresponse, err := b.client.Request(unpinConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -158,9 +173,9 @@ func (b *Bot) SendUnPinAllMessageEvent(ChannelUsername string, chatID int64) (*t
 }
 
 func (b *Bot) EditMessage(editMsg tgbotapi.EditMessageTextConfig) (*tgbotapi.APIResponse, error) {
-	b.limiter.WaitForAPI(context.Background())
+	b.waitForAPI(editMsg.ChatID)
 
-	response, err := b.BotAPI.Request(editMsg)
+	response, err := b.client.Request(editMsg)
 	if err != nil {
 		return nil, err
 	}
@@ -169,9 +184,9 @@ func (b *Bot) EditMessage(editMsg tgbotapi.EditMessageTextConfig) (*tgbotapi.API
 }
 
 func (b *Bot) DeleteMessage(deleteMsg tgbotapi.DeleteMessageConfig) error {
-	b.limiter.WaitForAPI(context.Background())
+	b.waitForAPI(deleteMsg.ChatID)
 
-	_, err := b.BotAPI.Request(deleteMsg)
+	_, err := b.client.Request(deleteMsg)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,76 @@
+package tgfsm
+
+import (
+	"slices"
+	"sort"
+)
+
+// Global states precedence
+//
+// For every update global states are checked before the user's current state, one by one:
+//  1. states with a higher Priority are checked first;
+//  2. states with equal Priority are checked in lexicographic order of their readable names
+//     (State.Name, the key for states without one), then of their keys. States of events have
+//     random keys but names derived from their triggers, so their order is stable across restarts;
+//  3. states whose scope excludes the user's current state are skipped (see State.OnlyIn and State.ExcludeIn);
+//  4. the first global state with a handler for the update handles it, the rest and the
+//     user's current state are not checked.
+//
+// The order does not depend on map iteration and is the same after Reload of the same states.
+
+// globalState is a global state prepared for evaluation
+type globalState struct {
+	name  string
+	state *State
+}
+
+// sortGlobalStates returns the global states in evaluation order
+func sortGlobalStates(states map[string]State) []globalState {
+	globalStates := make([]globalState, 0)
+	for name := range states {
+		// Each state is copied into its own variable, so pointers never alias one loop variable
+		state := states[name]
+		if state.Global {
+			globalStates = append(globalStates, globalState{name: name, state: &state})
+		}
+	}
+
+	sort.Slice(globalStates, func(i, j int) bool {
+		a, b := globalStates[i], globalStates[j]
+		if a.state.Priority != b.state.Priority {
+			return a.state.Priority > b.state.Priority
+		}
+		if a.label() != b.label() {
+			return a.label() < b.label()
+		}
+		return a.name < b.name
+	})
+	return globalStates
+}
+
+// label returns the name the state is ordered by: State.Name or the key
+func (g globalState) label() string {
+	if g.state.Name != "" {
+		return g.state.Name
+	}
+	return g.name
+}
+
+// activeIn reports whether the global state handles updates of a user in the current state
+// current is empty if the user has no state
+func (g globalState) activeIn(current string) bool {
+	if g.state.OnlyIn != nil && !slices.Contains(g.state.OnlyIn, current) {
+		return false
+	}
+	return !slices.Contains(g.state.ExcludeIn, current)
+}
+
+// GlobalStates returns the names of global states in evaluation order
+func (app *Bot) GlobalStates() []string {
+	globalStates := app.stateSet().globalStates
+	names := make([]string, 0, len(globalStates))
+	for _, g := range globalStates {
+		names = append(names, g.name)
+	}
+	return names
+}
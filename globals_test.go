@@ -0,0 +1,111 @@
+package tgfsm
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSortGlobalStates(t *testing.T) {
+	tests := []struct {
+		name   string
+		states map[string]State
+		want   []string
+	}{
+		{
+			name: "local states are skipped",
+			states: map[string]State{
+				"menu":  {},
+				"start": {Global: true},
+			},
+			want: []string{"start"},
+		},
+		{
+			name: "higher priority first",
+			states: map[string]State{
+				"a": {Global: true, Priority: -1},
+				"b": {Global: true},
+				"c": {Global: true, Priority: 10},
+			},
+			want: []string{"c", "b", "a"},
+		},
+		{
+			name: "equal priority by key",
+			states: map[string]State{
+				"help":   {Global: true},
+				"cancel": {Global: true},
+				"start":  {Global: true},
+			},
+			want: []string{"cancel", "help", "start"},
+		},
+		{
+			name: "equal priority by name before key",
+			states: map[string]State{
+				// Keys of event states are random, names are stable
+				"f3c1": {Global: true, Name: "TimePicker (/time): вход"},
+				"0a9e": {Global: true, Name: "Checklist (/tasks): вход"},
+				"menu": {Global: true},
+			},
+			want: []string{"0a9e", "f3c1", "menu"},
+		},
+		{
+			name: "equal names by key",
+			states: map[string]State{
+				"b": {Global: true, Name: "same"},
+				"a": {Global: true, Name: "same"},
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "priority before name",
+			states: map[string]State{
+				"a": {Global: true, Name: "A"},
+				"z": {Global: true, Name: "Z", Priority: 1},
+			},
+			want: []string{"z", "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The order must not depend on map iteration
+			for i := 0; i < 20; i++ {
+				var got []string
+				for _, g := range sortGlobalStates(tt.states) {
+					got = append(got, g.name)
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Fatalf("order %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGlobalStateActiveIn(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   State
+		current string
+		want    bool
+	}{
+		{name: "no scope", state: State{}, current: "menu", want: true},
+		{name: "no scope without state", state: State{}, current: "", want: true},
+		{name: "only in listed", state: State{OnlyIn: []string{"menu"}}, current: "menu", want: true},
+		{name: "only in other", state: State{OnlyIn: []string{"menu"}}, current: "form", want: false},
+		{name: "only in without state", state: State{OnlyIn: []string{"menu"}}, current: "", want: false},
+		{name: "only in no state", state: State{OnlyIn: []string{""}}, current: "", want: true},
+		{name: "empty only in", state: State{OnlyIn: []string{}}, current: "menu", want: false},
+		{name: "exclude listed", state: State{ExcludeIn: []string{"form"}}, current: "form", want: false},
+		{name: "exclude other", state: State{ExcludeIn: []string{"form"}}, current: "menu", want: true},
+		{name: "exclude wins", state: State{OnlyIn: []string{"form"}, ExcludeIn: []string{"form"}}, current: "form", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := tt.state
+			if got := (globalState{name: "global", state: &state}).activeIn(tt.current); got != tt.want {
+				t.Fatalf("activeIn(%q) = %v, want %v", tt.current, got, tt.want)
+			}
+		})
+	}
+}
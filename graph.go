@@ -0,0 +1,208 @@
+package tgfsm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Graph is a description of the bot's states and transitions used for diagrams
+// Graph can be serialized to JSON and rendered later (see cmd/tgfsm-graph)
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a state in the graph
+type GraphNode struct {
+	// ID is the name of the state in the states map
+	ID string `json:"id"`
+	// Label is a human-readable name of the state (State.Name), ID if not set
+	Label string `json:"label"`
+	// Global is true for global states
+	Global bool `json:"global,omitempty"`
+	// Initial is true for the initial state (see WithInitialState)
+	Initial bool `json:"initial,omitempty"`
+	// MessageTriggers are the keys of State.MessageHandlers
+	MessageTriggers []string `json:"message_triggers,omitempty"`
+	// CallbackTriggers are the keys of State.CallbackHandlers
+	CallbackTriggers []string `json:"callback_triggers,omitempty"`
+}
+
+// GraphEdge is a declared transition in the graph (see State.Transitions)
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Guarded is true if the transition has a guard (see State.Guards)
+	Guarded bool `json:"guarded,omitempty"`
+}
+
+// NewGraph builds the graph of the states
+// Nodes and edges are sorted, so the same states always produce the same graph
+func NewGraph(states map[string]State, initialState string) Graph {
+	names := make([]string, 0, len(states))
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	graph := Graph{
+		Nodes: make([]GraphNode, 0, len(names)),
+	}
+	for _, name := range names {
+		state := states[name]
+
+		label := state.Name
+		if label == "" {
+			label = name
+		}
+		graph.Nodes = append(graph.Nodes, GraphNode{
+			ID:               name,
+			Label:            label,
+			Global:           state.Global,
+			Initial:          name == initialState,
+			MessageTriggers:  sortedKeys(state.MessageHandlers),
+			CallbackTriggers: sortedKeys(state.CallbackHandlers),
+		})
+
+		for _, target := range state.Transitions {
+			graph.Edges = append(graph.Edges, GraphEdge{
+				From:    name,
+				To:      target,
+				Guarded: state.Guards[target] != nil,
+			})
+		}
+	}
+
+	return graph
+}
+
+// Graph returns the graph of the bot's states
+func (app *Bot) Graph() Graph {
+	set := app.stateSet()
+	return NewGraph(set.states, set.initialState)
+}
+
+// DOT renders the graph in Graphviz DOT format
+// Global states are drawn as dashed ellipses, the initial state with a double border,
+// guarded transitions with dashed arrows.
+func (g Graph) DOT() string {
+	var sb strings.Builder
+
+	sb.WriteString("digraph tgfsm {\n")
+	sb.WriteString("\trankdir=LR;\n")
+	sb.WriteString("\tnode [shape=box];\n")
+
+	for _, node := range g.Nodes {
+		attrs := []string{"label=" + dotQuote(nodeText(node, "\n"))}
+		if node.Global {
+			attrs = append(attrs, "shape=ellipse", "style=dashed")
+		}
+		if node.Initial {
+			attrs = append(attrs, "peripheries=2")
+		}
+		fmt.Fprintf(&sb, "\t%s [%s];\n", dotQuote(node.ID), strings.Join(attrs, ", "))
+	}
+
+	for _, edge := range g.Edges {
+		attrs := ""
+		if edge.Guarded {
+			attrs = " [style=dashed, label=\"guard\"]"
+		}
+		fmt.Fprintf(&sb, "\t%s -> %s%s;\n", dotQuote(edge.From), dotQuote(edge.To), attrs)
+	}
+
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart
+// Global states are drawn as stadiums, the initial state as a double circle,
+// guarded transitions with dotted arrows.
+func (g Graph) Mermaid() string {
+	var sb strings.Builder
+
+	sb.WriteString("flowchart LR\n")
+
+	// State names may contain any characters, so nodes get positional identifiers
+	ids := make(map[string]string, len(g.Nodes))
+	for i, node := range g.Nodes {
+		id := fmt.Sprintf("s%d", i)
+		ids[node.ID] = id
+
+		text := mermaidQuote(nodeText(node, "<br/>"))
+		switch {
+		case node.Initial:
+			fmt.Fprintf(&sb, "\t%s(((%s)))\n", id, text)
+		case node.Global:
+			fmt.Fprintf(&sb, "\t%s([%s])\n", id, text)
+		default:
+			fmt.Fprintf(&sb, "\t%s[%s]\n", id, text)
+		}
+	}
+
+	for _, edge := range g.Edges {
+		from, to := ids[edge.From], ids[edge.To]
+		// Transitions to unknown states are still shown (see ValidateStates)
+		if from == "" {
+			from = "missing_" + mermaidID(edge.From)
+		}
+		if to == "" {
+			to = "missing_" + mermaidID(edge.To)
+		}
+
+		arrow := "-->"
+		if edge.Guarded {
+			arrow = "-. guard .->"
+		}
+		fmt.Fprintf(&sb, "\t%s %s %s\n", from, arrow, to)
+	}
+
+	return sb.String()
+}
+
+// nodeText returns the label of the node followed by its triggers, one per line
+func nodeText(node GraphNode, newline string) string {
+	lines := []string{node.Label}
+	lines = append(lines, node.MessageTriggers...)
+	for _, trigger := range node.CallbackTriggers {
+		lines = append(lines, "callback: "+trigger)
+	}
+	return strings.Join(lines, newline)
+}
+
+// dotQuote quotes a string for DOT
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// mermaidQuote quotes a node label for Mermaid
+// Line breaks in names are drawn like the breaks between triggers.
+func mermaidQuote(s string) string {
+	s = strings.ReplaceAll(s, `"`, "#quot;")
+	s = strings.ReplaceAll(s, "\n", "<br/>")
+	return `"` + s + `"`
+}
+
+// mermaidID turns a state name into a Mermaid identifier
+func mermaidID(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// sortedKeys returns the sorted keys of the handlers map
+func sortedKeys(handlers map[string]Handler) []string {
+	keys := make([]string, 0, len(handlers))
+	for key := range handlers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
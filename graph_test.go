@@ -0,0 +1,90 @@
+package tgfsm_test
+
+import (
+	"testing"
+	"tgfsm"
+)
+
+// graphStates returns states covering the drawing cases of Graph:
+// a name with quotes and a label with a line break, a global state, states like the ones
+// generated by events (a key without meaning and a readable Name), guarded transitions
+// and a transition to a missing state
+func graphStates() map[string]tgfsm.State {
+	guard := func(*tgfsm.Bot, tgfsm.Transition) error { return nil }
+	handlers := func(triggers ...string) map[string]tgfsm.Handler {
+		handlers := make(map[string]tgfsm.Handler)
+		for _, trigger := range triggers {
+			handlers[trigger] = tgfsm.Handler{}
+		}
+		return handlers
+	}
+
+	return map[string]tgfsm.State{
+		"home": {
+			Transitions:     []string{`say "hi"`, "gone away"},
+			MessageHandlers: handlers("/start", "/about"),
+		},
+		`say "hi"`: {
+			Name:             "Greeting \"hi\"\nwith \\ backslash",
+			Transitions:      []string{"home"},
+			Guards:           map[string]tgfsm.GuardFunc{"home": guard},
+			CallbackHandlers: handlers("ok"),
+		},
+		"help": {
+			Name:            "Help",
+			Global:          true,
+			MessageHandlers: handlers("/help"),
+		},
+		"7c9e6679-7425-40de-944b-e07fc1f90ae7": {
+			Name:            "EnterData (/feedback)",
+			MessageHandlers: handlers("confirm"),
+			Transitions:     []string{"home"},
+			Guards:          map[string]tgfsm.GuardFunc{"home": guard},
+		},
+		"f47ac10b-58cc-4372-a567-0e02b2c3d479": {
+			Name:            "EnterData (/feedback): вход",
+			Global:          true,
+			MessageHandlers: handlers("/feedback"),
+			Transitions:     []string{"7c9e6679-7425-40de-944b-e07fc1f90ae7"},
+		},
+	}
+}
+
+func TestGraphDOT(t *testing.T) {
+	const golden = `digraph tgfsm {
+	rankdir=LR;
+	node [shape=box];
+	"7c9e6679-7425-40de-944b-e07fc1f90ae7" [label="EnterData (/feedback)\nconfirm"];
+	"f47ac10b-58cc-4372-a567-0e02b2c3d479" [label="EnterData (/feedback): вход\n/feedback", shape=ellipse, style=dashed];
+	"help" [label="Help\n/help", shape=ellipse, style=dashed];
+	"home" [label="home\n/about\n/start", peripheries=2];
+	"say \"hi\"" [label="Greeting \"hi\"\nwith \\ backslash\ncallback: ok"];
+	"7c9e6679-7425-40de-944b-e07fc1f90ae7" -> "home" [style=dashed, label="guard"];
+	"f47ac10b-58cc-4372-a567-0e02b2c3d479" -> "7c9e6679-7425-40de-944b-e07fc1f90ae7";
+	"home" -> "say \"hi\"";
+	"home" -> "gone away";
+	"say \"hi\"" -> "home" [style=dashed, label="guard"];
+}
+`
+	if got := tgfsm.NewGraph(graphStates(), "home").DOT(); got != golden {
+		t.Fatalf("output differs from golden:\n--- got\n%s--- want\n%s", got, golden)
+	}
+}
+
+func TestGraphMermaid(t *testing.T) {
+	const golden = `flowchart LR
+	s0["EnterData (/feedback)<br/>confirm"]
+	s1(["EnterData (/feedback): вход<br/>/feedback"])
+	s2(["Help<br/>/help"])
+	s3((("home<br/>/about<br/>/start")))
+	s4["Greeting #quot;hi#quot;<br/>with \ backslash<br/>callback: ok"]
+	s0 -. guard .-> s3
+	s1 --> s0
+	s3 --> s4
+	s3 --> missing_gone_away
+	s4 -. guard .-> s3
+`
+	if got := tgfsm.NewGraph(graphStates(), "home").Mermaid(); got != golden {
+		t.Fatalf("output differs from golden:\n--- got\n%s--- want\n%s", got, golden)
+	}
+}
@@ -0,0 +1,140 @@
+package tgfsm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Health is the state of update processing (see Bot.Health)
+// The package tgfsm/admin serves it as liveness and readiness probes.
+type Health struct {
+	// Polling is true while updates polling is started by Start or HandleUpdates
+	Polling bool `json:"polling"`
+	// PollingAlive is true while the polling loop runs
+	PollingAlive bool `json:"polling_alive"`
+	// PollingSince is the time the polling loop started, zero if it is not running
+	PollingSince time.Time `json:"polling_since"`
+	// LastPoll is the time of the last successful getUpdates call, zero if there was none
+	LastPoll time.Time `json:"last_poll"`
+	// LastPollError is the error of the last getUpdates call, empty if it succeeded
+	LastPollError string `json:"last_poll_error,omitempty"`
+	// QueueDepth is the number of received updates waiting to be dispatched
+	QueueDepth int `json:"queue_depth"`
+	// InFlight is the number of updates being processed by handlers
+	InFlight int `json:"in_flight"`
+}
+
+// pollHealth is the state of the polling loop
+type pollHealth struct {
+	mu        sync.Mutex
+	alive     bool
+	since     time.Time
+	lastPoll  time.Time
+	lastError string
+	queue     chan tgbotapi.Update // Updates received by the running loop
+}
+
+// Health returns the state of update processing
+func (b *Bot) Health() Health {
+	b.pollMu.Lock()
+	polling := b.stopPolling != nil
+	b.pollMu.Unlock()
+
+	b.pollHealth.mu.Lock()
+	defer b.pollHealth.mu.Unlock()
+
+	health := Health{
+		Polling:       polling,
+		PollingAlive:  b.pollHealth.alive,
+		PollingSince:  b.pollHealth.since,
+		LastPoll:      b.pollHealth.lastPoll,
+		LastPollError: b.pollHealth.lastError,
+		InFlight:      int(b.inFlight.Load()),
+	}
+	if b.pollHealth.queue != nil {
+		health.QueueDepth = len(b.pollHealth.queue)
+	}
+	return health
+}
+
+// pollStarted records the start of the polling loop
+func (b *Bot) pollStarted(queue chan tgbotapi.Update) {
+	b.pollHealth.mu.Lock()
+	defer b.pollHealth.mu.Unlock()
+
+	b.pollHealth.alive = true
+	b.pollHealth.since = time.Now()
+	b.pollHealth.lastError = ""
+	b.pollHealth.queue = queue
+}
+
+// pollStopped records the end of the polling loop receiving updates to the queue
+func (b *Bot) pollStopped(queue chan tgbotapi.Update) {
+	b.pollHealth.mu.Lock()
+	defer b.pollHealth.mu.Unlock()
+
+	// A loop started by a restart may already have replaced the stopped one
+	if b.pollHealth.queue != queue {
+		return
+	}
+	b.pollHealth.alive = false
+	b.pollHealth.since = time.Time{}
+	b.pollHealth.queue = nil
+}
+
+// pollDone records the result of a getUpdates call
+func (b *Bot) pollDone(err error) {
+	b.pollHealth.mu.Lock()
+	defer b.pollHealth.mu.Unlock()
+
+	if err != nil {
+		b.pollHealth.lastError = err.Error()
+		return
+	}
+	b.pollHealth.lastPoll = time.Now()
+	b.pollHealth.lastError = ""
+}
+
+// Pinger is implemented by storages that can check their availability,
+// e.g. the Redis and Postgres caches of the package tgfsm/cache
+type Pinger interface {
+	// Ping returns an error if the storage is unreachable
+	Ping(ctx context.Context) error
+}
+
+// CheckStorage checks that the storages of the bot are reachable
+// User states are kept in memory; the last messages cache (see WithLastMessageCache)
+// and the blacklist store (see WithBlacklistStore) are checked if they implement Pinger.
+func (b *Bot) CheckStorage(ctx context.Context) error {
+	for _, storage := range []interface{}{b.lastMessageCache, b.blacklistStore} {
+		if pinger, ok := storage.(Pinger); ok {
+			if err := pinger.Ping(ctx); err != nil {
+				return NewSFMError(ErrStorageUnavailable, err)
+			}
+		}
+	}
+	return nil
+}
+
+// WebhookInfo returns the webhook status of the bot, respecting the rate limiter
+// A bot receiving updates by polling has no webhook URL.
+func (b *Bot) WebhookInfo() (tgbotapi.WebhookInfo, error) {
+	return b.WebhookInfoContext(context.Background())
+}
+
+// WebhookInfoContext is WebhookInfo that stops waiting for the rate limiter when ctx is done
+// The request itself is not cancelled: the client does not take a context.
+func (b *Bot) WebhookInfoContext(ctx context.Context) (tgbotapi.WebhookInfo, error) {
+	if err := b.waitForAPIContext(ctx, 0); err != nil {
+		return tgbotapi.WebhookInfo{}, err
+	}
+
+	info, err := b.client.GetWebhookInfo()
+	if err != nil {
+		return tgbotapi.WebhookInfo{}, err
+	}
+	return info, nil
+}
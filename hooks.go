@@ -0,0 +1,146 @@
+package tgfsm
+
+import (
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Transition describes a change of the user's state
+type Transition struct {
+	// UserID is the ID of the user whose state changes
+	UserID int64
+	// From is the state the user leaves, empty if the user had no state
+	From string
+	// To is the state the user enters, empty if the user is left without a state.
+	// Empty for OnTimeout: the target is known only after the hook returns.
+	To string
+	// Update is the update that caused the transition, nil if there is none
+	// (e.g. the state was changed from a background job or by timeout)
+	Update *tgbotapi.Update
+}
+
+// HookFunc is a function type for state lifecycle hooks
+type HookFunc func(b *Bot, t Transition) error
+
+// stateTimer is a scheduled timeout of the user's current state
+type stateTimer struct {
+	timer *time.Timer
+	seq   uint64
+}
+
+// storeUserState saves the user's state in storage and reschedules the state timeout.
+// An empty state clears the user's state.
+// Returns the transition to pass to runTransitionHooks.
+func (app *Bot) storeUserState(userId int64, state string, update *tgbotapi.Update) Transition {
+	from, err := app.GetUserState(userId)
+	if err != nil {
+		from = ""
+	}
+
+	set := app.stateSet()
+	key := strconv.FormatInt(userId, 10)
+	to := state
+	if state == "" {
+		app.cache.Delete(key)
+		to = set.initialState
+	} else {
+		// The state must outlive its own TTL, otherwise the timeout hook would never see it
+		expiration := app.expiration
+		if ttl := set.states[state].TTL; ttl > expiration {
+			expiration = ttl
+		}
+		app.cache.Set(key, state, expiration)
+	}
+
+	app.scheduleStateTimeout(userId, state)
+
+	return Transition{
+		UserID: userId,
+		From:   from,
+		To:     to,
+		Update: update,
+	}
+}
+
+// runTransitionHooks calls OnExit of the left state and OnEnter of the entered state.
+// Nothing is called if the state did not change.
+func (app *Bot) runTransitionHooks(t Transition) {
+	if t.From == t.To {
+		return
+	}
+
+	set := app.stateSet()
+	if state, ok := set.states[t.From]; ok && state.OnExit != nil {
+		if err := state.OnExit(app, t); err != nil {
+			app.Logger().Error("failed to handle exit hook", LogField("state", t.From), ErrorField(err))
+		}
+	}
+
+	if state, ok := set.states[t.To]; ok && state.OnEnter != nil {
+		if err := state.OnEnter(app, t); err != nil {
+			app.Logger().Error("failed to handle enter hook", LogField("state", t.To), ErrorField(err))
+		}
+	}
+}
+
+// scheduleStateTimeout stops the user's previous state timer and starts a new one
+// if the state has TTL
+func (app *Bot) scheduleStateTimeout(userId int64, state string) {
+	app.timersMu.Lock()
+	defer app.timersMu.Unlock()
+
+	if current, ok := app.stateTimers[userId]; ok {
+		current.timer.Stop()
+		delete(app.stateTimers, userId)
+	}
+
+	if state == "" {
+		return
+	}
+	ttl := app.stateSet().states[state].TTL
+	if ttl <= 0 {
+		return
+	}
+
+	app.timerSeq++
+	seq := app.timerSeq
+	app.stateTimers[userId] = &stateTimer{
+		timer: time.AfterFunc(ttl, func() {
+			app.handleStateTimeout(userId, state, seq)
+		}),
+		seq: seq,
+	}
+}
+
+// handleStateTimeout calls OnTimeout of the expired state and returns the user
+// to the previous state (see PopUserState)
+func (app *Bot) handleStateTimeout(userId int64, state string, seq uint64) {
+	// The timer may fire right after it was replaced by a newer one
+	app.timersMu.Lock()
+	current, ok := app.stateTimers[userId]
+	if !ok || current.seq != seq {
+		app.timersMu.Unlock()
+		return
+	}
+	delete(app.stateTimers, userId)
+	app.timersMu.Unlock()
+
+	if userState, err := app.GetUserState(userId); err != nil || userState != state {
+		return
+	}
+
+	if hook := app.stateSet().states[state].OnTimeout; hook != nil {
+		if err := hook(app, Transition{UserID: userId, From: state}); err != nil {
+			app.Logger().Error("failed to handle timeout hook", LogField("state", state), ErrorField(err))
+		}
+	}
+
+	// The hook may have moved the user to another state itself
+	if userState, err := app.GetUserState(userId); err == nil && userState == state {
+		if _, err := app.PopUserState(userId); err != nil {
+			app.Logger().Error("failed to leave expired state", LogField("state", state), ErrorField(err))
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package tgfsm
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// nopClient is a client for tests that make no Bot API calls
+type nopClient struct {
+	Client
+}
+
+// hookLog records the calls of lifecycle hooks
+type hookLog struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+// hook returns a hook recording its kind and the transition, e.g. "exit menu->order"
+func (l *hookLog) hook(kind string) HookFunc {
+	return func(b *Bot, t Transition) error {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.calls = append(l.calls, fmt.Sprintf("%s %s->%s", kind, t.From, t.To))
+		return nil
+	}
+}
+
+// take returns the recorded calls and clears them
+func (l *hookLog) take() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	calls := l.calls
+	l.calls = nil
+	return calls
+}
+
+// newHooksBot creates a bot with the states, the initial state is menu
+func newHooksBot(t *testing.T, states map[string]State) *Bot {
+	t.Helper()
+
+	b, err := NewBot("test", WithClient(nopClient{}), WithLogger(zap.NewNop()), WithStates(states), WithInitialState("menu"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// waitForState fails the test if the user does not reach the state within a second
+func waitForState(t *testing.T, b *Bot, userID int64, state string) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		current, _ := b.GetUserState(userID)
+		if current == state {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("state %q, want %q", current, state)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestTransitionHooks(t *testing.T) {
+	log := &hookLog{}
+	states := map[string]State{
+		"menu":  {OnEnter: log.hook("enter"), OnExit: log.hook("exit")},
+		"order": {OnEnter: log.hook("enter"), OnExit: log.hook("exit")},
+	}
+	b := newHooksBot(t, states)
+
+	steps := []struct {
+		name  string
+		move  func() error
+		calls []string
+	}{
+		{"set", func() error { return b.SetUserState(1, "order") }, []string{"exit menu->order", "enter menu->order"}},
+		{"same state", func() error { return b.SetUserState(1, "order") }, nil},
+		{"push", func() error { return b.PushUserState(1, "menu") }, []string{"exit order->menu", "enter order->menu"}},
+		{"pop", func() error { _, err := b.PopUserState(1); return err }, []string{"exit menu->order", "enter menu->order"}},
+		{"reset", func() error { b.ResetUserState(1); return nil }, []string{"exit order->menu", "enter order->menu"}},
+	}
+
+	// OnExit of the left state runs before OnEnter of the entered state, each once
+	for _, step := range steps {
+		if err := step.move(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if calls := log.take(); !slices.Equal(calls, step.calls) {
+			t.Fatalf("%s: hooks %q, want %q", step.name, calls, step.calls)
+		}
+	}
+}
+
+func TestStateTimeout(t *testing.T) {
+	log := &hookLog{}
+	states := map[string]State{
+		"menu": {},
+		"quiz": {TTL: 20 * time.Millisecond, OnTimeout: log.hook("timeout")},
+	}
+	b := newHooksBot(t, states)
+
+	if err := b.SetUserState(1, "menu"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.PushUserState(1, "quiz"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The user returns to the state remembered by PushUserState
+	waitForState(t, b, 1, "menu")
+	if calls := log.take(); !slices.Equal(calls, []string{"timeout quiz->"}) {
+		t.Fatalf("hooks %q, want one timeout of quiz", calls)
+	}
+}
+
+func TestStateTimeoutHookMovesUser(t *testing.T) {
+	states := map[string]State{
+		"menu": {},
+		"done": {},
+	}
+	states["quiz"] = State{
+		TTL: 20 * time.Millisecond,
+		OnTimeout: func(b *Bot, t Transition) error {
+			return b.SetUserState(t.UserID, "done")
+		},
+	}
+	b := newHooksBot(t, states)
+
+	if err := b.SetUserState(1, "menu"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.PushUserState(1, "quiz"); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForState(t, b, 1, "done")
+	// The state is not popped after the hook moved the user
+	time.Sleep(20 * time.Millisecond)
+	waitForState(t, b, 1, "done")
+}
+
+func TestStaleStateTimer(t *testing.T) {
+	log := &hookLog{}
+	states := map[string]State{
+		"menu": {},
+		"quiz": {TTL: time.Hour, OnTimeout: log.hook("timeout")},
+	}
+	b := newHooksBot(t, states)
+
+	if err := b.SetUserState(1, "quiz"); err != nil {
+		t.Fatal(err)
+	}
+	b.timersMu.Lock()
+	stale := b.stateTimers[1].seq
+	b.timersMu.Unlock()
+
+	// Re-entering the state replaces its timer
+	if err := b.SetUserState(1, "menu"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetUserState(1, "quiz"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The replaced timer fires anyway, e.g. it was already running when stopped
+	b.handleStateTimeout(1, "quiz", stale)
+
+	if calls := log.take(); len(calls) != 0 {
+		t.Fatalf("hooks %q, want none for a stale timer", calls)
+	}
+	waitForState(t, b, 1, "quiz")
+
+	b.timersMu.Lock()
+	current, ok := b.stateTimers[1]
+	b.timersMu.Unlock()
+	if !ok || current.seq == stale {
+		t.Fatal("the timer of the re-entered state was removed by the stale one")
+	}
+	current.timer.Stop()
+}
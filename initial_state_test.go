@@ -0,0 +1,95 @@
+package tgfsm_test
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"tgfsm"
+	"tgfsm/tgfsmtest"
+)
+
+const initialUserID = 9
+
+// newInitialStateBot creates a bot with the states home and settings answering /start
+// and the global state help answering /help
+func newInitialStateBot(t *testing.T, options ...tgfsm.Option) (*tgfsmtest.Server, *tgfsm.Bot) {
+	t.Helper()
+
+	server := tgfsmtest.NewServer()
+	t.Cleanup(server.Close)
+
+	states := map[string]tgfsm.State{
+		"home":     {MessageHandlers: map[string]tgfsm.Handler{"/start": {Handle: reply("home")}}},
+		"settings": {MessageHandlers: map[string]tgfsm.Handler{"/start": {Handle: reply("settings")}}},
+		"help":     {Global: true, MessageHandlers: map[string]tgfsm.Handler{"/help": {Handle: reply("help")}}},
+	}
+	bot, err := tgfsmtest.NewBot(server, append([]tgfsm.Option{tgfsm.WithStates(states)}, options...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, bot
+}
+
+func TestResetUserState(t *testing.T) {
+	_, bot := newInitialStateBot(t, tgfsm.WithInitialState("home"))
+
+	if err := bot.PushUserState(initialUserID, "settings"); err != nil {
+		t.Fatal(err)
+	}
+	bot.ResetUserState(initialUserID)
+
+	if _, ok := bot.GetCache().Get(strconv.Itoa(initialUserID)); ok {
+		t.Error("state is kept in storage")
+	}
+	if stack := bot.GetUserStateStack(initialUserID); len(stack) != 0 {
+		t.Errorf("history %q, want none", stack)
+	}
+	if state, err := bot.GetUserState(initialUserID); err != nil || state != "home" {
+		t.Errorf("state %q (%v), want the initial state", state, err)
+	}
+}
+
+func TestInitialStateRouting(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []tgfsm.Option
+		sent    []string
+	}{
+		{name: "initial state", options: []tgfsm.Option{tgfsm.WithInitialState("home")}, sent: []string{"home", "help"}},
+		{name: "no initial state", sent: []string{"help"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, bot := newInitialStateBot(t, tt.options...)
+
+			// Users without a state get the handlers of the initial state and global states only
+			bot.ProcessUpdate(tgfsmtest.NewMessageUpdate(initialUserID, "/start"))
+			bot.ProcessUpdate(tgfsmtest.NewMessageUpdate(initialUserID, "/help"))
+
+			if texts := sentTexts(server); !reflect.DeepEqual(texts, tt.sent) {
+				t.Fatalf("sent %q, want %q", texts, tt.sent)
+			}
+		})
+	}
+}
+
+func TestUnknownInitialState(t *testing.T) {
+	server := tgfsmtest.NewServer()
+	t.Cleanup(server.Close)
+	states := map[string]tgfsm.State{"home": {}}
+
+	_, err := tgfsmtest.NewBot(server, tgfsm.WithStates(states), tgfsm.WithInitialState("missing"))
+	if !errors.Is(err, tgfsm.ErrInitialStateNotFound) {
+		t.Fatalf("error %v, want ErrInitialStateNotFound", err)
+	}
+
+	bot, err := tgfsmtest.NewBot(server, tgfsm.WithStates(states))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.UpdateBot(tgfsm.WithInitialState("missing")); !errors.Is(err, tgfsm.ErrInitialStateNotFound) {
+		t.Fatalf("update error %v, want ErrInitialStateNotFound", err)
+	}
+}
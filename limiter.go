@@ -11,12 +11,18 @@ import (
 const (
 	// Telegram API limits
 	GlobalMessageLimit = 30 // Maximum messages per second to all chats
-	ChatMessageLimit   = 1  // Maximum messages per second to one chat
+	ChatMessageLimit   = 1  // Maximum messages per second to one private chat
+	GroupMessageLimit  = 20 // Maximum messages per minute to one group or channel
 	APILimit           = 30 // Maximum API requests per second
 	BurstSize          = 5  // Burst allowance for rate limiter
+
+	// DefaultChatLimiterIdle is the time after which limiters of idle chats are evicted
+	DefaultChatLimiterIdle = 10 * time.Minute
 )
 
 // Limiter manages rate limiting for Telegram API requests
+// Messages to chats with negative IDs (groups, supergroups and channels) are limited
+// by the group chat limit, messages to other chats by the private chat limit.
 type Limiter struct {
 	mu sync.RWMutex
 
@@ -24,18 +30,110 @@ type Limiter struct {
 	globalLimiter *rate.Limiter
 
 	// Per-chat rate limiters
-	chatLimiters map[int64]*rate.Limiter
+	chatLimiters map[int64]*chatLimiter
 
 	// API rate limiter
 	apiLimiter *rate.Limiter
+
+	// Rate and burst of per-chat limiters of private chats
+	chatLimit rate.Limit
+	chatBurst int
+
+	// Rate and burst of per-chat limiters of groups and channels
+	groupLimit rate.Limit
+	groupBurst int
+
+	// Limiters of chats idle for this long are evicted
+	idleTimeout time.Duration
+	lastSweep   time.Time
+}
+
+// chatLimiter is the rate limiter of a chat
+type chatLimiter struct {
+	*rate.Limiter
+	lastUsed time.Time
+}
+
+// LimiterOption configures a Limiter (see NewLimiter)
+type LimiterOption func(*Limiter)
+
+// LimiterMessageLimit sets the limit of messages to all chats: n messages per period with the burst
+// A non-positive n removes the limit, the burst is at least 1. The same applies to the options below.
+func LimiterMessageLimit(n int, per time.Duration, burst int) LimiterOption {
+	return func(l *Limiter) {
+		l.globalLimiter = rate.NewLimiter(limitOf(n, per), max(burst, 1))
+	}
+}
+
+// LimiterAPIRequestLimit sets the limit of API requests: n requests per period with the burst
+func LimiterAPIRequestLimit(n int, per time.Duration, burst int) LimiterOption {
+	return func(l *Limiter) {
+		l.apiLimiter = rate.NewLimiter(limitOf(n, per), max(burst, 1))
+	}
+}
+
+// LimiterPrivateChatLimit sets the limit of messages to one private chat: n messages per period with the burst
+func LimiterPrivateChatLimit(n int, per time.Duration, burst int) LimiterOption {
+	return func(l *Limiter) {
+		l.chatLimit = limitOf(n, per)
+		l.chatBurst = max(burst, 1)
+	}
+}
+
+// LimiterGroupChatLimit sets the limit of messages to one group or channel: n messages per period with the burst
+func LimiterGroupChatLimit(n int, per time.Duration, burst int) LimiterOption {
+	return func(l *Limiter) {
+		l.groupLimit = limitOf(n, per)
+		l.groupBurst = max(burst, 1)
+	}
+}
+
+// LimiterChatIdle sets the time after which limiters of idle chats are evicted,
+// DefaultChatLimiterIdle by default
+func LimiterChatIdle(idle time.Duration) LimiterOption {
+	return func(l *Limiter) {
+		l.idleTimeout = idle
+	}
+}
+
+// limitOf returns the rate of n events per period, no limit if n is not positive
+func limitOf(n int, per time.Duration) rate.Limit {
+	if n <= 0 {
+		return rate.Inf
+	}
+	return rate.Every(per / time.Duration(n))
 }
 
-// NewLimiter creates a new rate limiter
-func NewLimiter() *Limiter {
+// NewLimiter creates a new rate limiter with Telegram limits, the options change them
+func NewLimiter(options ...LimiterOption) *Limiter {
+	l := &Limiter{
+		globalLimiter: rate.NewLimiter(limitOf(GlobalMessageLimit, time.Second), BurstSize),
+		chatLimiters:  make(map[int64]*chatLimiter),
+		apiLimiter:    rate.NewLimiter(limitOf(APILimit, time.Second), BurstSize),
+		chatLimit:     limitOf(ChatMessageLimit, time.Second),
+		chatBurst:     1,
+		groupLimit:    limitOf(GroupMessageLimit, time.Minute),
+		groupBurst:    1,
+		idleTimeout:   DefaultChatLimiterIdle,
+		lastSweep:     time.Now(),
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+// NewUnlimitedLimiter creates a limiter that never waits
+// Intended for tests and local Bot API servers without Telegram limits
+func NewUnlimitedLimiter() *Limiter {
 	return &Limiter{
-		globalLimiter: rate.NewLimiter(rate.Every(time.Second/GlobalMessageLimit), BurstSize),
-		chatLimiters:  make(map[int64]*rate.Limiter),
-		apiLimiter:    rate.NewLimiter(rate.Every(time.Second/APILimit), BurstSize),
+		globalLimiter: rate.NewLimiter(rate.Inf, 0),
+		chatLimiters:  make(map[int64]*chatLimiter),
+		apiLimiter:    rate.NewLimiter(rate.Inf, 0),
+		chatLimit:     rate.Inf,
+		groupLimit:    rate.Inf,
+		idleTimeout:   DefaultChatLimiterIdle,
+		lastSweep:     time.Now(),
 	}
 }
 
@@ -57,18 +155,40 @@ func (l *Limiter) WaitForAPI(ctx context.Context) error {
 }
 
 // getChatLimiter returns or creates a rate limiter for a specific chat
+// Limiters of idle chats are evicted, so memory does not grow with every chat ever messaged.
 func (l *Limiter) getChatLimiter(chatID int64) *rate.Limiter {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	now := time.Now()
+	if l.idleTimeout > 0 && now.Sub(l.lastSweep) >= l.idleTimeout {
+		l.evictIdle(now)
+	}
+
 	if limiter, exists := l.chatLimiters[chatID]; exists {
-		return limiter
+		limiter.lastUsed = now
+		return limiter.Limiter
 	}
 
-	// Create new limiter for this chat (1 message per second)
-	limiter := rate.NewLimiter(rate.Every(time.Second/ChatMessageLimit), 1)
+	// Create new limiter for this chat with the limit of its type
+	limit, burst := l.chatLimit, l.chatBurst
+	if chatID < 0 {
+		limit, burst = l.groupLimit, l.groupBurst
+	}
+	limiter := &chatLimiter{Limiter: rate.NewLimiter(limit, burst), lastUsed: now}
 	l.chatLimiters[chatID] = limiter
-	return limiter
+	return limiter.Limiter
+}
+
+// evictIdle deletes limiters of chats idle for idleTimeout
+// Only full limiters are deleted: a new limiter for the chat would allow the same.
+func (l *Limiter) evictIdle(now time.Time) {
+	for chatID, limiter := range l.chatLimiters {
+		if now.Sub(limiter.lastUsed) >= l.idleTimeout && limiter.TokensAt(now) >= float64(limiter.Burst()) {
+			delete(l.chatLimiters, chatID)
+		}
+	}
+	l.lastSweep = now
 }
 
 // AllowMessage checks if a message can be sent without waiting
@@ -87,3 +207,59 @@ func (l *Limiter) AllowMessage(chatID int64) bool {
 func (l *Limiter) AllowAPI() bool {
 	return l.apiLimiter.Allow()
 }
+
+// LimiterStatus is the state of the limiter (see Limiter.Status)
+type LimiterStatus struct {
+	// Tokens available for messages to all chats and for API requests, negative while requests wait,
+	// 0 for NewUnlimitedLimiter
+	GlobalTokens float64 `json:"global_tokens"`
+	APITokens    float64 `json:"api_tokens"`
+	// Rates in requests per second, 0 for NewUnlimitedLimiter
+	GlobalRate    float64 `json:"global_rate"`
+	APIRate       float64 `json:"api_rate"`
+	ChatRate      float64 `json:"chat_rate"`       // Private chats
+	GroupChatRate float64 `json:"group_chat_rate"` // Groups and channels
+	// Unlimited is true for NewUnlimitedLimiter
+	Unlimited bool `json:"unlimited"`
+	// Number of chats with a per-chat limiter
+	Chats int `json:"chats"`
+}
+
+// Status returns the current state of the limiter
+func (l *Limiter) Status() LimiterStatus {
+	l.mu.RLock()
+	chats := len(l.chatLimiters)
+	l.mu.RUnlock()
+
+	return LimiterStatus{
+		GlobalTokens:  tokens(l.globalLimiter),
+		APITokens:     tokens(l.apiLimiter),
+		GlobalRate:    finiteRate(l.globalLimiter.Limit()),
+		APIRate:       finiteRate(l.apiLimiter.Limit()),
+		ChatRate:      finiteRate(l.chatLimit),
+		GroupChatRate: finiteRate(l.groupLimit),
+		Unlimited:     l.apiLimiter.Limit() == rate.Inf,
+		Chats:         chats,
+	}
+}
+
+// tokens returns the tokens available in the limiter, 0 if it is unlimited
+func tokens(limiter *rate.Limiter) float64 {
+	if limiter.Limit() == rate.Inf {
+		return 0
+	}
+	return limiter.Tokens()
+}
+
+// finiteRate returns the rate in requests per second, 0 for rate.Inf
+func finiteRate(limit rate.Limit) float64 {
+	if limit == rate.Inf {
+		return 0
+	}
+	return float64(limit)
+}
+
+// LimiterStatus returns the current state of the bot's limiter
+func (b *Bot) LimiterStatus() LimiterStatus {
+	return b.settings().limiter.Status()
+}
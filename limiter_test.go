@@ -0,0 +1,85 @@
+package tgfsm
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestLimiterChatType(t *testing.T) {
+	l := NewLimiter(
+		LimiterPrivateChatLimit(2, time.Second, 3),
+		LimiterGroupChatLimit(10, time.Minute, 4),
+	)
+
+	tests := []struct {
+		name   string
+		chatID int64
+		limit  rate.Limit
+		burst  int
+	}{
+		{name: "private", chatID: 42, limit: rate.Every(500 * time.Millisecond), burst: 3},
+		{name: "group", chatID: -42, limit: rate.Every(6 * time.Second), burst: 4},
+		{name: "supergroup", chatID: -1001234567890, limit: rate.Every(6 * time.Second), burst: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := l.getChatLimiter(tt.chatID)
+			if limiter.Limit() != tt.limit || limiter.Burst() != tt.burst {
+				t.Errorf("limit %v burst %d, want %v burst %d", limiter.Limit(), limiter.Burst(), tt.limit, tt.burst)
+			}
+			if again := l.getChatLimiter(tt.chatID); again != limiter {
+				t.Error("a new limiter is created for a known chat")
+			}
+		})
+	}
+}
+
+func TestLimiterEvictIdle(t *testing.T) {
+	l := NewLimiter(LimiterPrivateChatLimit(1, time.Hour, 1), LimiterChatIdle(time.Minute))
+	now := time.Now()
+
+	l.getChatLimiter(1) // Full, idle
+	l.getChatLimiter(2).Allow()
+	l.getChatLimiter(3) // Full, used recently
+	l.chatLimiters[1].lastUsed = now.Add(-2 * time.Minute)
+	l.chatLimiters[2].lastUsed = now.Add(-2 * time.Minute)
+
+	l.evictIdle(now)
+
+	if _, ok := l.chatLimiters[1]; ok {
+		t.Error("limiter of an idle chat is not evicted")
+	}
+	if _, ok := l.chatLimiters[2]; !ok {
+		t.Error("limiter of an idle chat without tokens is evicted, the chat would bypass its limit")
+	}
+	if _, ok := l.chatLimiters[3]; !ok {
+		t.Error("limiter of an active chat is evicted")
+	}
+	if !l.lastSweep.Equal(now) {
+		t.Errorf("last sweep %v, want %v", l.lastSweep, now)
+	}
+}
+
+func TestLimiterSweep(t *testing.T) {
+	l := NewLimiter(LimiterChatIdle(time.Minute))
+	l.getChatLimiter(1)
+	l.chatLimiters[1].lastUsed = time.Now().Add(-2 * time.Minute)
+
+	// Idle limiters are evicted only once the idle timeout passed since the last sweep
+	l.getChatLimiter(2)
+	if len(l.chatLimiters) != 2 {
+		t.Fatalf("%d chat limiters before the sweep, want 2", len(l.chatLimiters))
+	}
+
+	l.lastSweep = time.Now().Add(-2 * time.Minute)
+	l.getChatLimiter(2)
+	if _, ok := l.chatLimiters[1]; ok {
+		t.Error("limiter of an idle chat is not evicted by the sweep")
+	}
+	if status := l.Status(); status.Chats != 1 {
+		t.Errorf("status reports %d chats, want 1", status.Chats)
+	}
+}
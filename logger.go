@@ -1,10 +1,45 @@
 package tgfsm
 
 import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// Logger is the structured logger used by the bot (see WithLogger, WithSlogLogger and WithCustomLogger)
+// Adapters for zap and log/slog are provided by NewZapAdapter and NewSlogAdapter.
+// Log entries about updates carry the fields update_id, user_id, chat_id, state and handler.
+type Logger interface {
+	Debug(msg string, fields ...Field)
+	Info(msg string, fields ...Field)
+	Warn(msg string, fields ...Field)
+	Error(msg string, fields ...Field)
+	// With returns a logger that adds the fields to every entry
+	With(fields ...Field) Logger
+}
+
+// Field is a key-value field of a log entry
+// Value is a string, bool, number, time.Duration or error.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// LogField creates a field of a log entry
+func LogField(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// ErrorField creates the "error" field of a log entry
+func ErrorField(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
 // NewZapLogger creates a new asynchronous JSON logger with configured timestamp formatting,
 // log levels and caller information.
 func NewZapLogger() (*zap.Logger, error) {
@@ -31,3 +66,105 @@ func NewZapLogger() (*zap.Logger, error) {
 	}
 	return logger, nil
 }
+
+// zapLogger adapts *zap.Logger to Logger
+type zapLogger struct {
+	logger *zap.Logger
+}
+
+// NewZapAdapter returns a Logger writing to the zap logger
+func NewZapAdapter(logger *zap.Logger) Logger {
+	// Report the caller of the adapter methods
+	return zapLogger{logger: logger.WithOptions(zap.AddCallerSkip(1))}
+}
+
+func (l zapLogger) Debug(msg string, fields ...Field) { l.logger.Debug(msg, zapFields(fields)...) }
+func (l zapLogger) Info(msg string, fields ...Field)  { l.logger.Info(msg, zapFields(fields)...) }
+func (l zapLogger) Warn(msg string, fields ...Field)  { l.logger.Warn(msg, zapFields(fields)...) }
+func (l zapLogger) Error(msg string, fields ...Field) { l.logger.Error(msg, zapFields(fields)...) }
+
+func (l zapLogger) With(fields ...Field) Logger {
+	return zapLogger{logger: l.logger.With(zapFields(fields)...)}
+}
+
+// zapFields converts fields to zap fields
+func zapFields(fields []Field) []zap.Field {
+	converted := make([]zap.Field, len(fields))
+	for i, field := range fields {
+		converted[i] = zap.Any(field.Key, field.Value)
+	}
+	return converted
+}
+
+// slogLogger adapts *slog.Logger to Logger
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogAdapter returns a Logger writing to the slog logger
+func NewSlogAdapter(logger *slog.Logger) Logger {
+	return slogLogger{logger: logger}
+}
+
+func (l slogLogger) Debug(msg string, fields ...Field) { l.log(slog.LevelDebug, msg, fields) }
+func (l slogLogger) Info(msg string, fields ...Field)  { l.log(slog.LevelInfo, msg, fields) }
+func (l slogLogger) Warn(msg string, fields ...Field)  { l.log(slog.LevelWarn, msg, fields) }
+func (l slogLogger) Error(msg string, fields ...Field) { l.log(slog.LevelError, msg, fields) }
+
+func (l slogLogger) With(fields ...Field) Logger {
+	return slogLogger{logger: slog.New(l.logger.Handler().WithAttrs(slogAttrs(fields)))}
+}
+
+// log writes the entry with the caller of the adapter method as its source
+func (l slogLogger) log(level slog.Level, msg string, fields []Field) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	// Skip runtime.Callers, log and the adapter method
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+	record := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	record.AddAttrs(slogAttrs(fields)...)
+	_ = l.logger.Handler().Handle(ctx, record)
+}
+
+// slogAttrs converts fields to slog attributes
+func slogAttrs(fields []Field) []slog.Attr {
+	converted := make([]slog.Attr, len(fields))
+	for i, field := range fields {
+		converted[i] = slog.Any(field.Key, field.Value)
+	}
+	return converted
+}
+
+// Logger returns the logger of the bot
+func (app *Bot) Logger() Logger {
+	return app.settings().logger
+}
+
+// updateLogger returns the logger with the fields of the update: update_id, user_id, chat_id, state and handler
+// state is the state processing the update, the user's current state if empty;
+// handler is the matched handler: its trigger, "catch_all" or "at_entrance", empty if none.
+// All fields are always present, so log entries of updates can be queried the same way.
+func (app *Bot) updateLogger(update tgbotapi.Update, state, handler string) Logger {
+	var userID, chatID int64
+	if user := update.SentFrom(); user != nil {
+		userID = user.ID
+		if state == "" {
+			state, _ = app.GetUserState(user.ID)
+		}
+	}
+	if chat := update.FromChat(); chat != nil {
+		chatID = chat.ID
+	}
+
+	return app.Logger().With(
+		LogField("update_id", update.UpdateID),
+		LogField("user_id", userID),
+		LogField("chat_id", chatID),
+		LogField("state", state),
+		LogField("handler", handler),
+	)
+}
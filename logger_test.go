@@ -0,0 +1,176 @@
+package tgfsm_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"tgfsm"
+	"tgfsm/tgfsmtest"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+const (
+	logUserID = 3
+	logChatID = -300
+)
+
+// logEntry is a log entry captured by a test logger
+type logEntry struct {
+	msg    string
+	fields map[string]string
+	file   string // Base name of the source file
+}
+
+// capturingHandler is a slog.Handler recording the entries
+type capturingHandler struct {
+	mu      *sync.Mutex
+	entries *[]logEntry
+	attrs   []slog.Attr
+}
+
+func newCapturingHandler() *capturingHandler {
+	return &capturingHandler{mu: &sync.Mutex{}, entries: &[]logEntry{}}
+}
+
+func (h *capturingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *capturingHandler) Handle(_ context.Context, record slog.Record) error {
+	entry := logEntry{msg: record.Message, fields: make(map[string]string)}
+	for _, attr := range h.attrs {
+		entry.fields[attr.Key] = attr.Value.String()
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		entry.fields[attr.Key] = attr.Value.String()
+		return true
+	})
+	frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+	entry.file = filepath.Base(frame.File)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.entries = append(*h.entries, entry)
+	return nil
+}
+
+func (h *capturingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &capturingHandler{mu: h.mu, entries: h.entries, attrs: append(append([]slog.Attr(nil), h.attrs...), attrs...)}
+}
+
+func (h *capturingHandler) WithGroup(string) slog.Handler { return h }
+
+func (h *capturingHandler) logged() []logEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]logEntry(nil), *h.entries...)
+}
+
+// observedEntries converts the entries logged to the zap observer
+func observedEntries(logs *observer.ObservedLogs) []logEntry {
+	var entries []logEntry
+	for _, logged := range logs.All() {
+		entry := logEntry{msg: logged.Message, fields: make(map[string]string), file: filepath.Base(logged.Caller.File)}
+		for key, value := range logged.ContextMap() {
+			entry.fields[key] = fmt.Sprint(value)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// findEntry returns the first entry with the message
+func findEntry(entries []logEntry, msg string) (logEntry, bool) {
+	for _, entry := range entries {
+		if entry.msg == msg {
+			return entry, true
+		}
+	}
+	return logEntry{}, false
+}
+
+// logUpdates processes a handled command, an unknown command and a handled callback in a group
+func logUpdates(t *testing.T, logger tgfsm.Option) []tgbotapi.Update {
+	t.Helper()
+
+	server := tgfsmtest.NewServer()
+	t.Cleanup(server.Close)
+
+	states := map[string]tgfsm.State{"home": {
+		MessageHandlers:  map[string]tgfsm.Handler{"/ok": {Handle: reply("ok")}},
+		CallbackHandlers: map[string]tgfsm.Handler{"press": {Handle: reply("pressed")}},
+	}}
+	bot, err := tgfsmtest.NewBot(server, tgfsm.WithStates(states), tgfsm.WithInitialState("home"), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	callback := tgfsmtest.NewCallbackUpdate(logUserID, "press", 1)
+	callback.CallbackQuery.Message.Chat = &tgbotapi.Chat{ID: logChatID, Type: "group"}
+	updates := []tgbotapi.Update{
+		tgfsmtest.NewChatMessageUpdate(logChatID, logUserID, "/ok"),
+		tgfsmtest.NewChatMessageUpdate(logChatID, logUserID, "/missing"),
+		callback,
+	}
+	for _, update := range updates {
+		bot.ProcessUpdate(update)
+	}
+	return updates
+}
+
+// checkUpdateEntries checks the fields and the source of the entries about the updates
+func checkUpdateEntries(t *testing.T, updates []tgbotapi.Update, entries []logEntry) {
+	t.Helper()
+
+	tests := []struct {
+		msg     string
+		update  tgbotapi.Update
+		handler string
+	}{
+		{msg: "command handled successfully", update: updates[0], handler: "/ok"},
+		{msg: "command not found", update: updates[1], handler: ""},
+		{msg: "callback handled successfully", update: updates[2], handler: "press"},
+	}
+
+	for _, tt := range tests {
+		entry, ok := findEntry(entries, tt.msg)
+		if !ok {
+			t.Errorf("no entry %q", tt.msg)
+			continue
+		}
+		want := map[string]string{
+			"update_id": fmt.Sprint(tt.update.UpdateID),
+			"user_id":   fmt.Sprint(logUserID),
+			"chat_id":   fmt.Sprint(logChatID),
+			"state":     "home",
+			"handler":   tt.handler,
+		}
+		for key, value := range want {
+			if entry.fields[key] != value {
+				t.Errorf("%q: %s is %q, want %q", tt.msg, key, entry.fields[key], value)
+			}
+		}
+		// The source is the bot code writing the entry, not the adapter
+		if entry.file != "bot.go" {
+			t.Errorf("%q: source %q, want bot.go", tt.msg, entry.file)
+		}
+	}
+}
+
+func TestSlogAdapter(t *testing.T) {
+	handler := newCapturingHandler()
+	updates := logUpdates(t, tgfsm.WithSlogLogger(slog.New(handler)))
+	checkUpdateEntries(t, updates, handler.logged())
+}
+
+func TestZapAdapter(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	updates := logUpdates(t, tgfsm.WithLogger(zap.New(core, zap.AddCaller())))
+	checkUpdateEntries(t, updates, observedEntries(logs))
+}
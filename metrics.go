@@ -0,0 +1,254 @@
+package tgfsm
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Reasons of filtered updates reported to Metrics.UpdateFiltered
+const (
+	FilterNoChat       = "no_chat"       // The update has no chat (inline queries, polls, ...)
+	FilterBlacklisted  = "blacklisted"   // The chat is blacklisted
+	FilterUserBanned   = "user_banned"   // The user is banned (see BanUser)
+	FilterNotAllowed   = "not_allowed"   // The user is not in the allowlist (see WithAllowlist)
+	FilterAccessDenied = "access_denied" // The user lacks the roles of the state or handler
+	FilterPrivateOnly  = "private_only"  // The chat is not private while WithPrivateOnly is set
+	FilterFlood        = "flood"         // The user exceeded the flood limit (see WithFloodControl)
+)
+
+// Outcomes of auto-deletion reported to Metrics.AutoDeleted
+const (
+	AutoDeleteDeleted   = "deleted"   // The previous message was deleted
+	AutoDeleteImportant = "important" // The previous message is important and was kept
+	AutoDeleteFailed    = "failed"    // Deleting the previous message failed
+)
+
+// Kinds of limiter waits reported to Metrics.LimiterWaited
+const (
+	LimiterMessage = "message" // Limiter.WaitForMessage
+	LimiterAPI     = "api"     // Limiter.WaitForAPI
+)
+
+// Metrics receives events of the bot for monitoring (see WithMetrics)
+// Implementations must be safe for concurrent use. The package tgfsm/metrics
+// provides an implementation exposing the events in Prometheus text format.
+type Metrics interface {
+	// UpdateReceived is called for every update before filters
+	UpdateReceived(updateType string)
+	// UpdateFiltered is called for updates rejected by filters, reason is one of Filter* constants
+	UpdateFiltered(updateType, reason string)
+	// UpdateHandled is called after a handler of the state processed an update
+	// state is the name of the state (see Bot.StateLabel), err is the error returned by the handler
+	UpdateHandled(updateType, state string, duration time.Duration, err error)
+	// LimiterWaited is called after waiting for the rate limiter, kind is one of Limiter* constants
+	LimiterWaited(kind string, wait time.Duration)
+	// APIError is called when a Bot API call fails; method is the Bot API method, e.g. "sendMessage",
+	// code is the Telegram error code, 0 for network and other errors
+	APIError(method string, code int)
+	// AutoDeleted is called when auto-deletion handles the previous message, outcome is one of AutoDelete* constants
+	AutoDeleted(outcome string)
+}
+
+// nopMetrics is the default Metrics that ignores all events
+type nopMetrics struct{}
+
+func (nopMetrics) UpdateReceived(string)                              {}
+func (nopMetrics) UpdateFiltered(string, string)                      {}
+func (nopMetrics) UpdateHandled(string, string, time.Duration, error) {}
+func (nopMetrics) LimiterWaited(string, time.Duration)                {}
+func (nopMetrics) APIError(string, int)                               {}
+func (nopMetrics) AutoDeleted(string)                                 {}
+
+// UpdateType returns the type of the update as named in the Bot API: "message", "callback_query", ...
+func UpdateType(update tgbotapi.Update) string {
+	switch {
+	case update.Message != nil:
+		return "message"
+	case update.EditedMessage != nil:
+		return "edited_message"
+	case update.ChannelPost != nil:
+		return "channel_post"
+	case update.EditedChannelPost != nil:
+		return "edited_channel_post"
+	case update.InlineQuery != nil:
+		return "inline_query"
+	case update.ChosenInlineResult != nil:
+		return "chosen_inline_result"
+	case update.CallbackQuery != nil:
+		return "callback_query"
+	case update.ShippingQuery != nil:
+		return "shipping_query"
+	case update.PreCheckoutQuery != nil:
+		return "pre_checkout_query"
+	case update.Poll != nil:
+		return "poll"
+	case update.PollAnswer != nil:
+		return "poll_answer"
+	case update.MyChatMember != nil:
+		return "my_chat_member"
+	case update.ChatMember != nil:
+		return "chat_member"
+	case update.ChatJoinRequest != nil:
+		return "chat_join_request"
+	default:
+		return "unknown"
+	}
+}
+
+// ActiveUsers returns the number of users in each state by state key
+// Use StateLabel to report the states by name, e.g. in metrics.
+// Only users whose state is stored are counted: users without a state are not included.
+func (app *Bot) ActiveUsers() map[string]int {
+	users := make(map[string]int)
+	if app.cache == nil {
+		return users
+	}
+
+	for key, item := range app.cache.Items() {
+		state, ok := item.Object.(string)
+		if !ok || state == "" {
+			continue
+		}
+		if _, err := strconv.ParseInt(key, 10, 64); err != nil {
+			continue
+		}
+		users[state]++
+	}
+	return users
+}
+
+// StateLabel returns the name of the state used in metrics: State.Name,
+// or the key if the name is empty or the state does not exist
+func (app *Bot) StateLabel(state string) string {
+	if s, ok := app.stateSet().states[state]; ok {
+		return s.label()
+	}
+	return state
+}
+
+// callHandler runs a handler of the state and reports it to metrics and the update span
+// name describes the handler: its trigger, "catch_all" or "at_entrance".
+// Users lacking the roles of the state or the handler are denied with ErrAccessDenied.
+func (app *Bot) callHandler(userState *State, name string, handler *Handler, update tgbotapi.Update) error {
+	if roles, err := app.authorizeHandler(update, userState, handler); err != nil {
+		app.traceHandler(update, userState.key, name, err)
+		app.denyAccess(update, FilterAccessDenied, roles, err)
+		return err
+	}
+
+	start := time.Now()
+	err := handler.Handle(app, update)
+	// Keys of event states are random, so the name keeps the label stable between restarts
+	app.metrics.UpdateHandled(UpdateType(update), userState.label(), time.Since(start), err)
+	app.traceHandler(update, userState.key, name, err)
+	return err
+}
+
+// waitForMessage waits for the limiter to send a message to the chat and reports the wait
+func (b *Bot) waitForMessage(chatID int64) {
+	_, span := b.tracer.Start(b.chatContext(chatID), "tgfsm.limiter", Attr("limiter.kind", LimiterMessage), Attr("chat.id", chatID))
+	defer span.End()
+
+	start := time.Now()
+	b.settings().limiter.WaitForMessage(context.Background(), chatID)
+	b.metrics.LimiterWaited(LimiterMessage, time.Since(start))
+}
+
+// waitForAPI waits for the limiter to make an API request and reports the wait
+// chatID is the chat of the request, 0 if it has none
+func (b *Bot) waitForAPI(chatID int64) {
+	b.waitForAPIContext(context.Background(), chatID)
+}
+
+// waitForAPIContext is waitForAPI returning early with the error of ctx when it is done
+func (b *Bot) waitForAPIContext(ctx context.Context, chatID int64) error {
+	attributes := []Attribute{Attr("limiter.kind", LimiterAPI)}
+	if chatID != 0 {
+		attributes = append(attributes, Attr("chat.id", chatID))
+	}
+	_, span := b.tracer.Start(b.chatContext(chatID), "tgfsm.limiter", attributes...)
+	defer span.End()
+
+	start := time.Now()
+	err := b.settings().limiter.WaitForAPI(ctx)
+	b.metrics.LimiterWaited(LimiterAPI, time.Since(start))
+	return err
+}
+
+// instrumentedClient traces calls of the client and reports failed calls to the bot metrics
+type instrumentedClient struct {
+	Client
+	bot *Bot
+}
+
+// instrumentClient wraps the client to trace and report its calls, unless it is already wrapped
+func (b *Bot) instrumentClient(client Client) Client {
+	if client == nil {
+		return nil
+	}
+	if instrumented, ok := client.(*instrumentedClient); ok {
+		client = instrumented.Client
+	}
+	return &instrumentedClient{Client: client, bot: b}
+}
+
+func (c *instrumentedClient) Send(chattable tgbotapi.Chattable) (tgbotapi.Message, error) {
+	span := c.start(chattable)
+	message, err := c.Client.Send(chattable)
+	c.end(span, callName(chattable), err)
+	return message, err
+}
+
+func (c *instrumentedClient) Request(chattable tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	span := c.start(chattable)
+	response, err := c.Client.Request(chattable)
+	c.end(span, callName(chattable), err)
+	return response, err
+}
+
+// GetUpdates is not traced: long polling would only produce noise
+func (c *instrumentedClient) GetUpdates(config tgbotapi.UpdateConfig) ([]tgbotapi.Update, error) {
+	updates, err := c.Client.GetUpdates(config)
+	c.end(nopSpan{}, callName(config), err)
+	return updates, err
+}
+
+func (c *instrumentedClient) GetFile(config tgbotapi.FileConfig) (tgbotapi.File, error) {
+	span := c.start(config)
+	file, err := c.Client.GetFile(config)
+	c.end(span, callName(config), err)
+	return file, err
+}
+
+// start starts the span of the call as a child of the update being processed in its chat
+func (c *instrumentedClient) start(config interface{}) Span {
+	chatID := chatIDOf(config)
+	attributes := []Attribute{Attr("api.call", callName(config))}
+	if chatID != 0 {
+		attributes = append(attributes, Attr("chat.id", chatID))
+	}
+
+	_, span := c.bot.tracer.Start(c.bot.chatContext(chatID), "tgfsm.api."+callName(config), attributes...)
+	return span
+}
+
+// end ends the span of the call and reports its error
+func (c *instrumentedClient) end(span Span, call string, err error) {
+	defer span.End()
+	if err == nil {
+		return
+	}
+
+	code := 0
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) {
+		code = apiErr.Code
+	}
+	span.SetAttributes(Attr("api.error_code", code))
+	span.RecordError(err)
+	c.bot.metrics.APIError(call, code)
+}
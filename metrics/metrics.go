@@ -0,0 +1,246 @@
+// Package metrics collects bot metrics and exposes them in Prometheus text format
+//
+//	collector := metrics.New()
+//	bot, err := tgfsm.NewBot(token, tgfsm.WithMetrics(collector), ...)
+//	collector.WatchBot(bot)
+//
+//	go metrics.ListenAndServe(":9090", metrics.DefaultPath, collector)
+//
+// The exposition is written without the Prometheus client library, so the package has no dependencies.
+// Exposed metrics (with the default namespace "tgfsm"):
+//
+//	tgfsm_updates_received_total{type}          updates received by the bot
+//	tgfsm_updates_filtered_total{type,reason}   updates rejected by filters
+//	tgfsm_updates_handled_total{type,state}     updates processed by handlers
+//	tgfsm_handler_errors_total{type,state}      errors returned by handlers
+//	tgfsm_handler_duration_seconds{type,state}  handler latency histogram
+//	tgfsm_limiter_wait_seconds{kind}            rate limiter wait histogram
+//	tgfsm_api_errors_total{method,code}         failed Bot API calls
+//	tgfsm_active_users{state}                   users in each state (see WatchBot)
+//	tgfsm_auto_delete_total{outcome}            auto-deletion of previous messages
+//
+// The state label is the name of the state (State.Name) or its key if the state has no name,
+// so states generated by events with random keys keep their labels between restarts.
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"tgfsm"
+	"time"
+)
+
+// DefaultPath is the usual HTTP path of the metrics
+const DefaultPath = "/metrics"
+
+// Default histogram buckets, in seconds
+var (
+	DefaultHandlerBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	DefaultLimiterBuckets = []float64{.001, .005, .01, .05, .1, .25, .5, 1, 2.5, 5}
+)
+
+// Ensure Collector implements Metrics interface
+var _ tgfsm.Metrics = (*Collector)(nil)
+
+// Collector collects bot metrics (see tgfsm.WithMetrics)
+type Collector struct {
+	mu sync.Mutex
+
+	namespace      string
+	handlerBuckets []float64
+	limiterBuckets []float64
+
+	received        *counterVec
+	filtered        *counterVec
+	handled         *counterVec
+	handlerErrors   *counterVec
+	handlerDuration *histogramVec
+	limiterWait     *histogramVec
+	apiErrors       *counterVec
+	autoDelete      *counterVec
+
+	bot *tgfsm.Bot
+}
+
+// Option is a function that modifies the collector configuration
+type Option func(*Collector)
+
+// WithNamespace sets the prefix of the metric names, "tgfsm" by default
+func WithNamespace(namespace string) Option {
+	return func(c *Collector) {
+		c.namespace = namespace
+	}
+}
+
+// WithHandlerBuckets sets the buckets of the handler latency histogram, in seconds
+func WithHandlerBuckets(buckets []float64) Option {
+	return func(c *Collector) {
+		c.handlerBuckets = buckets
+	}
+}
+
+// WithLimiterBuckets sets the buckets of the limiter wait histogram, in seconds
+func WithLimiterBuckets(buckets []float64) Option {
+	return func(c *Collector) {
+		c.limiterBuckets = buckets
+	}
+}
+
+// New creates a collector
+func New(options ...Option) *Collector {
+	c := &Collector{
+		namespace:      "tgfsm",
+		handlerBuckets: DefaultHandlerBuckets,
+		limiterBuckets: DefaultLimiterBuckets,
+	}
+	for _, option := range options {
+		option(c)
+	}
+
+	c.received = newCounterVec(c.name("updates_received_total"), "Updates received by the bot.", "type")
+	c.filtered = newCounterVec(c.name("updates_filtered_total"), "Updates rejected by filters.", "type", "reason")
+	c.handled = newCounterVec(c.name("updates_handled_total"), "Updates processed by state handlers.", "type", "state")
+	c.handlerErrors = newCounterVec(c.name("handler_errors_total"), "Errors returned by state handlers.", "type", "state")
+	c.handlerDuration = newHistogramVec(c.name("handler_duration_seconds"), "Latency of state handlers.", c.handlerBuckets, "type", "state")
+	c.limiterWait = newHistogramVec(c.name("limiter_wait_seconds"), "Time spent waiting for the rate limiter.", c.limiterBuckets, "kind")
+	c.apiErrors = newCounterVec(c.name("api_errors_total"), "Failed Telegram Bot API calls by Telegram error code, 0 for network errors.", "method", "code")
+	c.autoDelete = newCounterVec(c.name("auto_delete_total"), "Auto-deletion of previous messages by outcome.", "outcome")
+	return c
+}
+
+// name returns the full name of the metric
+func (c *Collector) name(name string) string {
+	if c.namespace == "" {
+		return name
+	}
+	return c.namespace + "_" + name
+}
+
+// WatchBot enables the active users gauge, counted from the bot's stored user states on every scrape
+func (c *Collector) WatchBot(bot *tgfsm.Bot) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.bot = bot
+}
+
+// UpdateReceived implements tgfsm.Metrics
+func (c *Collector) UpdateReceived(updateType string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.received.add(1, updateType)
+}
+
+// UpdateFiltered implements tgfsm.Metrics
+func (c *Collector) UpdateFiltered(updateType, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.filtered.add(1, updateType, reason)
+}
+
+// UpdateHandled implements tgfsm.Metrics
+func (c *Collector) UpdateHandled(updateType, state string, duration time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.handled.add(1, updateType, state)
+	c.handlerDuration.observe(duration.Seconds(), updateType, state)
+	if err != nil {
+		c.handlerErrors.add(1, updateType, state)
+	}
+}
+
+// LimiterWaited implements tgfsm.Metrics
+func (c *Collector) LimiterWaited(kind string, wait time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.limiterWait.observe(wait.Seconds(), kind)
+}
+
+// APIError implements tgfsm.Metrics
+func (c *Collector) APIError(method string, code int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.apiErrors.add(1, method, strconv.Itoa(code))
+}
+
+// AutoDeleted implements tgfsm.Metrics
+func (c *Collector) AutoDeleted(outcome string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.autoDelete.add(1, outcome)
+}
+
+// WriteTo writes the metrics in Prometheus text format
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	bot := c.bot
+	c.mu.Unlock()
+
+	// The cache is scanned without holding the lock, so handlers are not blocked
+	var activeUsers map[string]float64
+	if bot != nil {
+		activeUsers = make(map[string]float64)
+		// States are reported by name like in the handler metrics; states of several
+		// instances of an event may share a name, their users are summed
+		for state, users := range bot.ActiveUsers() {
+			activeUsers[bot.StateLabel(state)] += float64(users)
+		}
+	}
+
+	counter := &countingWriter{w: w}
+	buf := bufio.NewWriter(counter)
+
+	c.mu.Lock()
+	c.received.write(buf)
+	c.filtered.write(buf)
+	c.handled.write(buf)
+	c.handlerErrors.write(buf)
+	c.handlerDuration.write(buf)
+	c.limiterWait.write(buf)
+	c.apiErrors.write(buf)
+	c.autoDelete.write(buf)
+	c.mu.Unlock()
+	if activeUsers != nil {
+		writeGauge(buf, c.name("active_users"), "Users with a stored state by state.", []string{"state"}, activeUsers)
+	}
+
+	err := buf.Flush()
+	return counter.n, err
+}
+
+// ServeHTTP serves the metrics in Prometheus text format
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+// ListenAndServe serves the metrics of the collector on the address and HTTP path (DefaultPath if empty)
+func ListenAndServe(addr, path string, c *Collector) error {
+	if path == "" {
+		path = DefaultPath
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, c)
+	return http.ListenAndServe(addr, mux)
+}
+
+// countingWriter counts the bytes written
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
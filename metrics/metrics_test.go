@@ -0,0 +1,104 @@
+package metrics_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"tgfsm"
+	"tgfsm/metrics"
+	"tgfsm/tgfsmtest"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestCollectorStateLabels(t *testing.T) {
+	server := tgfsmtest.NewServer()
+	t.Cleanup(server.Close)
+
+	collector := metrics.New()
+	states := map[string]tgfsm.State{
+		"home": {
+			MessageHandlers: map[string]tgfsm.Handler{
+				"/menu": {Handle: tgfsm.NewSetUserStateHandler("3f1c9a52-event-key")},
+			},
+		},
+		// Events generate random keys and describe their states with names
+		"3f1c9a52-event-key": {
+			Name: "Menu (/menu)",
+			CatchAllFunc: &tgfsm.Handler{Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+				return nil
+			}},
+			MessageHandlers: map[string]tgfsm.Handler{},
+		},
+	}
+	bot, err := tgfsmtest.NewBot(server, tgfsm.WithStates(states), tgfsm.WithInitialState("home"), tgfsm.WithMetrics(collector))
+	if err != nil {
+		t.Fatalf("failed to create bot: %v", err)
+	}
+	collector.WatchBot(bot)
+
+	bot.ProcessUpdate(tgfsmtest.NewMessageUpdate(1, "/menu"))
+	bot.ProcessUpdate(tgfsmtest.NewMessageUpdate(1, "hello"))
+
+	var b strings.Builder
+	if _, err := collector.WriteTo(&b); err != nil {
+		t.Fatalf("failed to write metrics: %v", err)
+	}
+	output := b.String()
+
+	for _, line := range []string{
+		`tgfsm_updates_handled_total{type="message",state="home"} 1`,
+		`tgfsm_updates_handled_total{type="message",state="Menu (/menu)"} 1`,
+		`tgfsm_active_users{state="Menu (/menu)"} 1`,
+	} {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("no line %q in:\n%s", line, output)
+		}
+	}
+	if strings.Contains(output, "event-key") {
+		t.Errorf("state key in labels:\n%s", output)
+	}
+}
+
+// failingClient fails every call: sending with a Telegram error, requests with a network error
+type failingClient struct {
+	tgfsm.Client
+}
+
+func (failingClient) Send(tgbotapi.Chattable) (tgbotapi.Message, error) {
+	return tgbotapi.Message{}, &tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}
+}
+
+func (failingClient) Request(tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	return nil, errors.New("connection reset")
+}
+
+func TestCollectorAPIErrors(t *testing.T) {
+	server := tgfsmtest.NewServer()
+	t.Cleanup(server.Close)
+
+	collector := metrics.New()
+	bot, err := tgfsmtest.NewBot(server, tgfsm.WithClient(failingClient{}), tgfsm.WithMetrics(collector))
+	if err != nil {
+		t.Fatalf("failed to create bot: %v", err)
+	}
+
+	bot.SendMessage(tgbotapi.NewMessage(1, "hello"))
+	bot.Request(tgbotapi.ChatAdministratorsConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: -100}})
+
+	var b strings.Builder
+	if _, err := collector.WriteTo(&b); err != nil {
+		t.Fatalf("failed to write metrics: %v", err)
+	}
+	output := b.String()
+
+	// Calls are labeled with Bot API methods
+	for _, line := range []string{
+		`tgfsm_api_errors_total{method="sendMessage",code="403"} 1`,
+		`tgfsm_api_errors_total{method="getChatAdministrators",code="0"} 1`,
+	} {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("no line %q in:\n%s", line, output)
+		}
+	}
+}
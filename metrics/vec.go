@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// labelSep joins label values into map keys, it cannot appear in valid UTF-8 text
+const labelSep = "\xff"
+
+// counterVec is a counter with labels
+type counterVec struct {
+	name, help string
+	labels     []string
+	values     map[string]float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, values: make(map[string]float64)}
+}
+
+// add increases the counter with the label values
+func (c *counterVec) add(value float64, labelValues ...string) {
+	c.values[strings.Join(labelValues, labelSep)] += value
+}
+
+// write writes the counter in Prometheus text format
+func (c *counterVec) write(w *bufio.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+	for _, key := range sortedKeys(c.values) {
+		writeSample(w, c.name, c.labels, key, nil, c.values[key])
+	}
+}
+
+// writeGauge writes a gauge with labels in Prometheus text format
+func writeGauge(w *bufio.Writer, name, help string, labels []string, values map[string]float64) {
+	writeHeader(w, name, help, "gauge")
+	for _, key := range sortedKeys(values) {
+		writeSample(w, name, labels, key, nil, values[key])
+	}
+}
+
+// histogram is the state of a histogram with one set of label values
+type histogram struct {
+	counts []uint64 // Observations in each bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// histogramVec is a histogram with labels
+type histogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	values     map[string]*histogram
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &histogramVec{name: name, help: help, labels: labels, buckets: buckets, values: make(map[string]*histogram)}
+}
+
+// observe adds the value to the histogram with the label values
+func (h *histogramVec) observe(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, labelSep)
+	hist, ok := h.values[key]
+	if !ok {
+		hist = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hist
+	}
+
+	hist.count++
+	hist.sum += value
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		hist.counts[i]++
+	}
+}
+
+// write writes the histogram in Prometheus text format
+func (h *histogramVec) write(w *bufio.Writer) {
+	writeHeader(w, h.name, h.help, "histogram")
+
+	keys := make([]string, 0, len(h.values))
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		hist := h.values[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += hist.counts[i]
+			writeSample(w, h.name+"_bucket", h.labels, key, []string{"le", formatFloat(bound)}, float64(cumulative))
+		}
+		writeSample(w, h.name+"_bucket", h.labels, key, []string{"le", "+Inf"}, float64(hist.count))
+		writeSample(w, h.name+"_sum", h.labels, key, nil, hist.sum)
+		writeSample(w, h.name+"_count", h.labels, key, nil, float64(hist.count))
+	}
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	w.WriteString("# HELP " + name + " " + strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help) + "\n")
+	w.WriteString("# TYPE " + name + " " + kind + "\n")
+}
+
+// writeSample writes a sample line; key holds the joined label values,
+// extra is an additional label name and value (the "le" label of histograms)
+func writeSample(w *bufio.Writer, name string, labels []string, key string, extra []string, value float64) {
+	w.WriteString(name)
+
+	var values []string
+	if len(labels) > 0 {
+		values = strings.Split(key, labelSep)
+	}
+	if len(labels) > 0 || len(extra) > 0 {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(label + `="` + escapeLabel(values[i]) + `"`)
+		}
+		if len(extra) > 0 {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extra[0] + `="` + escapeLabel(extra[1]) + `"`)
+		}
+		w.WriteByte('}')
+	}
+
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+// escapeLabel escapes a label value for the text format
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatFloat formats a sample value for the text format
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// sortedKeys returns the keys of the map in order
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestWriteTextFormat(t *testing.T) {
+	counter := newCounterVec("test_total", "Counter with \\ and\nnewline.", "state")
+	counter.add(1, "plain")
+	counter.add(2, `quote " backslash \ newline`+"\n")
+	counter.add(0.5, "plain")
+
+	hist := newHistogramVec("test_seconds", "Histogram.", []float64{1, 0.1}, "kind")
+	for _, value := range []float64{0.05, 0.1, 0.5, 1, 2} {
+		hist.observe(value, "api")
+	}
+
+	var b strings.Builder
+	w := bufio.NewWriter(&b)
+	counter.write(w)
+	hist.write(w)
+	writeGauge(w, "test_users", "Gauge.", []string{"state"}, map[string]float64{"b": 2, "a": math.Inf(1)})
+	writeGauge(w, "test_up", "Gauge without labels.", nil, map[string]float64{"": 1})
+	w.Flush()
+
+	const golden = `# HELP test_total Counter with \\ and\nnewline.
+# TYPE test_total counter
+test_total{state="plain"} 1.5
+test_total{state="quote \" backslash \\ newline\n"} 2
+# HELP test_seconds Histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{kind="api",le="0.1"} 2
+test_seconds_bucket{kind="api",le="1"} 4
+test_seconds_bucket{kind="api",le="+Inf"} 5
+test_seconds_sum{kind="api"} 3.65
+test_seconds_count{kind="api"} 5
+# HELP test_users Gauge.
+# TYPE test_users gauge
+test_users{state="a"} +Inf
+test_users{state="b"} 2
+# HELP test_up Gauge without labels.
+# TYPE test_up gauge
+test_up 1
+`
+	if got := b.String(); got != golden {
+		t.Fatalf("output differs from golden:\n--- got\n%s--- want\n%s", got, golden)
+	}
+}
@@ -1,8 +1,11 @@
 package tgfsm
 
 import (
+	"log/slog"
+	"net/http"
 	"time"
 
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"go.uber.org/zap"
 )
 
@@ -25,18 +28,112 @@ func WithCleanupInterval(cleanupInterval time.Duration) Option {
 
 // WithStates sets the states for the bot
 func WithStates(states map[string]State) Option {
-	return func(b *Bot) {
+	return reloadable(func(b *Bot) {
 		b.states = states
+	})
+}
+
+// WithInitialState sets the state of users who have no state stored:
+// new users and users whose state was reset or expired
+// The state must be present in states (see WithStates)
+func WithInitialState(state string) Option {
+	return reloadable(func(b *Bot) {
+		b.initialState = state
+	})
+}
+
+// WithOrphanState sets the state of users whose current state was removed by Reload
+// If not set, the state of such users is reset (see ResetUserState)
+// The state must be present in states (see WithStates)
+func WithOrphanState(state string) Option {
+	return reloadable(func(b *Bot) {
+		b.orphanState = state
+	})
+}
+
+// WithStrictValidation makes NewBot fail if ValidateStates finds problems in states
+// By default the problems are only logged as warnings
+func WithStrictValidation(strict bool) Option {
+	return reloadable(func(b *Bot) {
+		b.strictValidation = strict
+	})
+}
+
+// WithAPIEndpoint sets the Bot API endpoint, e.g. of a local Bot API server
+// The endpoint is a format string with the token and the method, like tgbotapi.APIEndpoint:
+// "https://api.telegram.org/bot%s/%s"
+func WithAPIEndpoint(endpoint string) Option {
+	return func(b *Bot) {
+		b.apiEndpoint = endpoint
 	}
 }
 
-// WithLogger sets the logger for the bot
-func WithLogger(logger *zap.Logger) Option {
+// WithHTTPClient sets the HTTP client for Bot API requests
+func WithHTTPClient(client *http.Client) Option {
 	return func(b *Bot) {
-		b.logger = logger
+		b.httpClient = client
 	}
 }
 
+// WithClient sets the Telegram Bot API client, e.g. a decorator of the default one or a test double
+// The token is not used to connect then, and Bot.BotAPI is nil unless the client is a *tgbotapi.BotAPI.
+func WithClient(client Client) Option {
+	return func(b *Bot) {
+		b.client = client
+		b.BotAPI, _ = client.(*tgbotapi.BotAPI)
+	}
+}
+
+// WithMetrics sets the receiver of monitoring events, e.g. metrics.New from the package tgfsm/metrics
+func WithMetrics(metrics Metrics) Option {
+	return func(b *Bot) {
+		b.metrics = metrics
+	}
+}
+
+// WithTracer sets the tracer of update processing and Bot API calls (see Tracer)
+func WithTracer(tracer Tracer) Option {
+	return func(b *Bot) {
+		b.tracer = tracer
+	}
+}
+
+// WithLimiter sets the limiter of Bot API requests (see NewLimiter and NewUnlimitedLimiter)
+func WithLimiter(limiter *Limiter) Option {
+	return reloadable(func(b *Bot) {
+		b.limiter = limiter
+	})
+}
+
+// WithLogger sets the zap logger for the bot
+func WithLogger(logger *zap.Logger) Option {
+	return reloadable(func(b *Bot) {
+		if logger == nil {
+			b.logger = nil
+			return
+		}
+		b.logger = NewZapAdapter(logger)
+	})
+}
+
+// WithSlogLogger sets the log/slog logger for the bot
+func WithSlogLogger(logger *slog.Logger) Option {
+	return reloadable(func(b *Bot) {
+		if logger == nil {
+			b.logger = nil
+			return
+		}
+		b.logger = NewSlogAdapter(logger)
+	})
+}
+
+// WithCustomLogger sets the logger for the bot, e.g. an adapter of another logging library
+func WithCustomLogger(logger Logger) Option {
+	return reloadable(func(b *Bot) {
+		b.logger = logger
+	})
+}
+
 // WithUpdateHandler sets the update handler for the bot
 func WithUpdateHandler(updateHandler HandlerFunc) Option {
 	return func(b *Bot) {
@@ -51,13 +148,73 @@ func WithPrivateOnly(privateOnly bool) Option {
 	}
 }
 
-// WithBlacklistedChats sets the list of blacklisted chat IDs
+// WithBlacklistedChats bans the chats permanently in this bot
+// The bans are kept in memory only and are not added to the blacklist store (see WithBlacklistStore),
+// so they are not shared with other bots and removing a chat from the option unbans it.
+// Removals made by other bots do not unban them; Unban does until the option is set again.
 func WithBlacklistedChats(chatIDs []int64) Option {
 	return func(b *Bot) {
-		b.blacklistedChats = make(map[int64]bool)
-		for _, chatID := range chatIDs {
-			b.blacklistedChats[chatID] = true
+		b.blacklistedChats = append([]int64(nil), chatIDs...)
+	}
+}
+
+// WithAllowlist serves only the users, updates of other users are denied (see WithAccessDeniedHandler)
+// Combined with WithAllowedRoles, users having one of the roles are served too.
+func WithAllowlist(userIDs []int64) Option {
+	return reloadable(func(b *Bot) {
+		b.allowedUsers = make(map[int64]bool, len(userIDs))
+		for _, userID := range userIDs {
+			b.allowedUsers[userID] = true
 		}
+	})
+}
+
+// WithAllowedRoles serves only users having one of the roles (see WithRoles),
+// updates of other users are denied (see WithAccessDeniedHandler)
+func WithAllowedRoles(roles ...string) Option {
+	return reloadable(func(b *Bot) {
+		b.allowedRoles = roles
+	})
+}
+
+// WithRoles sets the providers of user roles checked for State.Roles, Handler.Roles
+// and WithAllowedRoles, e.g. NewMemoryRoleStore and ChatAdminRoles
+// A user has the roles given by all providers.
+func WithRoles(providers ...RoleProvider) Option {
+	return reloadable(func(b *Bot) {
+		b.roleProviders = providers
+	})
+}
+
+// WithAccessDeniedHandler sets the handler of updates denied by the allowlist or roles,
+// e.g. to answer "access denied". Denied updates are ignored by default.
+func WithAccessDeniedHandler(handler AccessDeniedFunc) Option {
+	return reloadable(func(b *Bot) {
+		b.accessDenied = handler
+	})
+}
+
+// WithFloodControl limits incoming updates per user or chat (see FloodControl)
+func WithFloodControl(control FloodControl) Option {
+	return reloadable(func(b *Bot) {
+		b.flood = newFloodLimiter(control)
+	})
+}
+
+// WithBlacklistStore sets the storage of the blacklist, NewMemoryBlacklistStore by default
+// Bots sharing a store (e.g. replicas using Redis or PostgreSQL) share the blacklist.
+func WithBlacklistStore(store BlacklistStore) Option {
+	return func(b *Bot) {
+		b.blacklistStore = store
+		b.blacklistStoreChanged = true
+	}
+}
+
+// WithBlacklistHook sets the function called for every change of the blacklist store,
+// including changes made by other bots sharing the store
+func WithBlacklistHook(hook BlacklistHook) Option {
+	return func(b *Bot) {
+		b.blacklistHook = hook
 	}
 }
 
@@ -76,3 +233,11 @@ func WithLastMessageCache(cache LastMessageCache) Option {
 		b.lastMessageCache = cache
 	}
 }
+
+// WithMaxStateHistory sets how many previous states are kept per user for PopUserState
+// Older entries are dropped when the limit is exceeded
+func WithMaxStateHistory(size int) Option {
+	return func(b *Bot) {
+		b.maxStateHistory = size
+	}
+}
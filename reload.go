@@ -0,0 +1,239 @@
+package tgfsm
+
+import (
+	"strconv"
+)
+
+// reloadable marks the option as accepted by Reload
+// Reload applies every option to a probe bot first and rejects the options that do not set the mark.
+func reloadable(option Option) Option {
+	return func(b *Bot) {
+		b.reloadableOption = true
+		option(b)
+	}
+}
+
+// isReloadable reports whether the option is marked by reloadable
+func isReloadable(option Option) bool {
+	var probe Bot
+	option(&probe)
+	return probe.reloadableOption
+}
+
+// stateSet is an immutable snapshot of the states configuration
+// The running bot reads states only through the current snapshot (see Bot.stateSet),
+// so Reload can replace them without stopping update processing.
+type stateSet struct {
+	states       map[string]State
+	globalStates []globalState
+	initialState string
+	orphanState  string
+}
+
+// newStateSet builds a snapshot of the states configuration
+// States of the snapshot know their keys, so handlers can be reported by state (see Metrics).
+func newStateSet(states map[string]State, initialState, orphanState string) *stateSet {
+	keyed := make(map[string]State, len(states))
+	for name, state := range states {
+		state.key = name
+		keyed[name] = state
+	}
+	states = keyed
+
+	return &stateSet{
+		states:       states,
+		globalStates: sortGlobalStates(states),
+		initialState: initialState,
+		orphanState:  orphanState,
+	}
+}
+
+// stateSet returns the current snapshot of the states configuration
+func (app *Bot) stateSet() *stateSet {
+	if set := app.current.Load(); set != nil {
+		return set
+	}
+	// The bot was created without NewBot
+	return newStateSet(app.states, app.initialState, app.orphanState)
+}
+
+// runtimeSettings is an immutable snapshot of the options Reload can replace besides the states:
+// the logger, the limiter, flood control, the allowlist, role providers and the access denied handler
+// Update processing reads them only through the current snapshot (see Bot.settings).
+type runtimeSettings struct {
+	logger        Logger
+	limiter       *Limiter
+	flood         *floodLimiter
+	allowedUsers  map[int64]bool
+	allowedRoles  []string
+	roleProviders []RoleProvider
+	accessDenied  AccessDeniedFunc
+}
+
+// newRuntimeSettings builds a snapshot of the runtime options set on the bot
+func (app *Bot) newRuntimeSettings() *runtimeSettings {
+	return &runtimeSettings{
+		logger:        app.logger,
+		limiter:       app.limiter,
+		flood:         app.flood,
+		allowedUsers:  app.allowedUsers,
+		allowedRoles:  app.allowedRoles,
+		roleProviders: app.roleProviders,
+		accessDenied:  app.accessDenied,
+	}
+}
+
+// settings returns the current snapshot of the runtime options
+func (app *Bot) settings() *runtimeSettings {
+	if settings := app.live.Load(); settings != nil {
+		return settings
+	}
+	// The bot was created without NewBot
+	return app.newRuntimeSettings()
+}
+
+// States returns the current states of the bot
+// The map must not be modified: use Reload to change the states.
+func (app *Bot) States() map[string]State {
+	return app.stateSet().states
+}
+
+// Reload atomically replaces the states and the runtime options of a running or stopped bot
+// User states, history and other stored data are kept.
+// Accepted options are those of the states configuration:
+// WithStates, WithInitialState, WithOrphanState and WithStrictValidation;
+// and the runtime options: WithLogger, WithSlogLogger, WithCustomLogger, WithLimiter,
+// WithFloodControl, WithAllowlist, WithAllowedRoles, WithRoles and WithAccessDeniedHandler.
+// Other options return ErrOptionNotReloadable and nothing is changed;
+// use UpdateBot on a stopped bot to change them.
+// Settings not given by the options are kept, e.g. the current states without WithStates.
+// Updates being processed finish with the settings they started with.
+//
+// Users whose current state no longer exists are moved to the orphan state (see WithOrphanState).
+// The new configuration is validated like in NewBot; on error nothing is changed.
+func (app *Bot) Reload(options ...Option) error {
+	for i, option := range options {
+		if !isReloadable(option) {
+			return NewSFMError(ErrOptionNotReloadable, "option "+strconv.Itoa(i))
+		}
+	}
+
+	app.reloadMu.Lock()
+	defer app.reloadMu.Unlock()
+
+	current := app.stateSet()
+	settings := app.settings()
+	next := Bot{
+		states:           current.states,
+		initialState:     current.initialState,
+		orphanState:      current.orphanState,
+		strictValidation: app.strictValidation,
+		logger:           settings.logger,
+		limiter:          settings.limiter,
+		flood:            settings.flood,
+		allowedUsers:     settings.allowedUsers,
+		allowedRoles:     settings.allowedRoles,
+		roleProviders:    settings.roleProviders,
+		accessDenied:     settings.accessDenied,
+	}
+	for _, option := range options {
+		option(&next)
+	}
+	if next.states == nil {
+		return ErrStatesNil
+	}
+	// Unset logger and limiter get the defaults of NewBot
+	if next.logger == nil {
+		logger, err := newDefaultLogger()
+		if err != nil {
+			return err
+		}
+		next.logger = logger
+	}
+	if next.limiter == nil {
+		next.limiter = NewLimiter()
+	}
+
+	if err := next.validateConfig(); err != nil {
+		return err
+	}
+
+	set := newStateSet(next.states, next.initialState, next.orphanState)
+	app.strictValidation = next.strictValidation
+	app.states = next.states
+	app.initialState = next.initialState
+	app.orphanState = next.orphanState
+	app.logger = next.logger
+	app.limiter = next.limiter
+	app.flood = next.flood
+	app.allowedUsers = next.allowedUsers
+	app.allowedRoles = next.allowedRoles
+	app.roleProviders = next.roleProviders
+	app.accessDenied = next.accessDenied
+	app.current.Store(set)
+	app.live.Store(app.newRuntimeSettings())
+	app.Logger().Info("states reloaded", LogField("states", len(set.states)))
+
+	app.recoverOrphans(set)
+	return nil
+}
+
+// ReloadStates replaces the states of the bot keeping the other settings (see Reload)
+func (app *Bot) ReloadStates(states map[string]State) error {
+	return app.Reload(WithStates(states))
+}
+
+// validateConfig checks the states configuration
+func (app *Bot) validateConfig() error {
+	if app.initialState != "" {
+		if _, ok := app.states[app.initialState]; !ok {
+			return NewSFMError(ErrInitialStateNotFound, app.initialState)
+		}
+	}
+	if app.orphanState != "" {
+		if _, ok := app.states[app.orphanState]; !ok {
+			return NewSFMError(ErrOrphanStateNotFound, app.orphanState)
+		}
+	}
+	return app.validateStates()
+}
+
+// recoverOrphans moves users whose stored state does not exist in the snapshot
+// Only the in-memory storage can be scanned; other users are recovered on their next update.
+func (app *Bot) recoverOrphans(set *stateSet) {
+	if app.cache == nil {
+		return
+	}
+
+	for key, item := range app.cache.Items() {
+		state, ok := item.Object.(string)
+		if !ok {
+			continue
+		}
+		userId, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			continue
+		}
+		if _, ok := set.states[state]; !ok {
+			app.recoverOrphan(userId, state)
+		}
+	}
+}
+
+// recoverOrphan moves the user out of a state that no longer exists:
+// to the orphan state if it is configured, otherwise the user's state is reset
+func (app *Bot) recoverOrphan(userId int64, state string) {
+	set := app.stateSet()
+	app.Logger().Warn("user state no longer exists",
+		LogField("user_id", userId),
+		LogField("state", state),
+		LogField("orphan_state", set.orphanState),
+	)
+
+	if set.orphanState == "" {
+		app.resetUserState(userId, nil)
+		return
+	}
+	// The lost state cannot restrict the transition, so it is not checked
+	app.runTransitionHooks(app.storeUserState(userId, set.orphanState, nil))
+}
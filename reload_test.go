@@ -0,0 +1,92 @@
+package tgfsm
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+func TestIsReloadable(t *testing.T) {
+	tests := []struct {
+		name       string
+		option     Option
+		reloadable bool
+	}{
+		{"states", WithStates(map[string]State{"home": {}}), true},
+		{"initial state", WithInitialState("home"), true},
+		{"orphan state", WithOrphanState("home"), true},
+		{"strict validation", WithStrictValidation(false), true},
+		{"logger", WithLogger(nil), true},
+		{"limiter", WithLimiter(NewUnlimitedLimiter()), true},
+		{"flood control", WithFloodControl(FloodControl{}), true},
+		{"allowlist", WithAllowlist(nil), true},
+		{"allowed roles", WithAllowedRoles("admin"), true},
+		{"roles", WithRoles(), true},
+		{"access denied handler", WithAccessDeniedHandler(nil), true},
+		{"expiration", WithExpiration(time.Hour), false},
+		{"private only", WithPrivateOnly(false), false},
+		{"custom option", func(b *Bot) {}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if reloadable := isReloadable(tt.option); reloadable != tt.reloadable {
+				t.Fatalf("reloadable %v, want %v", reloadable, tt.reloadable)
+			}
+		})
+	}
+}
+
+func TestReloadRejectsOtherOptions(t *testing.T) {
+	bot := &Bot{states: map[string]State{"home": {}}}
+
+	err := bot.Reload(WithStates(map[string]State{"menu": {}}), WithExpiration(time.Hour))
+	if !errors.Is(err, ErrOptionNotReloadable) {
+		t.Fatalf("error %v, want ErrOptionNotReloadable", err)
+	}
+	if _, ok := bot.States()["home"]; !ok {
+		t.Fatalf("states %v changed by a rejected reload", bot.States())
+	}
+}
+
+func TestReloadRuntimeOptions(t *testing.T) {
+	b := newHooksBot(t, map[string]State{"menu": {}})
+	update := tgbotapi.Update{Message: &tgbotapi.Message{From: &tgbotapi.User{ID: 2}, Chat: &tgbotapi.Chat{ID: 2}}}
+	logger := NewZapAdapter(zap.NewNop())
+	limiter := NewUnlimitedLimiter()
+
+	if err := b.Reload(WithCustomLogger(logger), WithLimiter(limiter), WithAllowlist([]int64{1})); err != nil {
+		t.Fatal(err)
+	}
+	if b.Logger() != logger || b.settings().limiter != limiter {
+		t.Fatal("logger or limiter not replaced")
+	}
+	if err := b.checkAllowlist(update); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("error %v, want the user denied by the reloaded allowlist", err)
+	}
+
+	// Options not given are kept, an invalid configuration changes nothing
+	err := b.Reload(WithAllowlist(nil), WithInitialState("missing"))
+	if !errors.Is(err, ErrInitialStateNotFound) {
+		t.Fatalf("error %v, want ErrInitialStateNotFound", err)
+	}
+	if err := b.checkAllowlist(update); err == nil {
+		t.Fatal("allowlist changed by a failed reload")
+	}
+
+	if err := b.Reload(WithAllowlist(nil), WithLogger(nil), WithLimiter(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.checkAllowlist(update); err != nil {
+		t.Fatalf("error %v, want the allowlist off", err)
+	}
+	if b.Logger() == nil || b.Logger() == logger || b.settings().limiter == nil || b.settings().limiter == limiter {
+		t.Fatal("unset logger and limiter do not get the defaults")
+	}
+	if _, ok := b.States()["menu"]; !ok {
+		t.Fatalf("states %v not kept", b.States())
+	}
+}
@@ -0,0 +1,314 @@
+package tgfsm
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// DefaultChatAdminsTTL is the default time the administrators of a chat are cached by ChatAdminRoles
+const DefaultChatAdminsTTL = 5 * time.Minute
+
+// RoleProvider assigns roles to users (see WithRoles)
+// chatID is the chat of the update being processed, 0 if unknown.
+// Implementations must be safe for concurrent use.
+type RoleProvider interface {
+	UserRoles(b *Bot, userID, chatID int64) ([]string, error)
+}
+
+// AccessDeniedFunc handles updates of users denied by the allowlist or by roles
+// (see WithAccessDeniedHandler). roles are the roles that would allow the update.
+type AccessDeniedFunc func(b *Bot, u tgbotapi.Update, roles []string) error
+
+// MemoryRoleStore is a RoleProvider with roles assigned to user IDs in memory
+type MemoryRoleStore struct {
+	mu    sync.RWMutex
+	roles map[int64][]string
+}
+
+// Ensure MemoryRoleStore implements RoleProvider interface
+var _ RoleProvider = (*MemoryRoleStore)(nil)
+
+// NewMemoryRoleStore creates a role store with the roles of users
+func NewMemoryRoleStore(roles map[int64][]string) *MemoryRoleStore {
+	store := &MemoryRoleStore{roles: make(map[int64][]string, len(roles))}
+	for userID, userRoles := range roles {
+		store.roles[userID] = slices.Clone(userRoles)
+	}
+	return store
+}
+
+// UserRoles returns the roles of the user
+func (s *MemoryRoleStore) UserRoles(_ *Bot, userID, _ int64) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.roles[userID]), nil
+}
+
+// SetUserRoles replaces the roles of the user, no roles removes the user
+func (s *MemoryRoleStore) SetUserRoles(userID int64, roles ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(roles) == 0 {
+		delete(s.roles, userID)
+		return
+	}
+	s.roles[userID] = slices.Clone(roles)
+}
+
+// AddUserRole adds the role to the user
+func (s *MemoryRoleStore) AddUserRole(userID int64, role string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !slices.Contains(s.roles[userID], role) {
+		s.roles[userID] = append(s.roles[userID], role)
+	}
+}
+
+// RemoveUserRole removes the role from the user
+func (s *MemoryRoleStore) RemoveUserRole(userID int64, role string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	roles := slices.DeleteFunc(slices.Clone(s.roles[userID]), func(r string) bool { return r == role })
+	if len(roles) == 0 {
+		delete(s.roles, userID)
+		return
+	}
+	s.roles[userID] = roles
+}
+
+// chatAdmins is the cached list of administrators of a chat
+type chatAdmins struct {
+	users   map[int64]bool
+	expires time.Time
+}
+
+// chatAdminRoles gives a role to administrators of a chat
+type chatAdminRoles struct {
+	chatID int64
+	role   string
+	ttl    time.Duration
+
+	mu       sync.Mutex
+	chats    map[int64]chatAdmins
+	inFlight map[int64]*adminsRequest
+}
+
+// adminsRequest is a getChatAdministrators request shared by concurrent lookups of a chat
+type adminsRequest struct {
+	done  chan struct{}
+	users map[int64]bool
+	err   error
+}
+
+// ChatAdminRoles returns a RoleProvider giving the role to administrators of the chat,
+// e.g. of the team's group. A zero chatID means the chat of the update, so administrators
+// of a group get the role in that group. Administrators are requested with getChatAdministrators
+// and cached for ttl (DefaultChatAdminsTTL if zero).
+func ChatAdminRoles(chatID int64, role string, ttl time.Duration) RoleProvider {
+	if ttl <= 0 {
+		ttl = DefaultChatAdminsTTL
+	}
+	return &chatAdminRoles{
+		chatID:   chatID,
+		role:     role,
+		ttl:      ttl,
+		chats:    make(map[int64]chatAdmins),
+		inFlight: make(map[int64]*adminsRequest),
+	}
+}
+
+// UserRoles returns the role if the user administers the chat
+func (p *chatAdminRoles) UserRoles(b *Bot, userID, chatID int64) ([]string, error) {
+	if p.chatID != 0 {
+		chatID = p.chatID
+	}
+	// Private chats have no administrators
+	if chatID >= 0 {
+		return nil, nil
+	}
+
+	admins, err := p.admins(b, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if admins[userID] {
+		return []string{p.role}, nil
+	}
+	return nil, nil
+}
+
+// admins returns the administrators of the chat, requesting them when the cache expires
+// Concurrent lookups of a chat wait for a single request.
+func (p *chatAdminRoles) admins(b *Bot, chatID int64) (map[int64]bool, error) {
+	p.mu.Lock()
+	if cached, ok := p.chats[chatID]; ok && time.Now().Before(cached.expires) {
+		p.mu.Unlock()
+		return cached.users, nil
+	}
+	if request, ok := p.inFlight[chatID]; ok {
+		p.mu.Unlock()
+		<-request.done
+		return request.users, request.err
+	}
+	request := &adminsRequest{done: make(chan struct{})}
+	p.inFlight[chatID] = request
+	p.mu.Unlock()
+
+	request.users, request.err = requestChatAdmins(b, chatID)
+
+	p.mu.Lock()
+	delete(p.inFlight, chatID)
+	if request.err == nil {
+		p.store(chatID, request.users)
+	}
+	p.mu.Unlock()
+	close(request.done)
+	return request.users, request.err
+}
+
+// store caches the administrators of the chat and drops expired chats,
+// so the cache does not grow with every group the bot sees. Must be called with mu held.
+func (p *chatAdminRoles) store(chatID int64, users map[int64]bool) {
+	now := time.Now()
+	for id, cached := range p.chats {
+		if !now.Before(cached.expires) {
+			delete(p.chats, id)
+		}
+	}
+	p.chats[chatID] = chatAdmins{users: users, expires: now.Add(p.ttl)}
+}
+
+// requestChatAdmins requests the administrators of the chat with getChatAdministrators
+func requestChatAdmins(b *Bot, chatID int64) (map[int64]bool, error) {
+	response, err := b.Request(tgbotapi.ChatAdministratorsConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: chatID}})
+	if err != nil {
+		return nil, err
+	}
+	var members []tgbotapi.ChatMember
+	if err := json.Unmarshal(response.Result, &members); err != nil {
+		return nil, err
+	}
+
+	users := make(map[int64]bool, len(members))
+	for _, member := range members {
+		if member.User != nil {
+			users[member.User.ID] = true
+		}
+	}
+	return users, nil
+}
+
+// UserRoles returns the roles of the user from all role providers (see WithRoles)
+// chatID is the chat where the roles are checked, 0 if unknown.
+func (b *Bot) UserRoles(userID, chatID int64) ([]string, error) {
+	var roles []string
+	for _, provider := range b.settings().roleProviders {
+		userRoles, err := provider.UserRoles(b, userID, chatID)
+		if err != nil {
+			return nil, err
+		}
+		for _, role := range userRoles {
+			if !slices.Contains(roles, role) {
+				roles = append(roles, role)
+			}
+		}
+	}
+	return roles, nil
+}
+
+// HasRole reports whether the user has one of the roles in the chat
+func (b *Bot) HasRole(userID, chatID int64, roles ...string) (bool, error) {
+	userRoles, err := b.UserRoles(userID, chatID)
+	if err != nil {
+		return false, err
+	}
+	for _, role := range roles {
+		if slices.Contains(userRoles, role) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// allowlistEnabled reports whether only allowlisted users are served
+func (s *runtimeSettings) allowlistEnabled() bool {
+	return len(s.allowedUsers) > 0 || len(s.allowedRoles) > 0
+}
+
+// checkAllowlist checks that the sender of the update is allowlisted by ID or role
+// Role provider errors deny the update.
+func (b *Bot) checkAllowlist(update tgbotapi.Update) error {
+	settings := b.settings()
+	if !settings.allowlistEnabled() {
+		return nil
+	}
+	user := update.SentFrom()
+	if user == nil {
+		return NewSFMError(ErrAccessDenied, "no user")
+	}
+	if settings.allowedUsers[user.ID] {
+		return nil
+	}
+	if len(settings.allowedRoles) > 0 {
+		return b.checkRoles(update, settings.allowedRoles)
+	}
+	return NewSFMError(ErrAccessDenied, user.ID)
+}
+
+// checkRoles checks that the sender of the update has one of the roles, no roles allow everyone
+// An empty list is no requirement too, e.g. "roles: []" of a definition.
+func (b *Bot) checkRoles(update tgbotapi.Update, roles []string) error {
+	if len(roles) == 0 {
+		return nil
+	}
+	user := update.SentFrom()
+	if user == nil {
+		return NewSFMError(ErrAccessDenied, "no user")
+	}
+
+	var chatID int64
+	if chat := update.FromChat(); chat != nil {
+		chatID = chat.ID
+	}
+	allowed, err := b.HasRole(user.ID, chatID, roles...)
+	if err != nil {
+		return NewSFMError(ErrAccessDenied, err)
+	}
+	if !allowed {
+		return NewSFMError(ErrAccessDenied, user.ID)
+	}
+	return nil
+}
+
+// authorizeHandler checks the roles of the state and the handler for the update
+func (b *Bot) authorizeHandler(update tgbotapi.Update, state *State, handler *Handler) ([]string, error) {
+	if err := b.checkRoles(update, state.Roles); err != nil {
+		return state.Roles, err
+	}
+	if err := b.checkRoles(update, handler.Roles); err != nil {
+		return handler.Roles, err
+	}
+	return nil, nil
+}
+
+// denyAccess reports the denied update and runs the access denied handler
+// roles are the roles that would allow the update.
+func (b *Bot) denyAccess(update tgbotapi.Update, reason string, roles []string, err error) {
+	b.metrics.UpdateFiltered(UpdateType(update), reason)
+	b.updateLogger(update, "", "").Info("access denied", LogField("reason", reason), ErrorField(err))
+
+	accessDenied := b.settings().accessDenied
+	if accessDenied == nil {
+		return
+	}
+	if err := accessDenied(b, update, roles); err != nil {
+		b.updateLogger(update, "", "").Error("failed to handle access denied", ErrorField(err))
+	}
+}
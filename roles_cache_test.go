@@ -0,0 +1,23 @@
+package tgfsm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChatAdminRolesDropsExpiredChats(t *testing.T) {
+	p := ChatAdminRoles(0, "moderator", time.Minute).(*chatAdminRoles)
+
+	p.store(-1, map[int64]bool{1: true})
+	p.store(-2, map[int64]bool{2: true})
+	p.chats[-1] = chatAdmins{users: p.chats[-1].users, expires: time.Now().Add(-time.Second)}
+
+	p.store(-3, map[int64]bool{3: true})
+
+	if _, ok := p.chats[-1]; ok {
+		t.Error("expired chat is kept")
+	}
+	if len(p.chats) != 2 {
+		t.Errorf("%d chats cached, want 2", len(p.chats))
+	}
+}
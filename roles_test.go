@@ -0,0 +1,256 @@
+package tgfsm_test
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"tgfsm"
+	"tgfsm/tgfsmtest"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// reply returns a handler answering the text in the chat of the update
+func reply(text string) tgfsm.HandlerFunc {
+	return func(b *tgfsm.Bot, u tgbotapi.Update) error {
+		_, err := b.SendMessage(tgbotapi.NewMessage(u.FromChat().ID, text))
+		return err
+	}
+}
+
+// deniedRecorder records the roles passed to the access denied handler
+type deniedRecorder struct {
+	mu    sync.Mutex
+	roles [][]string
+}
+
+func (r *deniedRecorder) handle(_ *tgfsm.Bot, _ tgbotapi.Update, roles []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.roles = append(r.roles, roles)
+	return nil
+}
+
+func (r *deniedRecorder) calls() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.roles
+}
+
+// failingRoles is a role provider that always fails
+type failingRoles struct{}
+
+func (failingRoles) UserRoles(*tgfsm.Bot, int64, int64) ([]string, error) {
+	return nil, errors.New("role storage is down")
+}
+
+// newRolesBot creates a bot with the states:
+// home (no roles) with /help, /ban (moderator) and /open (empty roles);
+// staff (admin) with /report
+func newRolesBot(t *testing.T, denied *deniedRecorder, options ...tgfsm.Option) (*tgfsmtest.Server, *tgfsm.Bot) {
+	t.Helper()
+
+	server := tgfsmtest.NewServer()
+	t.Cleanup(server.Close)
+
+	states := map[string]tgfsm.State{
+		"home": {MessageHandlers: map[string]tgfsm.Handler{
+			"/help": {Handle: reply("help")},
+			"/ban":  {Handle: reply("banned"), Roles: []string{"moderator"}},
+			"/open": {Handle: reply("open"), Roles: []string{}},
+		}},
+		"staff": {
+			Roles: []string{"admin"},
+			MessageHandlers: map[string]tgfsm.Handler{
+				"/report": {Handle: reply("report")},
+				"/ban":    {Handle: reply("banned"), Roles: []string{"moderator"}},
+			},
+		},
+		"public": {
+			Roles:           []string{},
+			MessageHandlers: map[string]tgfsm.Handler{"/help": {Handle: reply("help")}},
+		},
+	}
+	options = append([]tgfsm.Option{
+		tgfsm.WithStates(states),
+		tgfsm.WithInitialState("home"),
+		tgfsm.WithAccessDeniedHandler(denied.handle),
+	}, options...)
+
+	bot, err := tgfsmtest.NewBot(server, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, bot
+}
+
+// sentTexts returns the texts of the messages sent by the bot
+func sentTexts(server *tgfsmtest.Server) []string {
+	var texts []string
+	for _, request := range server.Sent() {
+		texts = append(texts, request.Text())
+	}
+	return texts
+}
+
+func TestAllowlist(t *testing.T) {
+	var dispatched []int64
+	denied := &deniedRecorder{}
+	server, bot := newRolesBot(t, denied,
+		tgfsm.WithAllowlist([]int64{1}),
+		tgfsm.WithAllowedRoles("staff"),
+		tgfsm.WithRoles(tgfsm.NewMemoryRoleStore(map[int64][]string{2: {"staff"}})),
+		tgfsm.WithUpdateHandler(func(b *tgfsm.Bot, u tgbotapi.Update) error {
+			dispatched = append(dispatched, u.SentFrom().ID)
+			return nil
+		}),
+	)
+
+	for _, userID := range []int64{1, 2, 3} {
+		bot.ProcessUpdate(tgfsmtest.NewMessageUpdate(userID, "/help"))
+	}
+
+	if texts := sentTexts(server); !reflect.DeepEqual(texts, []string{"help", "help"}) {
+		t.Errorf("sent %q, want help for the allowlisted user and the user with the role", texts)
+	}
+	if !reflect.DeepEqual(dispatched, []int64{1, 2}) {
+		t.Errorf("update handler called for users %v, want [1 2]: denied users are dropped before dispatch", dispatched)
+	}
+	if calls := denied.calls(); !reflect.DeepEqual(calls, [][]string{{"staff"}}) {
+		t.Errorf("access denied handler called with %q, want the allowed roles once", calls)
+	}
+}
+
+func TestAllowlistRoleProviderError(t *testing.T) {
+	denied := &deniedRecorder{}
+	server, bot := newRolesBot(t, denied,
+		tgfsm.WithAllowedRoles("staff"),
+		tgfsm.WithRoles(failingRoles{}),
+	)
+
+	bot.ProcessUpdate(tgfsmtest.NewMessageUpdate(1, "/help"))
+
+	if texts := sentTexts(server); len(texts) != 0 {
+		t.Errorf("sent %q, want the update denied", texts)
+	}
+	if calls := denied.calls(); len(calls) != 1 {
+		t.Errorf("access denied handler called %d times, want 1", len(calls))
+	}
+}
+
+func TestStateAndHandlerRoles(t *testing.T) {
+	tests := []struct {
+		name   string
+		state  string
+		roles  []string
+		text   string
+		sent   []string
+		denied [][]string
+	}{
+		{name: "no roles required", state: "home", text: "/help", sent: []string{"help"}},
+		{name: "empty handler roles", state: "home", text: "/open", sent: []string{"open"}},
+		{name: "empty state roles", state: "public", text: "/help", sent: []string{"help"}},
+		{name: "handler role missing", state: "home", text: "/ban", denied: [][]string{{"moderator"}}},
+		{name: "handler role", state: "home", roles: []string{"moderator"}, text: "/ban", sent: []string{"banned"}},
+		{name: "state role missing", state: "staff", roles: []string{"moderator"}, text: "/report", denied: [][]string{{"admin"}}},
+		{name: "state role", state: "staff", roles: []string{"admin"}, text: "/report", sent: []string{"report"}},
+		{name: "state role without handler role", state: "staff", roles: []string{"admin"}, text: "/ban", denied: [][]string{{"moderator"}}},
+		{name: "state and handler roles", state: "staff", roles: []string{"admin", "moderator"}, text: "/ban", sent: []string{"banned"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			denied := &deniedRecorder{}
+			roles := tgfsm.NewMemoryRoleStore(nil)
+			roles.SetUserRoles(1, tt.roles...)
+			server, bot := newRolesBot(t, denied, tgfsm.WithRoles(roles))
+			if err := bot.ForceUserState(1, tt.state); err != nil {
+				t.Fatal(err)
+			}
+
+			bot.ProcessUpdate(tgfsmtest.NewMessageUpdate(1, tt.text))
+
+			if texts := sentTexts(server); !reflect.DeepEqual(texts, tt.sent) {
+				t.Errorf("sent %q, want %q", texts, tt.sent)
+			}
+			if calls := denied.calls(); !reflect.DeepEqual(calls, tt.denied) {
+				t.Errorf("access denied handler called with %q, want %q", calls, tt.denied)
+			}
+		})
+	}
+}
+
+func TestStateRolesProviderError(t *testing.T) {
+	denied := &deniedRecorder{}
+	server, bot := newRolesBot(t, denied, tgfsm.WithRoles(tgfsm.NewMemoryRoleStore(map[int64][]string{1: {"admin"}}), failingRoles{}))
+	if err := bot.ForceUserState(1, "staff"); err != nil {
+		t.Fatal(err)
+	}
+
+	bot.ProcessUpdate(tgfsmtest.NewMessageUpdate(1, "/report"))
+
+	if texts := sentTexts(server); len(texts) != 0 {
+		t.Errorf("sent %q, want the update denied", texts)
+	}
+	if calls := denied.calls(); !reflect.DeepEqual(calls, [][]string{{"admin"}}) {
+		t.Errorf("access denied handler called with %q, want the state roles", calls)
+	}
+}
+
+func TestMemoryRoleStore(t *testing.T) {
+	store := tgfsm.NewMemoryRoleStore(map[int64][]string{1: {"admin"}})
+	store.AddUserRole(1, "moderator")
+	store.AddUserRole(1, "moderator")
+	store.AddUserRole(2, "moderator")
+	store.RemoveUserRole(1, "admin")
+	store.RemoveUserRole(2, "moderator")
+
+	if roles, _ := store.UserRoles(nil, 1, 0); !reflect.DeepEqual(roles, []string{"moderator"}) {
+		t.Errorf("roles of user 1 %q, want [moderator]", roles)
+	}
+	if roles, _ := store.UserRoles(nil, 2, 0); len(roles) != 0 {
+		t.Errorf("roles of user 2 %q, want none", roles)
+	}
+
+	store.SetUserRoles(1)
+	if roles, _ := store.UserRoles(nil, 1, 0); len(roles) != 0 {
+		t.Errorf("roles of user 1 %q after removing all, want none", roles)
+	}
+}
+
+func TestChatAdminRoles(t *testing.T) {
+	const group = -100
+
+	denied := &deniedRecorder{}
+	server, bot := newRolesBot(t, denied, tgfsm.WithRoles(tgfsm.ChatAdminRoles(0, "moderator", 0)))
+	server.SetResponse("getChatAdministrators", []tgbotapi.ChatMember{{User: tgfsmtest.NewUser(1), Status: "administrator"}})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, err := bot.HasRole(1, group, "moderator"); err != nil || !ok {
+				t.Errorf("administrator has no role: %v, %v", ok, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if ok, err := bot.HasRole(2, group, "moderator"); err != nil || ok {
+		t.Errorf("member has the role: %v, %v", ok, err)
+	}
+	if ok, err := bot.HasRole(1, 1, "moderator"); err != nil || ok {
+		t.Errorf("administrator has the role in a private chat: %v, %v", ok, err)
+	}
+	if calls := len(server.Calls("getChatAdministrators")); calls != 1 {
+		t.Errorf("getChatAdministrators called %d times, want 1 for concurrent and cached lookups", calls)
+	}
+
+	bot.ProcessUpdate(tgfsmtest.NewChatMessageUpdate(group, 1, "/ban"))
+	bot.ProcessUpdate(tgfsmtest.NewChatMessageUpdate(group, 2, "/ban"))
+	if texts := sentTexts(server); !reflect.DeepEqual(texts, []string{"banned"}) {
+		t.Errorf("sent %q, want the command of the administrator only", texts)
+	}
+}
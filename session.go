@@ -0,0 +1,93 @@
+package tgfsm
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UserSessionKey returns the cache key of a session value of the user: "<key>:<userID>"
+// Events keep their per-user data under such keys, so it can be inspected with UserSession.
+func UserSessionKey(key string, userId int64) string {
+	return key + ":" + strconv.FormatInt(userId, 10)
+}
+
+// UserSession returns the session data of the user: values stored in the cache
+// under UserSessionKey keys, by key. The state history is not included (see GetUserStateStack).
+func (app *Bot) UserSession(userId int64) map[string]interface{} {
+	session := make(map[string]interface{})
+	for key, value := range app.cache.Items() {
+		if name, ok := app.sessionKey(key, userId); ok {
+			session[name] = value.Object
+		}
+	}
+	return session
+}
+
+// SetUserSessionValue stores a session value of the user for the state expiration time
+func (app *Bot) SetUserSessionValue(userId int64, key string, value interface{}) {
+	app.cache.Set(UserSessionKey(key, userId), value, app.expiration)
+}
+
+// DeleteUserSessionValue deletes a session value of the user
+func (app *Bot) DeleteUserSessionValue(userId int64, key string) {
+	app.cache.Delete(UserSessionKey(key, userId))
+}
+
+// ClearUserSession deletes all session data of the user
+func (app *Bot) ClearUserSession(userId int64) {
+	for key := range app.cache.Items() {
+		if _, ok := app.sessionKey(key, userId); ok {
+			app.cache.Delete(key)
+		}
+	}
+}
+
+// sessionKey returns the session key of the user's cache key, false if it is not a session key
+func (app *Bot) sessionKey(cacheKey string, userId int64) (string, bool) {
+	if cacheKey == stackKey(userId) {
+		return "", false
+	}
+	name, ok := strings.CutSuffix(cacheKey, UserSessionKey("", userId))
+	return name, ok && name != ""
+}
+
+// UsersInState returns the IDs of users stored in the state, in ascending order
+// Users without a stored state are not included even if the state is the initial one.
+func (app *Bot) UsersInState(state string) []int64 {
+	var users []int64
+	for key, item := range app.cache.Items() {
+		if current, ok := item.Object.(string); !ok || current != state {
+			continue
+		}
+		userId, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			continue
+		}
+		users = append(users, userId)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i] < users[j] })
+	return users
+}
+
+// ForceUserState moves the user to the state bypassing State.Transitions and State.Guards
+// Intended for administration, e.g. to return a stuck user to a known state.
+// Transition hooks run as usual; an empty state name resets the user's state.
+// The state history is cleared as by SetUserState.
+func (app *Bot) ForceUserState(userId int64, state string) error {
+	if state == "" {
+		app.resetUserState(userId, nil)
+		return nil
+	}
+	if _, ok := app.stateSet().states[state]; !ok {
+		return NewSFMError(ErrStateHandlerNotFound, state)
+	}
+
+	app.stackMu.Lock()
+	app.clearStackOnLeave(userId, state)
+	transition := app.storeUserState(userId, state, nil)
+	app.stackMu.Unlock()
+
+	app.runTransitionHooks(transition)
+	return nil
+}
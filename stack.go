@@ -0,0 +1,207 @@
+package tgfsm
+
+import (
+	"strconv"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// DefaultMaxStateHistory is the default number of previous states kept per user
+	DefaultMaxStateHistory = 32
+
+	// stackKeyPrefix is the cache key prefix for user state history
+	stackKeyPrefix = "stack:"
+)
+
+// stackKey returns the cache key of the user's state history
+func stackKey(userId int64) string {
+	return stackKeyPrefix + strconv.FormatInt(userId, 10)
+}
+
+// GetUserStateStack returns a copy of the user's state history.
+// The last element is the state the user returns to on PopUserState.
+// An empty string in the history means "no state".
+func (app *Bot) GetUserStateStack(userId int64) []string {
+	app.stackMu.Lock()
+	defer app.stackMu.Unlock()
+
+	return append([]string(nil), app.loadStack(userId)...)
+}
+
+// PushUserState remembers the user's current state and moves the user to the new state.
+// The remembered state is restored by PopUserState.
+// If the user is already in the new state, the history is left unchanged.
+// The transition must be allowed by the current state (see State.Transitions and State.Guards).
+func (app *Bot) PushUserState(userId int64, state string) error {
+	return app.pushUserState(userId, state, nil)
+}
+
+// PushUserStateImmediate works like PushUserState and immediately processes the current update
+// in the new state (see SetUserStateImmediate)
+func (app *Bot) PushUserStateImmediate(userId int64, state string, update tgbotapi.Update) error {
+	if err := app.pushUserState(userId, state, &update); err != nil {
+		return err
+	}
+
+	app.processImmediate(state, update)
+	return nil
+}
+
+// pushUserState remembers the current state, moves the user to the new state and runs transition hooks
+func (app *Bot) pushUserState(userId int64, state string, update *tgbotapi.Update) error {
+	if _, ok := app.stateSet().states[state]; !ok {
+		return NewSFMError(ErrStateHandlerNotFound, state)
+	}
+	// Guards run without the lock so that they can read the user's history
+	if err := app.checkUserTransition(userId, state, update); err != nil {
+		return err
+	}
+
+	app.stackMu.Lock()
+	current, err := app.GetUserState(userId)
+	if err != nil {
+		current = ""
+	}
+	// The initial state is implicit: returning to it is the same as having no state
+	if current == app.stateSet().initialState {
+		current = ""
+	}
+
+	if current != state {
+		stack := append(app.loadStack(userId), current)
+		if len(stack) > app.maxStateHistory {
+			stack = stack[len(stack)-app.maxStateHistory:]
+		}
+		app.storeStack(userId, stack)
+	}
+	transition := app.storeUserState(userId, state, update)
+	app.stackMu.Unlock()
+
+	// Hooks run without the lock so that they can change the state themselves
+	app.runTransitionHooks(transition)
+	return nil
+}
+
+// PopUserState returns the user to the state remembered by the last PushUserState.
+// Returns the name of the restored state.
+// If the history is empty or the remembered state no longer exists, the user's state is cleared
+// and the initial state is returned (an empty string if it is not configured).
+func (app *Bot) PopUserState(userId int64) (string, error) {
+	return app.popUserState(userId, nil)
+}
+
+// PopUserStateImmediate works like PopUserState and immediately processes the current update
+// in the restored state (see SetUserStateImmediate)
+func (app *Bot) PopUserStateImmediate(userId int64, update tgbotapi.Update) error {
+	state, err := app.popUserState(userId, &update)
+	if err != nil {
+		return err
+	}
+
+	if state != "" {
+		app.processImmediate(state, update)
+	}
+	return nil
+}
+
+// popUserState restores the remembered state and runs transition hooks
+func (app *Bot) popUserState(userId int64, update *tgbotapi.Update) (string, error) {
+	app.stackMu.Lock()
+	stack := app.loadStack(userId)
+	previous := ""
+	if len(stack) > 0 {
+		previous = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+	}
+	app.storeStack(userId, stack)
+
+	if _, ok := app.stateSet().states[previous]; previous != "" && !ok {
+		app.Logger().Warn("previous state not found, clearing user state")
+		previous = ""
+	}
+	transition := app.storeUserState(userId, previous, update)
+	app.stackMu.Unlock()
+
+	// Hooks run without the lock so that they can change the state themselves
+	app.runTransitionHooks(transition)
+	return transition.To, nil
+}
+
+// ReplaceUserState moves the user to the new state without touching the history,
+// e.g. to the next step of a flow entered by PushUserState: PopUserState then leaves the flow.
+// SetUserState, in contrast, clears the history.
+// The transition must be allowed by the current state (see State.Transitions and State.Guards).
+func (app *Bot) ReplaceUserState(userId int64, state string) error {
+	return app.setUserState(userId, state, nil, true)
+}
+
+// ReplaceUserStateImmediate works like ReplaceUserState and immediately processes the current update
+// in the new state (see SetUserStateImmediate)
+func (app *Bot) ReplaceUserStateImmediate(userId int64, state string, update tgbotapi.Update) error {
+	if err := app.setUserState(userId, state, &update, true); err != nil {
+		return err
+	}
+
+	app.processImmediate(state, update)
+	return nil
+}
+
+// clearStackOnLeave clears the user's state history unless the user stays in the same state.
+// Must be called with stackMu held.
+func (app *Bot) clearStackOnLeave(userId int64, state string) {
+	if current, err := app.GetUserState(userId); err == nil && current == state {
+		return
+	}
+	app.cache.Delete(stackKey(userId))
+}
+
+// ResetUserStateStack clears the user's state history. The current state is not changed.
+func (app *Bot) ResetUserStateStack(userId int64) {
+	app.stackMu.Lock()
+	defer app.stackMu.Unlock()
+
+	app.cache.Delete(stackKey(userId))
+}
+
+// loadStack reads the user's state history from cache. Must be called with stackMu held.
+func (app *Bot) loadStack(userId int64) []string {
+	value, ok := app.cache.Get(stackKey(userId))
+	if !ok {
+		return nil
+	}
+
+	stack, ok := value.([]string)
+	if !ok {
+		return nil
+	}
+	return stack
+}
+
+// storeStack saves the user's state history to cache. Must be called with stackMu held.
+func (app *Bot) storeStack(userId int64, stack []string) {
+	if len(stack) == 0 {
+		app.cache.Delete(stackKey(userId))
+		return
+	}
+	// Copy so that appends by later pushes never share the cached array
+	app.cache.Set(stackKey(userId), append([]string(nil), stack...), app.expiration)
+}
+
+// NewPushUserStateImmediateHandler creates a handler that moves the user to the state,
+// remembering the current one, and immediately processes the update in the new state.
+// Use NewBackHandler to return.
+func NewPushUserStateImmediateHandler(state string) HandlerFunc {
+	return func(b *Bot, u tgbotapi.Update) error {
+		return b.PushUserStateImmediate(u.SentFrom().ID, state, u)
+	}
+}
+
+// NewBackHandler creates a handler that returns the user to the previous state
+// remembered by PushUserState and immediately processes the update there.
+// Typically bound to a "Back" button.
+func NewBackHandler() HandlerFunc {
+	return func(b *Bot, u tgbotapi.Update) error {
+		return b.PopUserStateImmediate(u.SentFrom().ID, u)
+	}
+}
@@ -0,0 +1,108 @@
+package tgfsm_test
+
+import (
+	"reflect"
+	"testing"
+	"tgfsm"
+	"tgfsm/tgfsmtest"
+)
+
+const stackUserID = 7
+
+// newStackBot creates a bot with the states menu, settings, language and help
+func newStackBot(t *testing.T) *tgfsm.Bot {
+	t.Helper()
+
+	server := tgfsmtest.NewServer()
+	t.Cleanup(server.Close)
+
+	states := make(map[string]tgfsm.State)
+	for _, key := range []string{"menu", "settings", "language", "help"} {
+		states[key] = tgfsm.State{}
+	}
+	bot, err := tgfsmtest.NewBot(server, tgfsm.WithStates(states), tgfsm.WithInitialState("menu"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bot
+}
+
+// expectStack fails the test if the user's state and history differ
+func expectStack(t *testing.T, bot *tgfsm.Bot, state string, stack []string) {
+	t.Helper()
+
+	if current, err := bot.GetUserState(stackUserID); err != nil || current != state {
+		t.Fatalf("state %q (%v), want %q", current, err, state)
+	}
+	if got := bot.GetUserStateStack(stackUserID); !reflect.DeepEqual(got, stack) {
+		t.Fatalf("history %q, want %q", got, stack)
+	}
+}
+
+func TestSetUserStateLeavesPushedStates(t *testing.T) {
+	bot := newStackBot(t)
+
+	if err := bot.PushUserState(stackUserID, "settings"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.PushUserState(stackUserID, "language"); err != nil {
+		t.Fatal(err)
+	}
+	expectStack(t, bot, "language", []string{"", "settings"})
+
+	// Staying in the state keeps the history
+	if err := bot.SetUserState(stackUserID, "language"); err != nil {
+		t.Fatal(err)
+	}
+	expectStack(t, bot, "language", []string{"", "settings"})
+
+	// E.g. a global trigger moves the user out of the flow
+	if err := bot.SetUserState(stackUserID, "help"); err != nil {
+		t.Fatal(err)
+	}
+	expectStack(t, bot, "help", nil)
+
+	state, err := bot.PopUserState(stackUserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != "menu" {
+		t.Fatalf("popped to %q, want the initial state instead of a state of the left flow", state)
+	}
+}
+
+func TestReplaceUserStateKeepsHistory(t *testing.T) {
+	bot := newStackBot(t)
+
+	if err := bot.PushUserState(stackUserID, "settings"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.ReplaceUserState(stackUserID, "language"); err != nil {
+		t.Fatal(err)
+	}
+	expectStack(t, bot, "language", []string{""})
+
+	state, err := bot.PopUserState(stackUserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != "menu" {
+		t.Fatalf("popped to %q, want menu", state)
+	}
+	expectStack(t, bot, "menu", nil)
+}
+
+func TestForceUserStateClearsHistory(t *testing.T) {
+	bot := newStackBot(t)
+
+	if err := bot.PushUserState(stackUserID, "settings"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.PushUserState(stackUserID, "language"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.ForceUserState(stackUserID, "help"); err != nil {
+		t.Fatal(err)
+	}
+	expectStack(t, bot, "help", nil)
+}
@@ -1,6 +1,8 @@
 package tgfsm
 
 import (
+	"time"
+
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
@@ -14,18 +16,53 @@ type Handler struct {
 
 	// Description provides information about the handler
 	Description *string
+
+	// Roles allowed to run the handler, the user needs one of them; no roles (nil or empty) allow everyone
+	// Checked in addition to State.Roles (see WithRoles and WithAccessDeniedHandler).
+	Roles []string
 }
 
 // State represents a bot state and defines message processing rules
 type State struct {
+	// Human-readable name of the state used in diagrams (see Graph)
+	// States generated by events have UUID keys, so events set it to describe the state.
+	Name string
 	// If true, handler triggers are checked independently of the user's current state
 	// If a suitable handler is found in a global state, it is executed and triggers of other
 	// states are not executed.
 	// After the first matching global state, other global states are not executed.
 	// Global states are set once during initialization.
+	// The order of global states is defined by Priority (see globals.go).
 	Global bool
-	// Executed when entering the state. Every time an update comes from the user while the user is in this state.
+	// Priority of a global state: global states with a higher priority are checked first,
+	// states with equal priority are checked in order of their names (State.Name, then keys).
+	Priority int
+	// Current states of the user in which a global state is active. nil means any state,
+	// an empty string in the list means "no state".
+	OnlyIn []string
+	// Current states of the user in which a global state is not active.
+	ExcludeIn []string
+	// Executed with the update that moved the user into the state by SetUserStateImmediate
+	// (and other *Immediate transitions) instead of routing that update to the handlers below.
 	AtEntranceFunc *Handler
+	// Executed once on every transition into the state from another state, including
+	// transitions without an update (SetUserState, PopUserState, ...).
+	OnEnter HookFunc
+	// Executed once on every transition from the state to another state.
+	OnExit HookFunc
+	// Executed when the user stays in the state longer than TTL.
+	// After the hook the user returns to the previous state (see PopUserState)
+	// unless the hook moved the user elsewhere.
+	OnTimeout HookFunc
+	// Time the user may stay in the state, counted from the last transition into it.
+	// Zero means no limit besides the bot-wide expiration (see WithExpiration).
+	TTL time.Duration
+	// States the user may be moved to from this state by SetUserState, ReplaceUserState and PushUserState.
+	// nil means any state. Returning with PopUserState and resetting are always allowed.
+	// Targets declared in a global state are allowed from every state.
+	Transitions []string
+	// Guards checked before moving the user from this state, keyed by target state.
+	Guards map[string]GuardFunc
 	// Executed for all events that did not match any routes.
 	CatchAllFunc *Handler
 	// Maps message text to handler key and executes it.
@@ -33,6 +70,20 @@ type State struct {
 	MessageHandlers map[string]Handler
 	// Maps callback data to handler key and executes it
 	CallbackHandlers map[string]Handler
+	// Roles allowed to run handlers of the state, the user needs one of them; no roles (nil or empty) allow everyone
+	// Handlers of a global state with roles are denied to other users instead of being skipped.
+	Roles []string
+
+	// Key of the state in the states map, set by the bot
+	key string
+}
+
+// label returns the name of the state, or the key if the name is empty
+func (s *State) label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.key
 }
 
 // NewState creates a new State instance with the given parameters
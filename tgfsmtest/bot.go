@@ -0,0 +1,100 @@
+package tgfsmtest
+
+import (
+	"strconv"
+	"sync/atomic"
+	"tgfsm"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// updateID is the ID of the last update created by the constructors below
+var updateID atomic.Int64
+
+// NewBot creates a bot connected to the fake server
+// Logging is disabled and the rate limiter never waits; both can be overridden by options.
+func NewBot(server *Server, options ...tgfsm.Option) (*tgfsm.Bot, error) {
+	defaults := []tgfsm.Option{
+		tgfsm.WithLogger(zap.NewNop()),
+		tgfsm.WithLimiter(tgfsm.NewUnlimitedLimiter()),
+	}
+	options = append(defaults, options...)
+	options = append(options, tgfsm.WithAPIEndpoint(server.Endpoint()))
+
+	return tgfsm.NewBot(Token, options...)
+}
+
+// NewUser returns a user with the ID
+func NewUser(userID int64) *tgbotapi.User {
+	return &tgbotapi.User{
+		ID:        userID,
+		FirstName: "User",
+		UserName:  "user" + itoa(userID),
+	}
+}
+
+// NewMessageUpdate returns an update with a text message from the user in a private chat
+// Text starting with "/" is marked as a bot command.
+func NewMessageUpdate(userID int64, text string) tgbotapi.Update {
+	return NewChatMessageUpdate(userID, userID, text)
+}
+
+// NewChatMessageUpdate returns an update with a text message from the user in the chat
+// Negative chat IDs are supergroups.
+func NewChatMessageUpdate(chatID, userID int64, text string) tgbotapi.Update {
+	message := &tgbotapi.Message{
+		MessageID: int(updateID.Load()) + 1,
+		From:      NewUser(userID),
+		Date:      int(time.Now().Unix()),
+		Chat:      &tgbotapi.Chat{ID: chatID, Type: chatType(chatID)},
+		Text:      text,
+	}
+	if len(text) > 1 && text[0] == '/' {
+		length := len(text)
+		for i, r := range text {
+			if r == ' ' {
+				length = i
+				break
+			}
+		}
+		message.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: length}}
+	}
+
+	return tgbotapi.Update{
+		UpdateID: nextUpdateID(),
+		Message:  message,
+	}
+}
+
+// NewCallbackUpdate returns an update with a pressed inline button
+// messageID is the message with the keyboard, sent by the bot to the user's private chat.
+func NewCallbackUpdate(userID int64, data string, messageID int) tgbotapi.Update {
+	id := nextUpdateID()
+	return tgbotapi.Update{
+		UpdateID: id,
+		CallbackQuery: &tgbotapi.CallbackQuery{
+			ID:   "callback" + itoa(int64(id)),
+			From: NewUser(userID),
+			Message: &tgbotapi.Message{
+				MessageID: messageID,
+				From:      &tgbotapi.User{ID: BotID, IsBot: true, FirstName: "Test", UserName: BotUserName},
+				Date:      int(time.Now().Unix()),
+				Chat:      &tgbotapi.Chat{ID: userID, Type: "private"},
+			},
+			ChatInstance: "chat" + itoa(userID),
+			Data:         data,
+		},
+	}
+}
+
+// nextUpdateID returns a new update ID
+func nextUpdateID() int {
+	return int(updateID.Add(1))
+}
+
+// itoa formats an integer
+func itoa(i int64) string {
+	return strconv.FormatInt(i, 10)
+}
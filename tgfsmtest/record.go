@@ -0,0 +1,285 @@
+package tgfsmtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Kinds of recording entries
+const (
+	// EntryUpdate is an update received by the bot
+	EntryUpdate = "update"
+	// EntryCall is a Bot API call made by the bot
+	EntryCall = "call"
+)
+
+// Entry is a line of a recording: an update received by the bot or a call it made
+// Recordings are stored as JSON Lines, one entry per line (see Recorder, ReadRecording).
+type Entry struct {
+	Kind   string           `json:"kind"`
+	Update *tgbotapi.Update `json:"update,omitempty"`
+	Method string           `json:"method,omitempty"`
+	// Params of the call; uploaded files are replaced with their names
+	Params map[string]string `json:"params,omitempty"`
+	// MessageID is the ID of the message sent or edited by the call
+	MessageID int `json:"message_id,omitempty"`
+}
+
+// Recorder is an http.RoundTripper recording the updates received by the bot
+// and the calls it makes into a recording for Replay:
+//
+//	file, _ := os.Create("testdata/start.jsonl")
+//	recorder := tgfsmtest.NewRecorder(file, nil)
+//	bot, err := tgfsm.NewBot(token, tgfsm.WithHTTPClient(recorder.Client()), ...)
+//
+// Updates are taken from getUpdates responses; bots using webhooks record them with RecordUpdate.
+// A call is recorded at the position where it was started, so the calls made while processing
+// an update follow that update even if they complete after the next update is received.
+type Recorder struct {
+	transport http.RoundTripper
+	mu        sync.Mutex
+	encoder   *json.Encoder
+	err       error
+	next      int             // Sequence number of the next started entry
+	written   int             // Sequence number of the next entry to write
+	pending   map[int][]Entry // Completed entries waiting for earlier ones
+}
+
+// NewRecorder creates a recorder writing to w and sending requests with the transport
+// A nil transport means http.DefaultTransport.
+func NewRecorder(w io.Writer, transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	return &Recorder{
+		transport: transport,
+		encoder:   encoder,
+		pending:   make(map[int][]Entry),
+	}
+}
+
+// Client returns an HTTP client using the recorder for tgfsm.WithHTTPClient
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Err returns the first error of writing the recording
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// RecordUpdate records an update received without getUpdates, e.g. from a webhook
+func (r *Recorder) RecordUpdate(update tgbotapi.Update) {
+	r.complete(r.start(), Entry{Kind: EntryUpdate, Update: &update})
+}
+
+// RoundTrip sends the request and records the call
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+
+	params, err := readParams(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// getUpdates is long polling, its updates are positioned when they are received
+	seq := -1
+	if method != "getUpdates" {
+		seq = r.start()
+	}
+	var entries []Entry
+	defer func() {
+		if seq >= 0 {
+			r.complete(seq, entries...)
+		}
+	}()
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var apiResp tgbotapi.APIResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil || !apiResp.Ok {
+		// Failed calls are not part of the behaviour to replay
+		return resp, nil
+	}
+
+	if method == "getUpdates" {
+		var updates []tgbotapi.Update
+		if err := json.Unmarshal(apiResp.Result, &updates); err == nil {
+			seq = r.start()
+			for i := range updates {
+				entries = append(entries, Entry{Kind: EntryUpdate, Update: &updates[i]})
+			}
+		}
+		return resp, nil
+	}
+
+	entry := Entry{Kind: EntryCall, Method: method, Params: params}
+	var message struct {
+		MessageID int `json:"message_id"`
+	}
+	if json.Unmarshal(apiResp.Result, &message) == nil {
+		entry.MessageID = message.MessageID
+	}
+	entries = append(entries, entry)
+
+	return resp, nil
+}
+
+// start reserves the position of an entry in the recording
+func (r *Recorder) start() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seq := r.next
+	r.next++
+	return seq
+}
+
+// complete fills the reserved position with entries (none for failed calls)
+// and writes all completed entries up to the first position still in progress
+func (r *Recorder) complete(seq int, entries ...Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pending[seq] = entries
+	for {
+		entries, ok := r.pending[r.written]
+		if !ok {
+			return
+		}
+		delete(r.pending, r.written)
+		r.written++
+
+		for _, entry := range entries {
+			if err := r.encoder.Encode(entry); err != nil && r.err == nil {
+				r.err = err
+			}
+		}
+	}
+}
+
+// readParams reads the parameters of the request and restores its body
+func readParams(req *http.Request) (map[string]string, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+
+	params := make(map[string]string)
+	mediaType, mediaParams, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+		for key := range values {
+			params[key] = values.Get(key)
+		}
+		return params, nil
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(body), mediaParams["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return params, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FileName() != "" {
+			params[part.FormName()] = part.FileName()
+			continue
+		}
+		value, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		params[part.FormName()] = string(value)
+	}
+}
+
+// ReadRecording reads a recording in JSON Lines
+func ReadRecording(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// LoadRecording reads a recording file
+func LoadRecording(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadRecording(file)
+}
+
+// WriteRecording writes entries to a recording file, e.g. to update a golden file with ReplayResult.Entries
+func WriteRecording(path string, entries []Entry) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// entryOf converts a call received by the fake server to a recording entry
+func entryOf(r Request) Entry {
+	params := make(map[string]string, len(r.Params))
+	for key := range r.Params {
+		params[key] = r.Params.Get(key)
+	}
+	return Entry{Kind: EntryCall, Method: r.Method, Params: params, MessageID: r.MessageID}
+}
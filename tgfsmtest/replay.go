@@ -0,0 +1,203 @@
+package tgfsmtest
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"tgfsm"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// messageIDParams are the call parameters holding message IDs
+// Message IDs of a recording differ from the IDs given by the fake server, so they are translated.
+var messageIDParams = []string{"message_id", "reply_to_message_id"}
+
+// ReplayResult is the result of replaying a recording
+type ReplayResult struct {
+	// Entries are the updates of the recording with the calls made by the bot during replay
+	Entries []Entry
+	// Diff lists the differences of the calls from the recording, empty if the behaviour is the same
+	// Each differing update is followed by the recorded calls missing in replay ("-")
+	// and the calls not found in the recording ("+").
+	Diff []string
+}
+
+// Replay processes the updates of the recording one by one and compares the calls made by the bot
+// after each update with the recorded ones. Calls recorded before the first update (getMe and other
+// startup calls) are not compared.
+//
+// Message IDs recorded from real Telegram are translated to the IDs given by the fake server:
+// messages sent in the same order are considered the same message.
+func Replay(server *Server, bot *tgfsm.Bot, recording []Entry) ReplayResult {
+	var result ReplayResult
+	ids := make(map[int]int) // Recorded message ID -> replayed message ID
+
+	for i := 0; i < len(recording); i++ {
+		entry := recording[i]
+		if entry.Kind != EntryUpdate || entry.Update == nil {
+			continue
+		}
+
+		var expected []Entry
+		for i+1 < len(recording) && recording[i+1].Kind == EntryCall {
+			i++
+			expected = append(expected, recording[i])
+		}
+
+		update := *entry.Update
+		if update.CallbackQuery != nil && update.CallbackQuery.Message != nil {
+			query := *update.CallbackQuery
+			message := *query.Message
+			if id, ok := ids[message.MessageID]; ok {
+				message.MessageID = id
+			}
+			query.Message = &message
+			update.CallbackQuery = &query
+		}
+
+		pos := len(server.Requests())
+		bot.ProcessUpdate(update)
+
+		var actual []Entry
+		for _, r := range server.Requests()[pos:] {
+			actual = append(actual, entryOf(r))
+		}
+
+		// Messages are matched by the order in which they were sent
+		var sent []Entry
+		for _, call := range actual {
+			if isSent(call.Method) && call.MessageID != 0 {
+				sent = append(sent, call)
+			}
+		}
+		for _, call := range expected {
+			if !isSent(call.Method) || call.MessageID == 0 || len(sent) == 0 {
+				continue
+			}
+			ids[call.MessageID] = sent[0].MessageID
+			sent = sent[1:]
+		}
+
+		expectedLines := make([]string, len(expected))
+		for j, call := range expected {
+			expectedLines[j] = formatCall(call, ids)
+		}
+		actualLines := make([]string, len(actual))
+		for j, call := range actual {
+			actualLines[j] = formatCall(call, nil)
+		}
+		if diff := diffLines(expectedLines, actualLines); len(diff) > 0 {
+			result.Diff = append(result.Diff, describeUpdate(entry.Update)+":")
+			result.Diff = append(result.Diff, diff...)
+		}
+
+		result.Entries = append(result.Entries, entry)
+		result.Entries = append(result.Entries, actual...)
+	}
+
+	return result
+}
+
+// ReplayFile replays the recording file and fails the test if the calls differ (see Replay)
+func ReplayFile(t TB, server *Server, bot *tgfsm.Bot, path string) ReplayResult {
+	t.Helper()
+
+	recording, err := LoadRecording(path)
+	if err != nil {
+		t.Fatalf("failed to load recording: %v", err)
+		return ReplayResult{}
+	}
+
+	result := Replay(server, bot, recording)
+	if len(result.Diff) > 0 {
+		t.Fatalf("calls differ from %s:\n%s", path, strings.Join(result.Diff, "\n"))
+	}
+	return result
+}
+
+// formatCall formats the call for comparison: the method and the parameters sorted by name
+// Message IDs found in ids are translated.
+func formatCall(call Entry, ids map[int]int) string {
+	keys := make([]string, 0, len(call.Params))
+	for key := range call.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(call.Method)
+	for _, key := range keys {
+		value := call.Params[key]
+		for _, param := range messageIDParams {
+			if key != param {
+				continue
+			}
+			if id, err := strconv.Atoi(value); err == nil {
+				if mapped, ok := ids[id]; ok {
+					value = strconv.Itoa(mapped)
+				}
+			}
+		}
+		b.WriteString(" ")
+		b.WriteString(key)
+		b.WriteString("=")
+		b.WriteString(strconv.Quote(value))
+	}
+	return b.String()
+}
+
+// diffLines returns the difference of two sequences of lines based on their longest common subsequence
+func diffLines(expected, actual []string) []string {
+	// lcs[i][j] is the length of the longest common subsequence of expected[i:] and actual[j:]
+	lcs := make([][]int, len(expected)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(actual)+1)
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		for j := len(actual) - 1; j >= 0; j-- {
+			if expected[i] == actual[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff []string
+	i, j := 0, 0
+	for i < len(expected) || j < len(actual) {
+		switch {
+		case i < len(expected) && j < len(actual) && expected[i] == actual[j]:
+			i++
+			j++
+		case i < len(expected) && (j == len(actual) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, "  - "+expected[i])
+			i++
+		default:
+			diff = append(diff, "  + "+actual[j])
+			j++
+		}
+	}
+	return diff
+}
+
+// describeUpdate returns a short description of the update for diffs
+func describeUpdate(update *tgbotapi.Update) string {
+	var b strings.Builder
+	b.WriteString("update ")
+	b.WriteString(strconv.Itoa(update.UpdateID))
+	switch {
+	case update.Message != nil:
+		b.WriteString(": message ")
+		b.WriteString(strconv.Quote(update.Message.Text))
+	case update.CallbackQuery != nil:
+		b.WriteString(": callback ")
+		b.WriteString(strconv.Quote(update.CallbackQuery.Data))
+	}
+	if user := update.SentFrom(); user != nil {
+		b.WriteString(" from ")
+		b.WriteString(strconv.FormatInt(user.ID, 10))
+	}
+	return b.String()
+}
@@ -0,0 +1,118 @@
+package tgfsmtest
+
+import (
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name             string
+		expected, actual []string
+		diff             []string
+	}{
+		{"equal", []string{"a", "b"}, []string{"a", "b"}, nil},
+		{"empty", nil, nil, nil},
+		{"missing", []string{"a", "b", "c"}, []string{"a", "c"}, []string{"  - b"}},
+		{"extra", []string{"a"}, []string{"a", "b"}, []string{"  + b"}},
+		{"changed", []string{"a", "b", "c"}, []string{"a", "x", "c"}, []string{"  - b", "  + x"}},
+		{"all new", nil, []string{"a", "b"}, []string{"  + a", "  + b"}},
+		{"reordered", []string{"a", "b"}, []string{"b", "a"}, []string{"  - a", "  + a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := diffLines(tt.expected, tt.actual); !slices.Equal(diff, tt.diff) {
+				t.Fatalf("diff %q, want %q", diff, tt.diff)
+			}
+		})
+	}
+}
+
+// recordGreeting runs /hi and the "Yes" button on a new bot and returns the conversation as a recording
+// Message IDs of the recording are shifted as if recorded from real Telegram.
+func recordGreeting(t *testing.T) []Entry {
+	t.Helper()
+
+	server, bot := newGreeterBot(t)
+	var recording []Entry
+	step := func(update Entry) {
+		pos := len(server.Requests())
+		bot.ProcessUpdate(*update.Update)
+		recording = append(recording, update)
+		for _, r := range server.Requests()[pos:] {
+			recording = append(recording, entryOf(r))
+		}
+	}
+
+	hi := NewMessageUpdate(1, "/hi")
+	step(Entry{Kind: EntryUpdate, Update: &hi})
+	yes := NewCallbackUpdate(1, "yes", server.Sent()[0].MessageID)
+	step(Entry{Kind: EntryUpdate, Update: &yes})
+
+	const shift = 1000
+	for i := range recording {
+		entry := &recording[i]
+		if entry.MessageID != 0 {
+			entry.MessageID += shift
+		}
+		if id, ok := entry.Params["message_id"]; ok {
+			n, err := strconv.Atoi(id)
+			if err != nil {
+				t.Fatalf("bad message_id %q: %v", id, err)
+			}
+			entry.Params["message_id"] = strconv.Itoa(n + shift)
+		}
+		if entry.Update != nil && entry.Update.CallbackQuery != nil {
+			entry.Update.CallbackQuery.Message.MessageID += shift
+		}
+	}
+	return recording
+}
+
+func TestReplay(t *testing.T) {
+	recording := recordGreeting(t)
+
+	server, bot := newGreeterBot(t)
+	result := Replay(server, bot, recording)
+	if len(result.Diff) > 0 {
+		t.Fatalf("unexpected diff:\n%q", result.Diff)
+	}
+	if len(result.Entries) != len(recording) {
+		t.Fatalf("replayed %d entries, want %d", len(result.Entries), len(recording))
+	}
+	if edited := server.Edited(); len(edited) != 1 || edited[0].MessageID != server.Sent()[0].MessageID {
+		t.Fatalf("edited %+v, want the replayed greeting", edited)
+	}
+}
+
+func TestReplayDiff(t *testing.T) {
+	recording := recordGreeting(t)
+	for i := range recording {
+		if recording[i].Method == "editMessageText" {
+			recording[i].Params["text"] = "Finished"
+		}
+	}
+
+	server, bot := newGreeterBot(t)
+	result := Replay(server, bot, recording)
+	if len(result.Diff) != 3 {
+		t.Fatalf("diff %q, want the callback update with the recorded and the replayed edit", result.Diff)
+	}
+	if !strings.HasSuffix(result.Diff[0], `: callback "yes" from 1:`) {
+		t.Fatalf("diff starts with %q, want the callback update", result.Diff[0])
+	}
+}
+
+func TestReplayFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "greeting.jsonl")
+	if err := WriteRecording(path, recordGreeting(t)); err != nil {
+		t.Fatalf("failed to write recording: %v", err)
+	}
+
+	server, bot := newGreeterBot(t)
+	ReplayFile(t, server, bot, path)
+}
@@ -0,0 +1,362 @@
+package tgfsmtest
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"tgfsm"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TB is the part of testing.TB used by scenarios
+type TB interface {
+	Helper()
+	Fatalf(format string, args ...interface{})
+}
+
+// Scenario is a conversation of a user with the bot checked step by step:
+//
+//	tgfsmtest.NewScenario(t, server, bot, 1).
+//		Send("/feedback").
+//		ExpectMessage("Enter data:").
+//		Send("Ann").
+//		ExpectMessageContains("Ann").
+//		ExpectKeyboard("Confirm").
+//		Press("Confirm").
+//		ExpectMessage("Data successfully saved!").
+//		ExpectState("")
+//
+// Actions (Send, Press, PressData) process the update synchronously with Bot.ProcessUpdate.
+// Expectations consume the calls the bot made to the user's chat after the last action in order,
+// so each message is matched once; unmatched calls are ignored.
+// The first failed expectation stops the test with Fatalf.
+type Scenario struct {
+	t      TB
+	server *Server
+	bot    *tgfsm.Bot
+	userID int64
+	chatID int64
+	pos    int      // Index of the first call not consumed by expectations
+	last   *Request // Message matched by the last expectation
+}
+
+// NewScenario starts a conversation of the user with the bot in the user's private chat
+func NewScenario(t TB, server *Server, bot *tgfsm.Bot, userID int64) *Scenario {
+	return &Scenario{
+		t:      t,
+		server: server,
+		bot:    bot,
+		userID: userID,
+		chatID: userID,
+		pos:    len(server.Requests()),
+	}
+}
+
+// InChat returns the scenario of the same user in another chat, e.g. a group
+// The returned scenario continues from the current position.
+func (s *Scenario) InChat(chatID int64) *Scenario {
+	next := *s
+	next.chatID = chatID
+	next.last = nil
+	return &next
+}
+
+// Send sends a text message from the user
+func (s *Scenario) Send(text string) *Scenario {
+	s.t.Helper()
+	return s.Update(NewChatMessageUpdate(s.chatID, s.userID, text))
+}
+
+// Press presses the button with the text on the last message with such a button:
+// an inline button sends its callback data, a reply keyboard button sends its text
+func (s *Scenario) Press(text string) *Scenario {
+	s.t.Helper()
+
+	requests := s.server.Requests()
+	for i := len(requests) - 1; i >= 0; i-- {
+		r := requests[i]
+		if !(isSent(r.Method) || isEdit(r.Method)) || r.ChatID() != s.chatID {
+			continue
+		}
+		for _, row := range r.InlineKeyboard() {
+			for _, button := range row {
+				if button.Text != text {
+					continue
+				}
+				if button.CallbackData == nil {
+					s.t.Fatalf("user %d: inline button %q has no callback data", s.userID, text)
+					return s
+				}
+				return s.press(*button.CallbackData, r.MessageID)
+			}
+		}
+		for _, row := range r.ReplyKeyboard() {
+			for _, button := range row {
+				if button.Text == text {
+					return s.Send(text)
+				}
+			}
+		}
+	}
+
+	s.t.Fatalf("user %d: no button %q", s.userID, text)
+	return s
+}
+
+// PressData presses the inline button with the callback data on the last message with an inline keyboard
+// If no button has the data, the callback is sent for the last message anyway, as a stale button would.
+func (s *Scenario) PressData(data string) *Scenario {
+	s.t.Helper()
+
+	message, _, ok := s.findButton(func(button tgbotapi.InlineKeyboardButton) bool {
+		return button.CallbackData != nil && *button.CallbackData == data
+	})
+	if !ok {
+		message = s.lastKeyboard()
+	}
+	messageID := 0
+	if message != nil {
+		messageID = message.MessageID
+	}
+	return s.press(data, messageID)
+}
+
+// Update processes an arbitrary update, e.g. built with the constructors of the package
+func (s *Scenario) Update(update tgbotapi.Update) *Scenario {
+	s.t.Helper()
+
+	s.pos = len(s.server.Requests())
+	s.last = nil
+	s.bot.ProcessUpdate(update)
+	return s
+}
+
+// Do runs a custom step, e.g. changing the state directly or checking stored data
+func (s *Scenario) Do(step func(bot *tgfsm.Bot)) *Scenario {
+	s.t.Helper()
+
+	step(s.bot)
+	return s
+}
+
+// ExpectMessage expects a message with exactly the text (caption for media)
+func (s *Scenario) ExpectMessage(text string) *Scenario {
+	s.t.Helper()
+	return s.expect(fmt.Sprintf("message %q", text), isSent, func(r Request) bool {
+		return r.Text() == text
+	})
+}
+
+// ExpectMessageContains expects a message whose text contains the substring
+func (s *Scenario) ExpectMessageContains(substr string) *Scenario {
+	s.t.Helper()
+	return s.expect(fmt.Sprintf("message containing %q", substr), isSent, func(r Request) bool {
+		return strings.Contains(r.Text(), substr)
+	})
+}
+
+// ExpectEdit expects an edit of a message to exactly the text
+func (s *Scenario) ExpectEdit(text string) *Scenario {
+	s.t.Helper()
+	return s.expect(fmt.Sprintf("edit to %q", text), isEdit, func(r Request) bool {
+		return r.Text() == text
+	})
+}
+
+// ExpectEditContains expects an edit of a message to a text containing the substring
+func (s *Scenario) ExpectEditContains(substr string) *Scenario {
+	s.t.Helper()
+	return s.expect(fmt.Sprintf("edit containing %q", substr), isEdit, func(r Request) bool {
+		return strings.Contains(r.Text(), substr)
+	})
+}
+
+// ExpectKeyboard expects a keyboard with the buttons (inline or reply, other buttons are allowed)
+// It checks the message matched by the previous expectation; without one, the next message or edit
+// with a keyboard is consumed.
+func (s *Scenario) ExpectKeyboard(buttons ...string) *Scenario {
+	s.t.Helper()
+
+	hasButtons := func(r Request) bool {
+		texts := r.Buttons()
+		for _, button := range buttons {
+			if !slices.Contains(texts, button) {
+				return false
+			}
+		}
+		return len(texts) > 0
+	}
+
+	if s.last != nil {
+		if !hasButtons(*s.last) {
+			s.t.Fatalf("user %d: expected keyboard with %q, message %q has %q",
+				s.userID, buttons, s.last.Text(), s.last.Buttons())
+		}
+		return s
+	}
+
+	return s.expect(fmt.Sprintf("keyboard with %q", buttons), func(method string) bool {
+		return isSent(method) || isEdit(method)
+	}, hasButtons)
+}
+
+// ExpectDeleted expects a message to be deleted
+func (s *Scenario) ExpectDeleted() *Scenario {
+	s.t.Helper()
+	return s.expect("deleted message", func(method string) bool {
+		return method == "deleteMessage"
+	}, func(Request) bool {
+		return true
+	})
+}
+
+// ExpectAnswer expects the callback query to be answered, text may be empty
+func (s *Scenario) ExpectAnswer(text string) *Scenario {
+	s.t.Helper()
+
+	for i, r := range s.pending() {
+		if r.Method == "answerCallbackQuery" && r.Params.Get("text") == text {
+			s.pos += i + 1
+			return s
+		}
+	}
+	s.t.Fatalf("user %d: expected callback answer %q, got %s", s.userID, text, s.describe())
+	return s
+}
+
+// ExpectNoMessages expects no more messages or edits in the chat after the last action
+func (s *Scenario) ExpectNoMessages() *Scenario {
+	s.t.Helper()
+
+	for _, r := range s.pending() {
+		if (isSent(r.Method) || isEdit(r.Method)) && r.ChatID() == s.chatID {
+			s.t.Fatalf("user %d: expected no messages, got %s %q", s.userID, r.Method, r.Text())
+			break
+		}
+	}
+	return s
+}
+
+// ExpectState expects the user to be in the state; an empty state means no state
+func (s *Scenario) ExpectState(state string) *Scenario {
+	s.t.Helper()
+
+	current, err := s.bot.GetUserState(s.userID)
+	if err != nil && !errors.Is(err, tgfsm.ErrStateNotFound) {
+		s.t.Fatalf("user %d: failed to get state: %v", s.userID, err)
+		return s
+	}
+	if current != state {
+		s.t.Fatalf("user %d: expected state %q, got %q", s.userID, state, current)
+	}
+	return s
+}
+
+// ExpectStateName expects the user to be in a state with the name (see State.Name)
+// Useful for states generated by events, whose keys are random.
+func (s *Scenario) ExpectStateName(name string) *Scenario {
+	s.t.Helper()
+
+	current, _ := s.bot.GetUserState(s.userID)
+	if got := s.bot.States()[current].Name; got != name {
+		s.t.Fatalf("user %d: expected state named %q, got %q (%q)", s.userID, name, got, current)
+	}
+	return s
+}
+
+// Last returns the message matched by the last expectation, nil if there is none
+func (s *Scenario) Last() *Request {
+	return s.last
+}
+
+// expect consumes the first pending call of the chat of the kind that matches
+func (s *Scenario) expect(what string, kind func(method string) bool, match func(Request) bool) *Scenario {
+	s.t.Helper()
+
+	for i, r := range s.pending() {
+		if !kind(r.Method) || r.ChatID() != s.chatID || !match(r) {
+			continue
+		}
+		s.pos += i + 1
+		s.last = &r
+		return s
+	}
+	s.t.Fatalf("user %d: expected %s, got %s", s.userID, what, s.describe())
+	return s
+}
+
+// pending returns the calls not consumed by expectations
+func (s *Scenario) pending() []Request {
+	requests := s.server.Requests()
+	if s.pos > len(requests) {
+		// The server was reset
+		s.pos = len(requests)
+	}
+	return requests[s.pos:]
+}
+
+// describe lists the pending calls for failure messages
+func (s *Scenario) describe() string {
+	pending := s.pending()
+	if len(pending) == 0 {
+		return "no calls"
+	}
+
+	calls := make([]string, len(pending))
+	for i, r := range pending {
+		calls[i] = fmt.Sprintf("%s(chat %d, %q", r.Method, r.ChatID(), r.Text())
+		if buttons := r.Buttons(); len(buttons) > 0 {
+			calls[i] += fmt.Sprintf(", buttons %q", buttons)
+		}
+		calls[i] += ")"
+	}
+	return strings.Join(calls, ", ")
+}
+
+// findButton returns the latest message of the chat with an inline button that matches
+func (s *Scenario) findButton(match func(tgbotapi.InlineKeyboardButton) bool) (*Request, tgbotapi.InlineKeyboardButton, bool) {
+	requests := s.server.Requests()
+	for i := len(requests) - 1; i >= 0; i-- {
+		r := requests[i]
+		if !(isSent(r.Method) || isEdit(r.Method)) || r.ChatID() != s.chatID {
+			continue
+		}
+		for _, row := range r.InlineKeyboard() {
+			for _, button := range row {
+				if match(button) {
+					return &r, button, true
+				}
+			}
+		}
+	}
+	return nil, tgbotapi.InlineKeyboardButton{}, false
+}
+
+// lastKeyboard returns the latest message of the chat with an inline keyboard
+func (s *Scenario) lastKeyboard() *Request {
+	message, _, _ := s.findButton(func(tgbotapi.InlineKeyboardButton) bool {
+		return true
+	})
+	return message
+}
+
+// press sends a callback query from the user for the message
+func (s *Scenario) press(data string, messageID int) *Scenario {
+	s.t.Helper()
+
+	update := NewCallbackUpdate(s.userID, data, messageID)
+	update.CallbackQuery.Message.Chat = &tgbotapi.Chat{ID: s.chatID, Type: chatType(s.chatID)}
+	return s.Update(update)
+}
+
+// isSent reports whether the method sends a message
+func isSent(method string) bool {
+	return strings.HasPrefix(method, "send") && method != "sendChatAction"
+}
+
+// isEdit reports whether the method edits a message
+func isEdit(method string) bool {
+	return strings.HasPrefix(method, "editMessage")
+}
@@ -0,0 +1,130 @@
+package tgfsmtest
+
+import (
+	"fmt"
+	"testing"
+	"tgfsm"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// fakeTB records failures instead of stopping the test, so failing expectations can be checked
+type fakeTB struct {
+	failures []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Fatalf(format string, args ...interface{}) {
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+// newGreeterBot creates a bot greeting on /hi with a "Yes" button that edits the greeting
+// and moves the user to the "done" state
+func newGreeterBot(t *testing.T) (*Server, *tgfsm.Bot) {
+	t.Helper()
+
+	server := NewServer()
+	t.Cleanup(server.Close)
+
+	states := map[string]tgfsm.State{
+		"home": {
+			MessageHandlers: map[string]tgfsm.Handler{
+				"/hi": {Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+					msg := tgbotapi.NewMessage(u.FromChat().ID, "Hello")
+					msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+						tgbotapi.NewInlineKeyboardButtonData("Yes", "yes"),
+					))
+					_, err := b.SendMessage(msg)
+					return err
+				}},
+			},
+			CallbackHandlers: map[string]tgfsm.Handler{
+				"yes": {Handle: func(b *tgfsm.Bot, u tgbotapi.Update) error {
+					b.Request(tgbotapi.NewCallback(u.CallbackQuery.ID, "ok"))
+					message := u.CallbackQuery.Message
+					if _, err := b.EditMessage(tgbotapi.NewEditMessageText(message.Chat.ID, message.MessageID, "Done")); err != nil {
+						return err
+					}
+					return b.SetUserState(u.SentFrom().ID, "done")
+				}},
+			},
+		},
+		"done": {Name: "Finished"},
+	}
+
+	bot, err := NewBot(server, tgfsm.WithStates(states), tgfsm.WithInitialState("home"))
+	if err != nil {
+		t.Fatalf("failed to create bot: %v", err)
+	}
+	return server, bot
+}
+
+func TestScenario(t *testing.T) {
+	server, bot := newGreeterBot(t)
+
+	s := NewScenario(t, server, bot, 1).
+		Send("/hi").
+		ExpectMessage("Hello").
+		ExpectKeyboard("Yes")
+	hello := s.Last()
+	if hello == nil || hello.ChatID() != 1 {
+		t.Fatalf("last message %+v, want the greeting in chat 1", hello)
+	}
+
+	s.Press("Yes").
+		ExpectAnswer("ok").
+		ExpectEdit("Done").
+		ExpectNoMessages().
+		ExpectState("done").
+		ExpectStateName("Finished")
+
+	if edit := s.Last(); edit.MessageID != hello.MessageID {
+		t.Fatalf("edited message %d, want the greeting %d", edit.MessageID, hello.MessageID)
+	}
+}
+
+func TestScenarioFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		steps  func(s *Scenario)
+		failed bool
+	}{
+		{"matching message", func(s *Scenario) { s.Send("/hi").ExpectMessage("Hello") }, false},
+		{"other text", func(s *Scenario) { s.Send("/hi").ExpectMessage("Bye") }, true},
+		{"message consumed once", func(s *Scenario) { s.Send("/hi").ExpectMessage("Hello").ExpectMessage("Hello") }, true},
+		{"message of an earlier action", func(s *Scenario) { s.Send("/hi").Send("text").ExpectMessage("Hello") }, true},
+		{"missing button", func(s *Scenario) { s.Send("/hi").ExpectMessage("Hello").ExpectKeyboard("No") }, true},
+		{"press missing button", func(s *Scenario) { s.Send("/hi").Press("No") }, true},
+		{"unexpected message", func(s *Scenario) { s.Send("/hi").ExpectNoMessages() }, true},
+		{"other state", func(s *Scenario) { s.Send("/hi").ExpectState("done") }, true},
+		{"other chat", func(s *Scenario) { s.Send("/hi").InChat(-100).ExpectMessage("Hello") }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, bot := newGreeterBot(t)
+			tb := &fakeTB{}
+
+			tt.steps(NewScenario(tb, server, bot, 1))
+			if failed := len(tb.failures) > 0; failed != tt.failed {
+				t.Fatalf("failed %v, want %v: %q", failed, tt.failed, tb.failures)
+			}
+		})
+	}
+}
+
+func TestScenarioInChat(t *testing.T) {
+	server, bot := newGreeterBot(t)
+
+	NewScenario(t, server, bot, 1).
+		InChat(-100).
+		Send("/hi").
+		ExpectMessage("Hello").
+		Press("Yes").
+		ExpectEdit("Done")
+
+	if calls := server.Sent(); len(calls) != 1 || calls[0].ChatID() != -100 {
+		t.Fatalf("sent %+v, want the greeting in the group", calls)
+	}
+}
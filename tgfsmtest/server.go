@@ -0,0 +1,421 @@
+// Package tgfsmtest provides an in-process fake Telegram Bot API server for testing bots
+// built with tgfsm without network access:
+//
+//	server := tgfsmtest.NewServer()
+//	defer server.Close()
+//
+//	bot, err := tgfsmtest.NewBot(server, tgfsm.WithStates(states))
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//
+//	bot.ProcessUpdate(tgfsmtest.NewMessageUpdate(42, "/start"))
+//
+//	sent := server.Sent()
+//	if len(sent) != 1 || sent[0].Params.Get("text") != "Hello!" {
+//		t.Fatalf("unexpected messages: %v", sent)
+//	}
+//
+// Conversations are checked more conveniently with a Scenario.
+package tgfsmtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// Token is the bot token accepted by the server
+	Token = "123456:TEST"
+
+	// BotID is the ID of the bot returned by getMe
+	BotID = 123456
+
+	// BotUserName is the username of the bot returned by getMe
+	BotUserName = "tgfsm_test_bot"
+
+	// maxPollWait limits how long getUpdates waits for new updates,
+	// so that stopping the bot does not hang on a long poll
+	maxPollWait = 100 * time.Millisecond
+)
+
+// Request is a Bot API call received by the server
+type Request struct {
+	// Method is the Bot API method, e.g. "sendMessage"
+	Method string
+	// Params are the parameters of the call; files of multipart requests are not kept
+	Params url.Values
+	// MessageID is the ID of the sent or edited message, 0 for other calls
+	MessageID int
+	// Time is when the call was received
+	Time time.Time
+}
+
+// ChatID returns the chat_id parameter of the request, 0 if missing
+func (r Request) ChatID() int64 {
+	id, _ := strconv.ParseInt(r.Params.Get("chat_id"), 10, 64)
+	return id
+}
+
+// Text returns the text parameter of the request (caption for media)
+func (r Request) Text() string {
+	if text := r.Params.Get("text"); text != "" {
+		return text
+	}
+	return r.Params.Get("caption")
+}
+
+// InlineKeyboard returns the inline keyboard of the request, nil if it has none
+func (r Request) InlineKeyboard() [][]tgbotapi.InlineKeyboardButton {
+	var markup tgbotapi.InlineKeyboardMarkup
+	if err := json.Unmarshal([]byte(r.Params.Get("reply_markup")), &markup); err != nil {
+		return nil
+	}
+	return markup.InlineKeyboard
+}
+
+// ReplyKeyboard returns the reply keyboard of the request, nil if it has none
+func (r Request) ReplyKeyboard() [][]tgbotapi.KeyboardButton {
+	var markup tgbotapi.ReplyKeyboardMarkup
+	if err := json.Unmarshal([]byte(r.Params.Get("reply_markup")), &markup); err != nil {
+		return nil
+	}
+	return markup.Keyboard
+}
+
+// Buttons returns the texts of all buttons of the inline or reply keyboard of the request
+func (r Request) Buttons() []string {
+	var buttons []string
+	for _, row := range r.InlineKeyboard() {
+		for _, button := range row {
+			buttons = append(buttons, button.Text)
+		}
+	}
+	for _, row := range r.ReplyKeyboard() {
+		for _, button := range row {
+			buttons = append(buttons, button.Text)
+		}
+	}
+	return buttons
+}
+
+// Server is a fake Telegram Bot API server
+// It answers every method successfully, records all calls and serves injected updates to getUpdates.
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	requests      []Request
+	updates       []tgbotapi.Update
+	nextMessageID int
+	nextUpdateID  int
+	responses     map[string]interface{}
+	changed       chan struct{}
+}
+
+// NewServer starts a fake Bot API server
+// The server must be closed with Close.
+func NewServer() *Server {
+	s := &Server{
+		nextMessageID: 1,
+		nextUpdateID:  1,
+		responses:     make(map[string]interface{}),
+		changed:       make(chan struct{}),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Endpoint returns the Bot API endpoint of the server for tgfsm.WithAPIEndpoint
+func (s *Server) Endpoint() string {
+	return s.URL + "/bot%s/%s"
+}
+
+// SetResponse sets the result returned for the method instead of the default one
+// The result is encoded to JSON as the "result" field of the response.
+func (s *Server) SetResponse(method string, result interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.responses[method] = result
+}
+
+// PushUpdate queues the update for getUpdates, i.e. for a bot started with Start
+// A zero UpdateID is replaced with the next one. Use Bot.ProcessUpdate to process updates synchronously.
+func (s *Server) PushUpdate(update tgbotapi.Update) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if update.UpdateID == 0 {
+		update.UpdateID = s.nextUpdateID
+	}
+	if update.UpdateID >= s.nextUpdateID {
+		s.nextUpdateID = update.UpdateID + 1
+	}
+	s.updates = append(s.updates, update)
+	s.notify()
+}
+
+// Requests returns all recorded calls in order
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// Calls returns the recorded calls of the methods
+func (s *Server) Calls(methods ...string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var calls []Request
+	for _, r := range s.requests {
+		for _, method := range methods {
+			if r.Method == method {
+				calls = append(calls, r)
+				break
+			}
+		}
+	}
+	return calls
+}
+
+// Sent returns the recorded calls sending messages: sendMessage, sendPhoto, sendSticker and other send* methods
+func (s *Server) Sent() []Request {
+	return s.filter(isSent)
+}
+
+// Edited returns the recorded calls editing messages: editMessageText, editMessageReplyMarkup and others
+func (s *Server) Edited() []Request {
+	return s.filter(isEdit)
+}
+
+// Deleted returns the recorded deleteMessage calls
+func (s *Server) Deleted() []Request {
+	return s.Calls("deleteMessage")
+}
+
+// Reset forgets the recorded calls and queued updates
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = nil
+	s.updates = nil
+}
+
+// WaitFor waits until at least n calls match the methods and returns them
+// Returns false if the timeout expires first. Useful with a bot started with Start.
+func (s *Server) WaitFor(n int, timeout time.Duration, methods ...string) ([]Request, bool) {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		changed := s.changed
+		s.mu.Unlock()
+
+		if calls := s.Calls(methods...); len(calls) >= n {
+			return calls, true
+		}
+
+		select {
+		case <-changed:
+		case <-deadline:
+			return s.Calls(methods...), false
+		}
+	}
+}
+
+// filter returns the recorded calls whose method matches
+func (s *Server) filter(match func(method string) bool) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var calls []Request
+	for _, r := range s.requests {
+		if match(r.Method) {
+			calls = append(calls, r)
+		}
+	}
+	return calls
+}
+
+// notify wakes up waiting getUpdates and WaitFor. Must be called with mu held.
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// handle serves a Bot API call: /bot<token>/<method>
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(parts) != 2 || parts[0] != "bot"+Token {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	method := parts[1]
+
+	params, err := parseParams(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Bad Request: "+err.Error())
+		return
+	}
+
+	// getUpdates is polled constantly, so it is not recorded
+	if method == "getUpdates" {
+		writeResult(w, s.pollUpdates(params))
+		return
+	}
+
+	s.mu.Lock()
+	request := Request{Method: method, Params: params, Time: time.Now()}
+	result, ok := s.responses[method]
+	if !ok {
+		result = s.defaultResult(method, params)
+	}
+	if message, ok := result.(tgbotapi.Message); ok {
+		request.MessageID = message.MessageID
+	}
+	s.requests = append(s.requests, request)
+	s.notify()
+	s.mu.Unlock()
+
+	writeResult(w, result)
+}
+
+// pollUpdates returns the queued updates starting from the offset
+func (s *Server) pollUpdates(params url.Values) []tgbotapi.Update {
+	offset, _ := strconv.Atoi(params.Get("offset"))
+	deadline := time.After(maxPollWait)
+
+	for {
+		s.mu.Lock()
+		var updates []tgbotapi.Update
+		for _, update := range s.updates {
+			if update.UpdateID >= offset {
+				updates = append(updates, update)
+			}
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		if len(updates) > 0 {
+			return updates
+		}
+
+		select {
+		case <-changed:
+		case <-deadline:
+			return []tgbotapi.Update{}
+		}
+	}
+}
+
+// defaultResult returns a plausible result of the method. Must be called with mu held.
+func (s *Server) defaultResult(method string, params url.Values) interface{} {
+	switch {
+	case method == "getMe":
+		return tgbotapi.User{ID: BotID, IsBot: true, FirstName: "Test", UserName: BotUserName}
+
+	case method == "getWebhookInfo":
+		return tgbotapi.WebhookInfo{}
+
+	case method == "getFile":
+		fileID := params.Get("file_id")
+		return tgbotapi.File{FileID: fileID, FileUniqueID: fileID, FilePath: "files/" + fileID}
+
+	case method == "getChat":
+		chatID, _ := strconv.ParseInt(params.Get("chat_id"), 10, 64)
+		return tgbotapi.Chat{ID: chatID, Type: chatType(chatID)}
+
+	case method == "getChatAdministrators":
+		return []tgbotapi.ChatMember{}
+
+	case method == "sendMediaGroup":
+		return []tgbotapi.Message{s.newMessage(params)}
+
+	case strings.HasPrefix(method, "send"), method == "copyMessage", method == "forwardMessage":
+		return s.newMessage(params)
+
+	case strings.HasPrefix(method, "editMessage"):
+		// Inline messages are edited without chat_id, Telegram returns true for them
+		if params.Get("inline_message_id") != "" {
+			return true
+		}
+		message := s.messageFor(params)
+		message.MessageID, _ = strconv.Atoi(params.Get("message_id"))
+		return message
+
+	default:
+		return true
+	}
+}
+
+// newMessage returns a new sent message. Must be called with mu held.
+func (s *Server) newMessage(params url.Values) tgbotapi.Message {
+	message := s.messageFor(params)
+	message.MessageID = s.nextMessageID
+	s.nextMessageID++
+	return message
+}
+
+// messageFor returns a message of the bot with the parameters of the call
+func (s *Server) messageFor(params url.Values) tgbotapi.Message {
+	chatID, _ := strconv.ParseInt(params.Get("chat_id"), 10, 64)
+	return tgbotapi.Message{
+		From:    &tgbotapi.User{ID: BotID, IsBot: true, FirstName: "Test", UserName: BotUserName},
+		Date:    int(time.Now().Unix()),
+		Chat:    &tgbotapi.Chat{ID: chatID, Type: chatType(chatID)},
+		Text:    params.Get("text"),
+		Caption: params.Get("caption"),
+	}
+}
+
+// parseParams reads the parameters of form and multipart requests
+func parseParams(r *http.Request) (url.Values, error) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return nil, err
+		}
+		return url.Values(r.MultipartForm.Value), nil
+	}
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	return r.Form, nil
+}
+
+// writeResult writes a successful Bot API response
+func writeResult(w http.ResponseWriter, result interface{}) {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: true, Result: raw})
+}
+
+// writeError writes a failed Bot API response
+func writeError(w http.ResponseWriter, code int, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: false, ErrorCode: code, Description: description})
+}
+
+// chatType returns the type of the chat by its ID: negative IDs belong to groups
+func chatType(chatID int64) string {
+	if chatID < 0 {
+		return "supergroup"
+	}
+	return "private"
+}
@@ -0,0 +1,199 @@
+package tgfsm
+
+import (
+	"context"
+	"reflect"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Tracer creates spans for distributed tracing (see WithTracer)
+// The interface follows OpenTelemetry, so an adapter is a few lines:
+//
+//	type otelTracer struct{ tracer trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string, attributes ...tgfsm.Attribute) (context.Context, tgfsm.Span) {
+//		ctx, span := t.tracer.Start(ctx, name)
+//		s := otelSpan{span}
+//		s.SetAttributes(attributes...)
+//		return ctx, s
+//	}
+//
+// The bot creates spans:
+//   - "tgfsm.update" for every processed update with the update ID and type, user, chat,
+//     the user's state and the matched handler;
+//   - "tgfsm.limiter" for rate limiter waits;
+//   - "tgfsm.api.<method>" for every Bot API call, e.g. "tgfsm.api.sendMessage" for sending a message.
+//
+// Handlers do not receive a context, so the parent of limiter waits and API calls is found
+// by the chat of the call: it is the span of the update being processed in that chat.
+// Limitation: if several updates of one chat are processed concurrently, the calls are
+// attributed to the update received last, and calls without a chat (e.g. getMe) or to
+// another chat have no parent. The handler attributes and Bot.UpdateContext are exact:
+// they find the span by the update ID. Handlers can start their own child spans
+// with the context returned by Bot.UpdateContext.
+type Tracer interface {
+	// Start starts a span that is a child of the span in ctx, if any
+	Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span)
+}
+
+// Span is a traced operation
+type Span interface {
+	// SetAttributes sets attributes of the span
+	SetAttributes(attributes ...Attribute)
+	// RecordError records an error of the operation
+	RecordError(err error)
+	// End completes the span
+	End()
+}
+
+// Attribute is a key-value attribute of a span
+// Value is a string, bool, int, int64 or float64.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attr creates an attribute
+func Attr(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// nopTracer is the default Tracer that creates no spans
+type nopTracer struct{}
+
+func (nopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+// nopSpan is the span of nopTracer
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(...Attribute) {}
+func (nopSpan) RecordError(error)          {}
+func (nopSpan) End()                       {}
+
+// updateTrace is the span of an update being processed
+type updateTrace struct {
+	ctx  context.Context
+	span Span
+}
+
+// traceUpdate starts the span of the update and makes it the parent of spans in the update's chat
+// The span is also registered by the update ID for UpdateContext and traceHandler.
+// The returned function ends the span.
+func (app *Bot) traceUpdate(update tgbotapi.Update) func() {
+	attributes := []Attribute{
+		Attr("update.id", update.UpdateID),
+		Attr("update.type", UpdateType(update)),
+	}
+	if user := update.SentFrom(); user != nil {
+		attributes = append(attributes, Attr("user.id", user.ID))
+		if state, err := app.GetUserState(user.ID); err == nil {
+			attributes = append(attributes, Attr("state", state))
+		}
+	}
+	chat := update.FromChat()
+	if chat != nil {
+		attributes = append(attributes, Attr("chat.id", chat.ID))
+	}
+
+	ctx, span := app.tracer.Start(context.Background(), "tgfsm.update", attributes...)
+	trace := &updateTrace{ctx: ctx, span: span}
+
+	app.tracesMu.Lock()
+	app.updateTraces[update.UpdateID] = trace
+	if chat != nil {
+		app.traces[chat.ID] = trace
+	}
+	app.tracesMu.Unlock()
+
+	return func() {
+		app.tracesMu.Lock()
+		// Another update with the same ID or a newer update of the chat may have replaced the trace
+		if app.updateTraces[update.UpdateID] == trace {
+			delete(app.updateTraces, update.UpdateID)
+		}
+		if chat != nil && app.traces[chat.ID] == trace {
+			delete(app.traces, chat.ID)
+		}
+		app.tracesMu.Unlock()
+		span.End()
+	}
+}
+
+// updateTraceOf returns the span of the update while it is processed, nil if there is none
+// Updates created without an ID fall back to the span of their chat.
+func (app *Bot) updateTraceOf(update tgbotapi.Update) *updateTrace {
+	app.tracesMu.Lock()
+	trace := app.updateTraces[update.UpdateID]
+	app.tracesMu.Unlock()
+	if trace != nil {
+		return trace
+	}
+
+	if chat := update.FromChat(); chat != nil {
+		return app.chatTrace(chat.ID)
+	}
+	return nil
+}
+
+// chatTrace returns the span of the update being processed in the chat, nil if there is none
+func (app *Bot) chatTrace(chatID int64) *updateTrace {
+	if chatID == 0 {
+		return nil
+	}
+
+	app.tracesMu.Lock()
+	defer app.tracesMu.Unlock()
+
+	return app.traces[chatID]
+}
+
+// chatContext returns the context with the span of the update being processed in the chat
+func (app *Bot) chatContext(chatID int64) context.Context {
+	if trace := app.chatTrace(chatID); trace != nil {
+		return trace.ctx
+	}
+	return context.Background()
+}
+
+// UpdateContext returns the context with the span of the update while it is processed,
+// context.Background() otherwise. Handlers use it to start child spans of the update.
+func (app *Bot) UpdateContext(update tgbotapi.Update) context.Context {
+	if trace := app.updateTraceOf(update); trace != nil {
+		return trace.ctx
+	}
+	return context.Background()
+}
+
+// traceHandler sets the matched handler on the span of the update
+func (app *Bot) traceHandler(update tgbotapi.Update, state, handler string, err error) {
+	trace := app.updateTraceOf(update)
+	if trace == nil {
+		return
+	}
+
+	trace.span.SetAttributes(Attr("handler.state", state), Attr("handler", handler))
+	if err != nil {
+		trace.span.RecordError(err)
+	}
+}
+
+// chatIDOf returns the chat of a Bot API call config, 0 if the call has no chat
+// tgbotapi configs keep the chat in a ChatID field, usually of an embedded BaseChat or BaseEdit.
+func chatIDOf(config interface{}) int64 {
+	v := reflect.ValueOf(config)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return 0
+	}
+
+	field := v.FieldByName("ChatID")
+	if !field.IsValid() || !field.CanInt() {
+		return 0
+	}
+	return field.Int()
+}
@@ -0,0 +1,142 @@
+package tgfsm_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"tgfsm"
+	"tgfsm/tgfsmtest"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// spanKey is the context key of the recorded span
+type spanKey struct{}
+
+// recordedSpan is a span of recordingTracer
+type recordedSpan struct {
+	name       string
+	parent     *recordedSpan
+	mu         sync.Mutex
+	attributes map[string]interface{}
+}
+
+func (s *recordedSpan) SetAttributes(attributes ...tgfsm.Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, attribute := range attributes {
+		s.attributes[attribute.Key] = attribute.Value
+	}
+}
+
+func (s *recordedSpan) attribute(key string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attributes[key]
+}
+
+func (s *recordedSpan) RecordError(error) {}
+func (s *recordedSpan) End()              {}
+
+// recordingTracer records the started spans with their parents
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, attributes ...tgfsm.Attribute) (context.Context, tgfsm.Span) {
+	parent, _ := ctx.Value(spanKey{}).(*recordedSpan)
+	span := &recordedSpan{name: name, parent: parent, attributes: make(map[string]interface{})}
+	span.SetAttributes(attributes...)
+
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func TestUpdateContextConcurrentUpdatesOfChat(t *testing.T) {
+	server := tgfsmtest.NewServer()
+	t.Cleanup(server.Close)
+
+	tracer := &recordingTracer{}
+	// Both updates wait until the other one has started, so they overlap
+	var started sync.WaitGroup
+	started.Add(2)
+	handle := func(b *tgfsm.Bot, u tgbotapi.Update) error {
+		started.Done()
+		started.Wait()
+		tracer.Start(b.UpdateContext(u), "handler "+u.Message.Text)
+		return nil
+	}
+	states := map[string]tgfsm.State{
+		"home": {MessageHandlers: map[string]tgfsm.Handler{"a": {Handle: handle}, "b": {Handle: handle}}},
+	}
+	bot, err := tgfsmtest.NewBot(server, tgfsm.WithStates(states), tgfsm.WithInitialState("home"), tgfsm.WithTracer(tracer))
+	if err != nil {
+		t.Fatalf("failed to create bot: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, text := range []string{"a", "b"} {
+		update := tgfsmtest.NewMessageUpdate(1, text)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bot.ProcessUpdate(update)
+		}()
+	}
+	wg.Wait()
+
+	handlers := 0
+	for _, span := range tracer.spans {
+		if span.name != "handler a" && span.name != "handler b" {
+			continue
+		}
+		handlers++
+		if span.parent == nil || span.parent.name != "tgfsm.update" {
+			t.Fatalf("%s has parent %+v, want the update span", span.name, span.parent)
+		}
+		if got, want := span.parent.attribute("handler"), span.name[len("handler "):]; got != want {
+			t.Fatalf("%s is a child of the update handled by %v", span.name, got)
+		}
+	}
+	if handlers != 2 {
+		t.Fatalf("recorded %d handler spans, want 2", handlers)
+	}
+}
+
+func TestAPICallSpans(t *testing.T) {
+	server := tgfsmtest.NewServer()
+	t.Cleanup(server.Close)
+
+	tracer := &recordingTracer{}
+	bot, err := tgfsmtest.NewBot(server, tgfsm.WithTracer(tracer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bot.SendMessage(tgbotapi.NewMessage(1, "hello")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bot.Request(tgbotapi.NewCallback("callback", "")); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	var calls []interface{}
+	for _, span := range tracer.spans {
+		if call := span.attribute("api.call"); call != nil {
+			names = append(names, span.name)
+			calls = append(calls, call)
+		}
+	}
+	// Spans are named by Bot API methods
+	if want := []string{"tgfsm.api.sendMessage", "tgfsm.api.answerCallbackQuery"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("spans %q, want %q", names, want)
+	}
+	if want := []interface{}{"sendMessage", "answerCallbackQuery"}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("api.call attributes %q, want %q", calls, want)
+	}
+}
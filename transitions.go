@@ -0,0 +1,183 @@
+package tgfsm
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// GuardFunc is a function type for transition guards
+// A non-nil error rejects the transition, the user stays in the current state
+type GuardFunc func(b *Bot, t Transition) error
+
+// checkTransition checks that the user may move from the current state to the new one.
+// Returns ErrTransitionNotAllowed if the target is not declared in State.Transitions
+// and ErrTransitionRejected (wrapping the guard error) if a guard rejects the transition.
+func (app *Bot) checkTransition(t Transition) error {
+	if t.From == t.To {
+		return nil
+	}
+
+	from, ok := app.stateSet().states[t.From]
+	if !ok {
+		return nil
+	}
+
+	if from.Transitions != nil && !slices.Contains(from.Transitions, t.To) && !app.isGlobalTarget(t.To) {
+		return NewSFMError(ErrTransitionNotAllowed, t.From+" -> "+t.To)
+	}
+
+	if guard := from.Guards[t.To]; guard != nil {
+		if err := guard(app, t); err != nil {
+			return NewSFMError(ErrTransitionRejected, fmt.Errorf("%s -> %s: %w", t.From, t.To, err))
+		}
+	}
+
+	return nil
+}
+
+// checkUserTransition checks the transition of the user from the current state to the new one
+func (app *Bot) checkUserTransition(userId int64, state string, update *tgbotapi.Update) error {
+	current, err := app.GetUserState(userId)
+	if err != nil {
+		current = ""
+	}
+
+	return app.checkTransition(Transition{
+		UserID: userId,
+		From:   current,
+		To:     state,
+		Update: update,
+	})
+}
+
+// isGlobalTarget reports whether the state is declared as a transition target of a global state.
+// Global handlers run in any state, so their targets are allowed from anywhere.
+func (app *Bot) isGlobalTarget(state string) bool {
+	for _, s := range app.stateSet().states {
+		if s.Global && slices.Contains(s.Transitions, state) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateStates checks the states configuration and returns the found problems:
+//   - dangling targets: Transitions and Guards referring to states that do not exist
+//   - duplicate global triggers: the same message or callback handled by several global states;
+//     the states are listed in precedence order, the first one handles the trigger
+//     unless their scopes (OnlyIn, ExcludeIn) do not overlap
+//   - scopes referring to states that do not exist
+//   - unreachable states: non-global states that are neither initial nor declared as a target
+//     of any state. Checked only if at least one non-global state declares Transitions,
+//     i.e. the bot describes its transitions rather than only entries of global triggers.
+//
+// NewBot logs the problems as warnings, or fails with them if WithStrictValidation is set.
+func ValidateStates(states map[string]State, initialState string) []error {
+	var problems []error
+
+	names := make([]string, 0, len(states))
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	declared := false
+	targets := make(map[string]bool)
+	messageTriggers := make(map[string][]string)
+	callbackTriggers := make(map[string][]string)
+
+	for _, name := range names {
+		state := states[name]
+
+		if state.Transitions != nil && !state.Global {
+			declared = true
+		}
+		for _, target := range state.Transitions {
+			targets[target] = true
+			if _, ok := states[target]; !ok {
+				problems = append(problems, NewSFMError(ErrDanglingTransition, name+" -> "+target))
+			}
+		}
+		guards := make([]string, 0, len(state.Guards))
+		for target := range state.Guards {
+			guards = append(guards, target)
+		}
+		sort.Strings(guards)
+		for _, target := range guards {
+			if _, ok := states[target]; !ok {
+				problems = append(problems, NewSFMError(ErrDanglingTransition, name+" -> "+target))
+			}
+		}
+
+		for _, scope := range append(append([]string(nil), state.OnlyIn...), state.ExcludeIn...) {
+			if _, ok := states[scope]; scope != "" && !ok {
+				problems = append(problems, NewSFMError(ErrUnknownScopeState, name+": "+scope))
+			}
+		}
+	}
+
+	// Triggers are collected in precedence order, so the state listed first handles the trigger
+	for _, global := range sortGlobalStates(states) {
+		for trigger := range global.state.MessageHandlers {
+			messageTriggers[trigger] = append(messageTriggers[trigger], global.name)
+		}
+		for trigger := range global.state.CallbackHandlers {
+			callbackTriggers[trigger] = append(callbackTriggers[trigger], global.name)
+		}
+	}
+
+	problems = append(problems, duplicateTriggers("message", messageTriggers)...)
+	problems = append(problems, duplicateTriggers("callback", callbackTriggers)...)
+
+	if declared {
+		for _, name := range names {
+			if states[name].Global || name == initialState || targets[name] {
+				continue
+			}
+			problems = append(problems, NewSFMError(ErrUnreachableState, name))
+		}
+	}
+
+	return problems
+}
+
+// duplicateTriggers reports triggers handled by more than one global state
+func duplicateTriggers(kind string, triggers map[string][]string) []error {
+	keys := make([]string, 0, len(triggers))
+	for trigger, states := range triggers {
+		if len(states) > 1 {
+			keys = append(keys, trigger)
+		}
+	}
+	sort.Strings(keys)
+
+	problems := make([]error, 0, len(keys))
+	for _, trigger := range keys {
+		problems = append(problems, NewSFMError(ErrDuplicateGlobalTrigger,
+			fmt.Sprintf("%s %q in %s", kind, trigger, strings.Join(triggers[trigger], ", "))))
+	}
+	return problems
+}
+
+// validateStates runs ValidateStates and logs the problems
+// In strict mode the problems are returned as a single error instead
+func (app *Bot) validateStates() error {
+	problems := ValidateStates(app.states, app.initialState)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	if app.strictValidation {
+		return NewSFMError(ErrInvalidStates, errors.Join(problems...))
+	}
+
+	for _, problem := range problems {
+		app.Logger().Warn(problem.Error())
+	}
+	return nil
+}
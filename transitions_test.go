@@ -0,0 +1,200 @@
+package tgfsm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"tgfsm"
+	"tgfsm/tgfsmtest"
+)
+
+const transitionUserID = 5
+
+// newTransitionBot creates a bot with the states cart -> checkout -> paid,
+// a global help state that may lead to support, and the guard of checkout -> paid
+func newTransitionBot(t *testing.T, guard tgfsm.GuardFunc) *tgfsm.Bot {
+	t.Helper()
+
+	server := tgfsmtest.NewServer()
+	t.Cleanup(server.Close)
+
+	states := map[string]tgfsm.State{
+		"cart":     {Transitions: []string{"checkout"}},
+		"checkout": {Transitions: []string{"cart", "paid"}, Guards: map[string]tgfsm.GuardFunc{"paid": guard}},
+		"paid":     {Transitions: []string{}},
+		"support":  {},
+		"help": {
+			Global:          true,
+			Transitions:     []string{"support"},
+			MessageHandlers: map[string]tgfsm.Handler{"/help": {Handle: reply("help")}},
+		},
+	}
+	bot, err := tgfsmtest.NewBot(server, tgfsm.WithStates(states), tgfsm.WithInitialState("cart"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bot
+}
+
+// expectState fails the test if the user is not in the state
+func expectState(t *testing.T, bot *tgfsm.Bot, state string) {
+	t.Helper()
+
+	if current, err := bot.GetUserState(transitionUserID); err != nil || current != state {
+		t.Fatalf("state %q (%v), want %q", current, err, state)
+	}
+}
+
+func TestTransitionNotAllowed(t *testing.T) {
+	bot := newTransitionBot(t, nil)
+
+	err := bot.SetUserState(transitionUserID, "paid")
+	if !errors.Is(err, tgfsm.ErrTransitionNotAllowed) {
+		t.Fatalf("error %v, want ErrTransitionNotAllowed", err)
+	}
+	expectState(t, bot, "cart")
+
+	if err := bot.PushUserState(transitionUserID, "paid"); !errors.Is(err, tgfsm.ErrTransitionNotAllowed) {
+		t.Fatalf("push error %v, want ErrTransitionNotAllowed", err)
+	}
+	expectState(t, bot, "cart")
+
+	// An empty list allows no transitions at all, unlike nil
+	if err := bot.ForceUserState(transitionUserID, "paid"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.SetUserState(transitionUserID, "cart"); !errors.Is(err, tgfsm.ErrTransitionNotAllowed) {
+		t.Fatalf("error %v, want ErrTransitionNotAllowed from a state without transitions", err)
+	}
+}
+
+func TestTransitionGuard(t *testing.T) {
+	errUnpaid := errors.New("not paid")
+	var guarded []tgfsm.Transition
+	bot := newTransitionBot(t, func(b *tgfsm.Bot, transition tgfsm.Transition) error {
+		guarded = append(guarded, transition)
+		if len(guarded) == 1 {
+			return errUnpaid
+		}
+		return nil
+	})
+	if err := bot.SetUserState(transitionUserID, "checkout"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := bot.SetUserState(transitionUserID, "paid")
+	if !errors.Is(err, tgfsm.ErrTransitionRejected) || !strings.Contains(err.Error(), errUnpaid.Error()) {
+		t.Fatalf("error %v, want ErrTransitionRejected with the guard error", err)
+	}
+	expectState(t, bot, "checkout")
+
+	if err := bot.SetUserState(transitionUserID, "paid"); err != nil {
+		t.Fatalf("error %v once the guard passes", err)
+	}
+	expectState(t, bot, "paid")
+
+	if len(guarded) != 2 || guarded[0].From != "checkout" || guarded[0].To != "paid" || guarded[0].UserID != transitionUserID {
+		t.Fatalf("guard called with %+v, want checkout -> paid twice", guarded)
+	}
+}
+
+func TestTransitionGlobalTarget(t *testing.T) {
+	bot := newTransitionBot(t, nil)
+
+	// support is declared by the global help state, so it is allowed from any state
+	if err := bot.SetUserState(transitionUserID, "support"); err != nil {
+		t.Fatalf("error %v, want the global target allowed", err)
+	}
+	expectState(t, bot, "support")
+}
+
+func TestValidateStates(t *testing.T) {
+	handler := map[string]tgfsm.Handler{"/help": {}}
+
+	tests := []struct {
+		name    string
+		states  map[string]tgfsm.State
+		initial string
+		want    []error
+	}{
+		{
+			name: "valid",
+			states: map[string]tgfsm.State{
+				"home": {Transitions: []string{"menu"}},
+				"menu": {Transitions: []string{"home"}},
+			},
+			initial: "home",
+		},
+		{
+			name: "dangling transition and guard",
+			states: map[string]tgfsm.State{
+				"home": {Transitions: []string{"missing"}, Guards: map[string]tgfsm.GuardFunc{"gone": nil}},
+			},
+			initial: "home",
+			want:    []error{tgfsm.ErrDanglingTransition, tgfsm.ErrDanglingTransition},
+		},
+		{
+			name: "duplicate global trigger",
+			states: map[string]tgfsm.State{
+				"a": {Global: true, MessageHandlers: handler},
+				"b": {Global: true, MessageHandlers: handler},
+			},
+			want: []error{tgfsm.ErrDuplicateGlobalTrigger},
+		},
+		{
+			name: "unknown scope",
+			states: map[string]tgfsm.State{
+				"home":   {},
+				"global": {Global: true, OnlyIn: []string{"home", ""}, ExcludeIn: []string{"missing"}},
+			},
+			want: []error{tgfsm.ErrUnknownScopeState},
+		},
+		{
+			name: "unreachable",
+			states: map[string]tgfsm.State{
+				"home":   {Transitions: []string{"menu"}},
+				"menu":   {},
+				"orphan": {},
+			},
+			initial: "home",
+			want:    []error{tgfsm.ErrUnreachableState},
+		},
+		{
+			name: "reachability without declared transitions",
+			states: map[string]tgfsm.State{
+				"home":   {},
+				"orphan": {},
+			},
+			initial: "home",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := tgfsm.ValidateStates(tt.states, tt.initial)
+			if len(problems) != len(tt.want) {
+				t.Fatalf("problems %v, want %v", problems, tt.want)
+			}
+			for i, problem := range problems {
+				if !errors.Is(problem, tt.want[i]) {
+					t.Errorf("problem %d is %v, want %v", i, problem, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStrictValidation(t *testing.T) {
+	server := tgfsmtest.NewServer()
+	t.Cleanup(server.Close)
+	states := map[string]tgfsm.State{"home": {Transitions: []string{"missing"}}}
+
+	_, err := tgfsmtest.NewBot(server, tgfsm.WithStates(states), tgfsm.WithStrictValidation(true))
+	if !errors.Is(err, tgfsm.ErrInvalidStates) || !strings.Contains(err.Error(), "home -> missing") {
+		t.Fatalf("error %v, want ErrInvalidStates with the problem", err)
+	}
+
+	if _, err := tgfsmtest.NewBot(server, tgfsm.WithStates(states)); err != nil {
+		t.Fatalf("error %v without strict validation, want the problem only logged", err)
+	}
+}